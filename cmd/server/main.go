@@ -2,74 +2,344 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/damantine/multi-tenant-hosting/internal/adapters/docker"
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/git"
 	"github.com/damantine/multi-tenant-hosting/internal/adapters/handler"
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/mailer"
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/metrics"
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/ratelimit"
 	"github.com/damantine/multi-tenant-hosting/internal/adapters/repository"
+	"github.com/damantine/multi-tenant-hosting/internal/config"
 	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
 	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/damantine/multi-tenant-hosting/internal/logging"
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
 	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 func main() {
-	dsn := os.Getenv("DB_DSN")
-	if dsn == "" {
-		dsn = "host=localhost user=postgres password=postgres dbname=multitenant port=5432 sslmode=disable TimeZone=Asia/Jakarta"
+	if err := run(); err != nil {
+		slog.Error("server stopped", slog.Any("error", err))
+		os.Exit(1)
 	}
-	
-	var db *gorm.DB
-	var err error
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+}
+
+// run menjalankan server sampai sinyal shutdown; error dikembalikan (bukan os.Exit) supaya
+// semua defer, termasuk flush tracer, tetap jalan
+func run() error {
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	slog.SetDefault(logging.New(os.Stdout, logging.ParseLevel(cfg.LogLevel)))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, "multi-tenant-hosting")
 	if err != nil {
-		log.Printf("Warning: Failed to connect to database: %v. Running in Memory/Mock mode not implemented fully.", err)
-	} else {
-		log.Println("Database connected. Running migrations...")
-		db.AutoMigrate(&domain.User{}, &domain.Project{}, &domain.EnvVar{}, &domain.Deployment{})
+		return fmt.Errorf("init tracing: %w", err)
 	}
+	defer shutdownTracing(context.Background())
 
-	dockerClient, err := docker.NewDockerClient()
+	// Tanpa DB server tidak bisa jalan, jadi retry sebentar lalu keluar jika tetap gagal
+	db, err := connectDB(ctx, cfg.Database.DSN, 10, 2*time.Second)
+	if err != nil {
+		return fmt.Errorf("connect to database: %w", err)
+	}
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Failed to init Docker client: %v", err)
+		return fmt.Errorf("get database handle: %w", err)
 	}
 
 	projectRepo := repository.NewGormProjectRepository(db)
+	promMetrics := metrics.NewPrometheusMetrics(projectRepo)
+	auditRepo := repository.NewGormAuditRepository(db)
+	auditService := services.NewAuditService(auditRepo)
 
-	authService := services.NewAuthService(db, "rahasia-negara-dont-use-in-prod")
-	projectService := services.NewProjectService(projectRepo, dockerClient)
+	dockerClient, err := docker.NewDockerClient(promMetrics, cfg.Docker)
+	if err != nil {
+		return fmt.Errorf("init docker client: %w", err)
+	}
+
+	mail, err := newMailer(cfg.Mail)
+	if err != nil {
+		return fmt.Errorf("init mailer: %w", err)
+	}
 
-	r := handler.NewRouter(authService, projectService)
-	
-	log.Println("Starting server on :8080")
-	if err := r.Run(":8080"); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	signingKeys, err := loadSigningKeys(cfg.Auth)
+	if err != nil {
+		return fmt.Errorf("load JWT signing keys: %w", err)
+	}
+	authSecret, err := loadAuthSecret(cfg.Auth)
+	if err != nil {
+		return fmt.Errorf("load auth secret: %w", err)
+	}
+
+	authService := services.NewAuthService(db, signingKeys, authSecret, mail, cfg.AppURL, auditService)
+	encryptionKey, err := loadEncryptionKey(cfg.Auth, authSecret)
+	if err != nil {
+		return fmt.Errorf("load encryption key: %w", err)
+	}
+	secretBox, err := services.NewSecretBox(encryptionKey)
+	if err != nil {
+		return fmt.Errorf("init secret encryption: %w", err)
+	}
+	registryService := services.NewRegistryService(db, secretBox, auditService)
+	imagePolicyService := services.NewImagePolicyService(db, auditService)
+	seccompProfile, err := loadSeccompProfile(cfg.SeccompProfile)
+	if err != nil {
+		return fmt.Errorf("load seccomp profile: %w", err)
+	}
+	hardeningService := services.NewHardeningService(db, auditService, seccompProfile)
+	rateLimitStore, err := newRateLimitStore(ctx, db, cfg.RateLimitStore)
+	if err != nil {
+		return fmt.Errorf("init rate limit store: %w", err)
+	}
+	loginThrottle := services.NewLoginThrottle(rateLimitStore, services.DefaultThrottleConfig())
+	tokenService := services.NewTokenService(db, auditService)
+	orgService := services.NewOrganizationService(db, auditService)
+
+	// SSO OIDC aktif jika issuer dan client ID di-set
+	oidcService := services.NewOIDCService(db, authService, services.OIDCConfig{
+		IssuerURL:      cfg.OIDC.Issuer,
+		ClientID:       cfg.OIDC.ClientID,
+		ClientSecret:   cfg.OIDC.ClientSecret,
+		RedirectURL:    cfg.OIDC.RedirectURL,
+		AllowedDomains: cfg.OIDC.AllowedDomains,
+	})
+	oidcHandler := handler.NewOIDCHandler(oidcService, cfg.OIDC.PostLoginRedirect)
+	passwordLogin := !(oidcService.Enabled() && cfg.OIDC.DisablePasswordLogin)
+	projectService := services.NewProjectService(projectRepo, dockerClient, promMetrics, repository.NewGormTenantRepository(db), registryService, imagePolicyService, hardeningService, auditService,
+		services.ProjectConfig{BaseDomain: cfg.BaseDomain, NetworkPrefix: cfg.Docker.NetworkPrefix})
+	adminService := services.NewAdminService(db, authService, projectService, auditService)
+	gitClient := git.NewCLIClient(cfg.Build.GitAllowLocal)
+	buildLimits := services.DefaultBuildLimits()
+	buildLimits.MaxUploadBytes = cfg.Build.MaxUploadMB << 20
+	buildLimits.MaxContextBytes = cfg.Build.MaxContextMB << 20
+	buildService := services.NewBuildService(projectRepo, gitClient, dockerClient, projectService, auditService, buildLimits)
+
+	// Readiness: ping Postgres & Docker daemon, masing-masing timeout 2 detik
+	healthService := services.NewHealthService(2*time.Second,
+		services.HealthCheck{Name: "postgres", Check: sqlDB.PingContext},
+		services.HealthCheck{Name: "docker", Check: dockerClient.Ping},
+	)
+
+	// Sampling stats container tiap 15 detik, simpan 40 sampel (~10 menit)
+	statsCollector := services.NewStatsCollector(projectRepo, dockerClient, 15*time.Second, 40)
+	promMetrics.RegisterContainerStats(statsCollector)
+
+	r := handler.NewRouter(cfg, authService, loginThrottle, oidcHandler, passwordLogin, tokenService, adminService, imagePolicyService, hardeningService, orgService, registryService, projectService, buildService, statsCollector, promMetrics, healthService)
+	srv := &http.Server{Addr: cfg.Server.Addr, Handler: r}
+	// Scrape Prometheus di listener terpisah supaya metrics internal tidak ikut terekspos lewat API publik
+	metricsSrv := &http.Server{Addr: cfg.Server.MetricsAddr, Handler: promMetrics.Handler()}
+
+	// Server sudah listen selama migrasi supaya /healthz bisa dijawab, tapi /readyz dan API lain
+	// baru dilayani setelah migrasi selesai
+	serveErr := make(chan error, 2)
+	go func() {
+		slog.Info("starting server", slog.String("addr", srv.Addr))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("server failed: %w", err)
+		}
+	}()
+	go func() {
+		slog.Info("starting metrics server", slog.String("addr", metricsSrv.Addr))
+		if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("metrics server failed: %w", err)
+		}
+	}()
+
+	slog.Info("database connected, running migrations")
+	if err := db.WithContext(ctx).AutoMigrate(&domain.User{}, &domain.Project{}, &domain.EnvVar{}, &domain.Deployment{}, &domain.Session{}, &domain.APIToken{},
+		&domain.Organization{}, &domain.Membership{}, &domain.Invitation{}, &domain.UserIdentity{},
+		&domain.RecoveryCode{}, &domain.EmailToken{}, &domain.AuditEvent{}, &domain.Build{}, &domain.RegistryCredential{}, &domain.ImagePolicy{}, &domain.SecurityPolicy{}); err != nil {
+		return fmt.Errorf("run migrations: %w", err)
+	}
+	if err := auditRepo.Protect(ctx); err != nil {
+		return fmt.Errorf("protect audit log: %w", err)
+	}
+	if err := orgService.MigratePersonalOrganizations(ctx); err != nil {
+		return fmt.Errorf("migrate personal organizations: %w", err)
+	}
+	// Daftar user ID/email terverifikasi yang dijadikan platform admin saat startup
+	if err := adminService.PromoteAdmins(ctx, cfg.Auth.PlatformAdmins); err != nil {
+		return fmt.Errorf("promote platform admins: %w", err)
+	}
+	healthService.MarkReady()
+	slog.Info("migrations finished, server is ready")
+
+	go statsCollector.Run(ctx)
+
+	var stopErr error
+	select {
+	case <-ctx.Done():
+	case stopErr = <-serveErr:
+	}
+	slog.Info("shutting down server")
+	healthService.MarkNotReady()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("graceful shutdown failed", slog.Any("error", err))
+	}
+	if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("metrics server shutdown failed", slog.Any("error", err))
+	}
+	return stopErr
+}
+
+// loadSigningKeys membaca key JWT dari JWTKeysDir (satu file PEM per kid, JWTActiveKID opsional).
+// Tanpa JWTKeysDir dibuat key Ed25519 sementara: access token tidak valid lagi setelah restart.
+func loadSigningKeys(cfg config.AuthConfig) (*services.KeySet, error) {
+	if cfg.JWTKeysDir == "" {
+		slog.Warn("JWT_KEYS_DIR not set, using an ephemeral signing key (development only)")
+		return services.GenerateKeySet()
+	}
+	return services.LoadKeySet(cfg.JWTKeysDir, cfg.JWTActiveKID)
+}
+
+// loadAuthSecret secret HMAC dari konfigurasi; tanpa itu dibuat acak per proses
+func loadAuthSecret(cfg config.AuthConfig) ([]byte, error) {
+	secret, err := cfg.SecretBytes()
+	if err != nil || secret != nil {
+		return secret, err
+	}
+	slog.Warn("AUTH_SECRET not set, using a random secret (email links and SSO logins in progress break on restart)")
+	secret = make([]byte, 32)
+	_, err = rand.Read(secret)
+	return secret, err
+}
+
+// loadEncryptionKey key AES-256 untuk secret yang disimpan terenkripsi (token registry).
+// Tanpa ENCRYPTION_KEY diturunkan dari auth secret, jadi ikut berubah jika AUTH_SECRET diganti.
+func loadEncryptionKey(cfg config.AuthConfig, authSecret []byte) ([]byte, error) {
+	key, err := cfg.EncryptionKeyBytes()
+	if err != nil || key != nil {
+		return key, err
+	}
+	if cfg.Secret == "" {
+		slog.Warn("ENCRYPTION_KEY and AUTH_SECRET not set, stored registry credentials become unreadable on restart")
+	}
+	return hkdf.Key(sha256.New, authSecret, nil, "mth registry credentials", 32)
+}
+
+// loadSeccompProfile path file JSON profile seccomp untuk container tenant (opsional)
+func loadSeccompProfile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !json.Valid(data) {
+		return "", fmt.Errorf("%s is not valid JSON", path)
+	}
+	return string(data), nil
+}
+
+// newMailer memakai SMTP jika host SMTP di-set, selain itu email ditulis sebagai file .eml di cfg.Dir
+func newMailer(cfg config.MailConfig) (ports.Mailer, error) {
+	if cfg.SMTPHost == "" {
+		slog.Warn("SMTP_HOST not set, emails will be written to disk", slog.String("dir", cfg.Dir))
+		return mailer.NewFileMailer(cfg.Dir, cfg.From)
+	}
+	return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+}
+
+// newRateLimitStore "postgres" supaya limit berlaku di semua replica, default in-memory
+func newRateLimitStore(ctx context.Context, db *gorm.DB, kind string) (ports.RateLimitStore, error) {
+	switch kind {
+	case "", "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "postgres":
+		store := ratelimit.NewPostgresStore(db)
+		if err := store.AutoMigrate(ctx); err != nil {
+			return nil, err
+		}
+		go func() {
+			ticker := time.NewTicker(10 * time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := store.Cleanup(ctx); err != nil {
+						slog.Warn("rate limit cleanup failed", slog.Any("error", err))
+					}
+				}
+			}
+		}()
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q (expected memory or postgres)", kind)
 	}
 }
 
+// connectDB membuka koneksi Postgres dengan retry (mis. saat container DB masih booting)
+func connectDB(ctx context.Context, dsn string, attempts int, delay time.Duration) (*gorm.DB, error) {
+	var err error
+	for i := 1; i <= attempts; i++ {
+		var db *gorm.DB
+		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		if err == nil {
+			return db, nil
+		}
+
+		slog.Warn("database not reachable, retrying", slog.Int("attempt", i), slog.Any("error", err))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return nil, err
+}
+
 func runDemo(svc *services.ProjectService) {
 	ctx := context.Background()
-	log.Println("--- Starting Demo Scenario ---")
-	
+	slog.Info("--- Starting Demo Scenario ---")
+
 	fakeUserID := uuid.New()
-	
-	log.Println("1. Creating Project Metadata...")
-	proj, err := svc.CreateProject(ctx, fakeUserID, "Demo App", "nginx:alpine", "demo-site", 80)
+
+	slog.Info("1. Creating Project Metadata...")
+	proj, err := svc.CreateProject(ctx, fakeUserID, uuid.New(), "Demo App", "nginx:alpine", "demo-site", 80, "", nil)
 	if err != nil {
-		log.Printf("Error creating project (DB might be down): %v", err)
+		slog.Error("error creating project (DB might be down)", slog.Any("error", err))
 		return
 	}
-	log.Printf("Project Created: ID=%s Subdomain=%s", proj.ID, proj.Subdomain)
+	slog.Info("project created", slog.String("id", proj.ID.String()), slog.String("subdomain", proj.Subdomain))
 
 	// Deploy
-	log.Println("2. Deploying to Docker...")
+	slog.Info("2. Deploying to Docker...")
 	deployment, err := svc.DeployProject(ctx, proj.ID)
 	if err != nil {
-		log.Fatalf("Deployment Failed: %v", err)
+		slog.Error("deployment failed", slog.Any("error", err))
+		os.Exit(1)
 	}
-	
-	log.Printf("SUCCESS! Container ID: %s. Status: %s", deployment.ContainerID, deployment.Status)
-	log.Println("Try checking 'docker ps'")
+
+	slog.Info("SUCCESS! Try checking 'docker ps'", slog.String("container_id", deployment.ContainerID), slog.String("status", deployment.Status))
 }
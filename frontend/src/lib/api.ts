@@ -15,4 +15,39 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
+// Access token berumur pendek: saat 401, tukar refresh token sekali lalu ulangi request
+let refreshing: Promise<string> | null = null;
+
+api.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const original = error.config;
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (error.response?.status !== 401 || original._retry || !refreshToken || original.url === '/auth/refresh') {
+      return Promise.reject(error);
+    }
+    original._retry = true;
+
+    try {
+      refreshing ??= api
+        .post('/auth/refresh', { refresh_token: refreshToken })
+        .then((res) => {
+          localStorage.setItem('token', res.data.token);
+          localStorage.setItem('refresh_token', res.data.refresh_token);
+          return res.data.token as string;
+        })
+        .finally(() => {
+          refreshing = null;
+        });
+      const token = await refreshing;
+      original.headers.Authorization = `Bearer ${token}`;
+      return api(original);
+    } catch (refreshError) {
+      localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
+      return Promise.reject(refreshError);
+    }
+  }
+);
+
 export default api;
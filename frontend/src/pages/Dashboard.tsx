@@ -56,8 +56,10 @@ const Dashboard: React.FC = () => {
     fetchData();
   }, []);
 
-  const handleLogout = () => {
+  const handleLogout = async () => {
+      await api.post('/auth/logout').catch(() => {});
       localStorage.removeItem('token');
+      localStorage.removeItem('refresh_token');
       navigate('/login');
   };
 
@@ -26,8 +26,17 @@ const Login: React.FC = () => {
     setError('');
 
     try {
-      const res = await api.post('/auth/login', formData);
+      let res = await api.post('/auth/login', formData);
+      if (res.data.mfa_required) {
+        const code = window.prompt('Enter your two-factor code (or a recovery code)') || '';
+        const isRecovery = code.includes('-');
+        res = await api.post('/auth/2fa/challenge', {
+          challenge_token: res.data.challenge_token,
+          ...(isRecovery ? { recovery_code: code } : { code }),
+        });
+      }
       localStorage.setItem('token', res.data.token);
+      localStorage.setItem('refresh_token', res.data.refresh_token);
       navigate('/dashboard');
     } catch (err: any) {
       console.error(err);
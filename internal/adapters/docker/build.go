@@ -0,0 +1,169 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/docker/docker/api/types"
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// buildMessage satu baris progress JSON dari ImageBuild
+type buildMessage struct {
+	Stream string `json:"stream"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// BuildImage implementasi ports.ImageBuilder. Build context dikirim sebagai tar (mengikuti .dockerignore)
+// dan output build diteruskan ke logs.
+func (d *DockerClient) BuildImage(ctx context.Context, config ports.BuildConfig, logs io.Writer) error {
+	ctx, span := startSpan(ctx, "image_build", attribute.StringSlice("docker.tags", config.Tags))
+	defer span.End()
+
+	dockerfile := config.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	// Tar ditulis sambil dikirim supaya context besar tidak ditampung di memory
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeBuildContext(pw, config.ContextDir, dockerfile))
+	}()
+	defer pr.Close()
+
+	slog.InfoContext(ctx, "building image", slog.Any("tags", config.Tags))
+	start := time.Now()
+	resp, err := d.cli.ImageBuild(ctx, pr, types.ImageBuildOptions{
+		Tags:        config.Tags,
+		Dockerfile:  filepath.ToSlash(dockerfile),
+		Labels:      config.Labels,
+		BuildArgs:   config.BuildArgs,
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return d.track(ctx, "image_build", err)
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg buildMessage
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return d.track(ctx, "image_build", err)
+		}
+		if msg.Error != "" {
+			return d.track(ctx, "image_build", errors.New(msg.Error))
+		}
+		switch {
+		case msg.Stream != "":
+			io.WriteString(logs, msg.Stream)
+		case msg.Status != "":
+			io.WriteString(logs, msg.Status+"\n")
+		}
+	}
+
+	slog.InfoContext(ctx, "image built", slog.Any("tags", config.Tags), slog.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// writeBuildContext menulis isi dir sebagai tar, melewati file yang cocok dengan .dockerignore dan folder .git.
+// Dockerfile dan .dockerignore selalu ikut dikirim seperti perilaku docker CLI.
+func writeBuildContext(w io.Writer, dir, dockerfile string) error {
+	var patterns []string
+	if f, err := os.Open(filepath.Join(dir, ".dockerignore")); err == nil {
+		patterns, err = ignorefile.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("invalid .dockerignore: %w", err)
+		}
+	}
+	pm, err := patternmatcher.New(patterns)
+	if err != nil {
+		return fmt.Errorf("invalid .dockerignore: %w", err)
+	}
+	alwaysInclude := map[string]bool{path.Clean(filepath.ToSlash(dockerfile)): true, ".dockerignore": true}
+
+	tw := tar.NewWriter(w)
+	err = filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() && rel == ".git" {
+			return filepath.SkipDir
+		}
+		if !alwaysInclude[rel] {
+			excluded, err := pm.MatchesOrParentMatches(rel)
+			if err != nil {
+				return err
+			}
+			if excluded {
+				// Folder tetap ditelusuri jika ada pola pengecualian ("!pattern") yang mungkin cocok di dalamnya
+				if entry.IsDir() && !pm.Exclusions() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		// Jangan bocorkan user/group host ke image
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -2,100 +2,388 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/damantine/multi-tenant-hosting/internal/config"
 	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
 )
 
 type DockerClient struct {
-	cli *client.Client
+	cli            *client.Client
+	metrics        ports.MetricsRecorder
+	proxyContainer string
+
+	// networkMu mencegah network tenant dihapus di antara ensureNetwork dan ContainerCreate
+	networkMu sync.Mutex
 }
 
-// NewDockerClient inisialisasi koneksi ke Docker Daemon
-func NewDockerClient() (*DockerClient, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+// NewDockerClient inisialisasi koneksi ke Docker Daemon. cfg.ProxyContainer nama container Traefik
+// yang disambungkan ke setiap network tenant (kosong = tidak disambungkan otomatis).
+// Koneksi dibaca dari env Docker standar (DOCKER_HOST, dsb); opts menimpa env, mis. host lain di test.
+func NewDockerClient(metrics ports.MetricsRecorder, cfg config.DockerConfig, opts ...client.Opt) (*DockerClient, error) {
+	cli, err := client.NewClientWithOpts(append([]client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}, opts...)...)
 	if err != nil {
 		return nil, err
 	}
-	return &DockerClient{cli: cli}, nil
+	return &DockerClient{cli: cli, metrics: metrics, proxyContainer: cfg.ProxyContainer}, nil
+}
+
+// startSpan membuat span untuk satu panggilan Docker API
+func startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "docker."+operation, append(attrs, attribute.String("docker.operation", operation))...)
+}
+
+// track mencatat error Docker API per operasi (metrics + log + span) lalu meneruskan error-nya
+func (d *DockerClient) track(ctx context.Context, operation string, err error) error {
+	if err != nil {
+		d.metrics.IncDockerError(operation)
+		slog.ErrorContext(ctx, "docker api error", slog.String("operation", operation), slog.Any("error", err))
+
+		span := trace.SpanFromContext(ctx)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// pullMessage satu baris progress JSON dari ImagePull
+type pullMessage struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// Ping cek koneksi ke Docker daemon (dipakai readiness probe)
+func (d *DockerClient) Ping(ctx context.Context) error {
+	ctx, span := startSpan(ctx, "ping")
+	defer span.End()
+
+	_, err := d.cli.Ping(ctx)
+	return d.track(ctx, "ping", err)
+}
+
+// PrepareImage implementasi ports.ContainerRuntime. Setelah pull (atau memakai image lokal)
+// tag di-resolve ke repo digest supaya container menjalankan image yang persis sama walau tag dipindah.
+func (d *DockerClient) PrepareImage(ctx context.Context, imageName, pullPolicy string, auth *ports.RegistryAuth) (*ports.ImageRef, error) {
+	ctx, span := startSpan(ctx, "image_prepare", attribute.String("docker.image", imageName), attribute.String("docker.pull_policy", pullPolicy))
+	defer span.End()
+
+	inspect, _, err := d.cli.ImageInspectWithRaw(ctx, imageName)
+	present := err == nil
+	if err != nil && !client.IsErrNotFound(err) {
+		return nil, d.track(ctx, "image_inspect", err)
+	}
+
+	switch {
+	case pullPolicy == "always", pullPolicy != "never" && !present:
+		if err := d.pullImage(ctx, imageName, auth); err != nil {
+			return nil, fmt.Errorf("failed to pull image: %w", err)
+		}
+		if inspect, _, err = d.cli.ImageInspectWithRaw(ctx, imageName); err != nil {
+			return nil, d.track(ctx, "image_inspect", err)
+		}
+	case !present:
+		return nil, fmt.Errorf("%w: %s", ports.ErrImageNotPresent, imageName)
+	case len(inspect.RepoDigests) > 0:
+		// Image registry di cache bisa jadi di-pull tenant lain dengan kredensialnya; akses tenant ini
+		// (anonim jika tidak punya kredensial) tetap dicek ke registry. Image yang tidak pernah
+		// di-pull (mis. hasil build lokal) tidak punya RepoDigests dan tetap bisa dipakai offline.
+		if err := d.verifyRegistryAccess(ctx, imageName, auth); err != nil {
+			return nil, err
+		}
+	}
+
+	ref := &ports.ImageRef{ID: inspect.ID, Digest: repoDigest(imageName, inspect.RepoDigests), Size: inspect.Size}
+	if inspect.Config != nil {
+		ref.Labels = inspect.Config.Labels
+	}
+	span.SetAttributes(attribute.String("docker.image_digest", ref.Pinned()))
+	return ref, nil
 }
 
-// EnsureImage memastikan image tersedia (pull jika belum ada)
-func (d *DockerClient) EnsureImage(ctx context.Context, imageName string) error {
-	reader, err := d.cli.ImagePull(ctx, imageName, types.ImagePullOptions{})
+// repoDigest memilih RepoDigests milik repository image (satu image bisa di-tag ke beberapa repository)
+func repoDigest(imageName string, repoDigests []string) string {
+	named, err := reference.ParseNormalizedNamed(imageName)
 	if err != nil {
-		return err
+		return ""
+	}
+	for _, rd := range repoDigests {
+		canonical, err := reference.ParseNormalizedNamed(rd)
+		if err == nil && canonical.Name() == named.Name() {
+			return canonical.String()
+		}
+	}
+	return ""
+}
+
+// verifyRegistryAccess cek manifest image ke registry (tanpa pull) memakai auth. auth nil = akses anonim.
+func (d *DockerClient) verifyRegistryAccess(ctx context.Context, imageName string, auth *ports.RegistryAuth) error {
+	ctx, span := startSpan(ctx, "distribution_inspect", attribute.String("docker.image", imageName))
+	defer span.End()
+
+	var encoded string
+	if auth != nil {
+		var err error
+		if encoded, err = encodeRegistryAuth(auth); err != nil {
+			return err
+		}
+	}
+	if _, err := d.cli.DistributionInspect(ctx, imageName, encoded); err != nil {
+		return fmt.Errorf("%w: %s: %w", ports.ErrRegistryAccessDenied, imageName, d.track(ctx, "distribution_inspect", err))
+	}
+	return nil
+}
+
+// pullImage pull image dari registry. auth nil = pull anonim.
+func (d *DockerClient) pullImage(ctx context.Context, imageName string, auth *ports.RegistryAuth) error {
+	ctx, span := startSpan(ctx, "image_pull", attribute.String("docker.image", imageName))
+	defer span.End()
+
+	slog.InfoContext(ctx, "pulling image", slog.String("image", imageName))
+	start := time.Now()
+	opts := types.ImagePullOptions{}
+	if auth != nil {
+		encoded, err := encodeRegistryAuth(auth)
+		if err != nil {
+			return err
+		}
+		opts.RegistryAuth = encoded
+	}
+	reader, err := d.cli.ImagePull(ctx, imageName, opts)
+	if err != nil {
+		return d.track(ctx, "image_pull", err)
 	}
 	defer reader.Close()
-	// Discard output agar tidak memenuhi buffer, di real app bisa di stream ke logs
-	io.Copy(os.Stdout, reader) 
+
+	// Progress pull di-stream ke log level debug. Error pull juga dikirim lewat stream ini.
+	dec := json.NewDecoder(reader)
+	for {
+		var msg pullMessage
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return d.track(ctx, "image_pull", err)
+		}
+		if msg.Error != "" {
+			return d.track(ctx, "image_pull", errors.New(msg.Error))
+		}
+		slog.DebugContext(ctx, "image pull progress", slog.String("image", imageName), slog.String("status", msg.Status))
+	}
+
+	d.metrics.ObserveImagePull(time.Since(start))
+	slog.InfoContext(ctx, "image pulled", slog.String("image", imageName), slog.Duration("duration", time.Since(start)))
 	return nil
 }
 
+// encodeRegistryAuth format header X-Registry-Auth. Docker Hub memakai alamat index lama.
+func encodeRegistryAuth(auth *ports.RegistryAuth) (string, error) {
+	server := auth.ServerAddress
+	if server == "docker.io" {
+		server = "https://index.docker.io/v1/"
+	}
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		ServerAddress: server,
+	})
+}
+
 // CreateContainer implementasi ports.ContainerRuntime
 func (d *DockerClient) CreateContainer(ctx context.Context, config ports.ContainerConfig) (string, error) {
-	// 1. Pastikan Image ada
-	if err := d.EnsureImage(ctx, config.Image); err != nil {
-		return "", fmt.Errorf("failed to pull image: %w", err)
-	}
+	ctx, span := startSpan(ctx, "container_create", attribute.String("docker.image", config.Image), attribute.String("docker.container_name", config.Name))
+	defer span.End()
+
+	// Image sudah disiapkan lewat PrepareImage; config.Image berupa referensi yang dipin ke digest
 
-	// 2. Konfigurasi Port Binding (Expose port container ke host dynamic port atau internal network)
+	// Konfigurasi Port Binding (Expose port container ke host dynamic port atau internal network)
 	// Untuk kasus Traefik dan Single Node, biasanya kita tidak perlu bind ke Host Port jika dalam satu network.
 	// Namun untuk debug, kita bisa set up variable.
 	// Di sini kita asumsikan Traefik route via Docker Network, jadi tidak perlu publish ports ke Host (User -> Traefik -> Container IP).
-	
+
 	containerConfig := &container.Config{
-		Image: config.Image,
-		Env:   config.Env,
+		Image:  config.Image,
+		Env:    config.Env,
 		Labels: config.Labels, // Traefik labels masuk sini
 		ExposedPorts: nat.PortSet{
 			nat.Port(fmt.Sprintf("%d/tcp", config.Port)): {},
 		},
 	}
 
-	hostConfig := &container.HostConfig{
-		NetworkMode: "traefik-net",
+	hostConfig := &container.HostConfig{}
+	applySecurity(containerConfig, hostConfig, config.Security)
+
+	// Container hanya tersambung ke network tenant-nya sendiri; alias dipakai antar project tenant
+	var networkConfig *network.NetworkingConfig
+	if config.Network != "" {
+		hostConfig.NetworkMode = container.NetworkMode(config.Network)
+		networkConfig = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				config.Network: {Aliases: config.NetworkAliases},
+			},
+		}
+
+		d.networkMu.Lock()
+		defer d.networkMu.Unlock()
+		if err := d.ensureNetwork(ctx, config.Network); err != nil {
+			return "", err
+		}
 	}
 
-	resp, err := d.cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, config.Name)
+	resp, err := d.cli.ContainerCreate(ctx, containerConfig, hostConfig, networkConfig, nil, config.Name)
 	if err != nil {
-		return "", err
+		return "", d.track(ctx, "container_create", err)
 	}
 
+	slog.InfoContext(ctx, "container created", slog.String("container_id", resp.ID), slog.String("name", config.Name))
 	return resp.ID, nil
 }
 
+// applySecurity menerjemahkan profil hardening ke opsi container Docker
+func applySecurity(cfg *container.Config, host *container.HostConfig, profile ports.SecurityProfile) {
+	cfg.User = profile.User
+	if profile.NoNewPrivileges {
+		host.SecurityOpt = append(host.SecurityOpt, "no-new-privileges:true")
+	}
+	if profile.SeccompProfile != "" {
+		host.SecurityOpt = append(host.SecurityOpt, "seccomp="+profile.SeccompProfile)
+	}
+	host.CapDrop = profile.CapDrop
+	host.CapAdd = profile.CapAdd
+	host.ReadonlyRootfs = profile.ReadOnlyRootfs
+	host.Tmpfs = profile.Tmpfs
+	if profile.PidsLimit > 0 {
+		limit := profile.PidsLimit
+		host.PidsLimit = &limit
+	}
+}
+
 func (d *DockerClient) StartContainer(ctx context.Context, containerID string) error {
-	return d.cli.ContainerStart(ctx, containerID, types.ContainerStartOptions{})
+	ctx, span := startSpan(ctx, "container_start", attribute.String("docker.container_id", containerID))
+	defer span.End()
+
+	return d.track(ctx, "container_start", d.cli.ContainerStart(ctx, containerID, types.ContainerStartOptions{}))
 }
 
 func (d *DockerClient) StopContainer(ctx context.Context, containerID string) error {
+	ctx, span := startSpan(ctx, "container_stop", attribute.String("docker.container_id", containerID))
+	defer span.End()
+
 	// Timeout default 10s
-	return d.cli.ContainerStop(ctx, containerID, container.StopOptions{})
+	return d.track(ctx, "container_stop", d.cli.ContainerStop(ctx, containerID, container.StopOptions{}))
 }
 
 func (d *DockerClient) RemoveContainer(ctx context.Context, containerID string) error {
-	return d.cli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{
+	ctx, span := startSpan(ctx, "container_remove", attribute.String("docker.container_id", containerID))
+	defer span.End()
+
+	return d.track(ctx, "container_remove", d.cli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{
 		Force: true,
-	})
+	}))
 }
 
 func (d *DockerClient) InspectContainer(ctx context.Context, containerID string) (*ports.ContainerStatus, error) {
+	ctx, span := startSpan(ctx, "container_inspect", attribute.String("docker.container_id", containerID))
+	defer span.End()
+
 	json, err := d.cli.ContainerInspect(ctx, containerID)
 	if err != nil {
-		return nil, err
+		return nil, d.track(ctx, "container_inspect", err)
 	}
-	
+
 	return &ports.ContainerStatus{
-		ID: json.ID,
-		State: json.State.Status, // running, paused, etc
+		ID:     json.ID,
+		State:  json.State.Status, // running, paused, etc
 		Status: json.State.Status,
 	}, nil
 }
+
+// Stats mengambil satu sampel stats dari Docker (stream=false).
+// Dengan stream=false Docker tetap mengisi precpu_stats sehingga CPU% bisa dihitung.
+func (d *DockerClient) Stats(ctx context.Context, containerID string) (*ports.ContainerStats, error) {
+	ctx, span := startSpan(ctx, "container_stats", attribute.String("docker.container_id", containerID))
+	defer span.End()
+
+	resp, err := d.cli.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return nil, d.track(ctx, "container_stats", err)
+	}
+	defer resp.Body.Close()
+
+	var raw types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, d.track(ctx, "container_stats", fmt.Errorf("failed to decode stats: %w", err))
+	}
+
+	stats := &ports.ContainerStats{
+		Timestamp:   raw.Read,
+		CPUPercent:  cpuPercent(&raw.Stats),
+		MemoryUsage: memoryUsage(&raw.MemoryStats),
+		MemoryLimit: raw.MemoryStats.Limit,
+		PIDs:        raw.PidsStats.Current,
+	}
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100.0
+	}
+
+	for _, n := range raw.Networks {
+		stats.NetworkRx += n.RxBytes
+		stats.NetworkTx += n.TxBytes
+	}
+
+	for _, e := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			stats.BlockRead += e.Value
+		case "write":
+			stats.BlockWrite += e.Value
+		}
+	}
+
+	return stats, nil
+}
+
+// cpuPercent menghitung CPU% dengan rumus yang sama seperti `docker stats`
+func cpuPercent(s *types.Stats) float64 {
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+
+	onlineCPUs := float64(s.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	return cpuDelta / systemDelta * onlineCPUs * 100.0
+}
+
+// memoryUsage mengurangi page cache dari usage, sama seperti `docker stats`
+func memoryUsage(m *types.MemoryStats) uint64 {
+	// cgroup v1 pakai total_inactive_file, cgroup v2 pakai inactive_file
+	if v, ok := m.Stats["total_inactive_file"]; ok && v < m.Usage {
+		return m.Usage - v
+	}
+	if v, ok := m.Stats["inactive_file"]; ok && v < m.Usage {
+		return m.Usage - v
+	}
+	return m.Usage
+}
@@ -0,0 +1,422 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/config"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/docker/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type recordingMetrics struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func (m *recordingMetrics) ObserveDeploy(string, time.Duration) {}
+func (m *recordingMetrics) ObserveImagePull(time.Duration)      {}
+func (m *recordingMetrics) IncDockerError(operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors = append(m.errors, operation)
+}
+
+// fakeDaemon Docker Engine API minimal: ping dan inspect container "web"
+func fakeDaemon(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Api-Version", "1.44")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			w.Write([]byte("OK"))
+		case strings.HasSuffix(r.URL.Path, "/containers/web/json"):
+			json.NewEncoder(w).Encode(map[string]any{"Id": "web", "State": map[string]any{"Status": "running"}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No such container"})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDockerClientSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.InstallExporter("test", exporter)
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	metrics := &recordingMetrics{}
+	daemon := fakeDaemon(t)
+	d, err := NewDockerClient(metrics, config.DockerConfig{}, client.WithHost("tcp://"+daemon.Listener.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		container string
+		wantCode  codes.Code
+	}{
+		{container: "web", wantCode: codes.Unset},
+		{container: "missing", wantCode: codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.container, func(t *testing.T) {
+			exporter.Reset()
+			_, err := d.InspectContainer(context.Background(), tt.container)
+			if (err != nil) != (tt.wantCode == codes.Error) {
+				t.Fatalf("InspectContainer err = %v", err)
+			}
+
+			// Selain span kita, SDK Docker membuat span HTTP client yang harus menjadi child-nya
+			var span tracetest.SpanStub
+			var children int
+			for _, s := range exporter.GetSpans() {
+				if s.Name == "docker.container_inspect" {
+					span = s
+				}
+			}
+			if span.Name == "" {
+				t.Fatalf("no docker.container_inspect span in %v", exporter.GetSpans().Snapshots())
+			}
+			for _, s := range exporter.GetSpans() {
+				if s.Parent.SpanID() == span.SpanContext.SpanID() {
+					children++
+				}
+			}
+			if children == 0 {
+				t.Fatal("Docker API request span is not a child of docker.container_inspect")
+			}
+			for _, want := range []attribute.KeyValue{
+				attribute.String("docker.operation", "container_inspect"),
+				attribute.String("docker.container_id", tt.container),
+			} {
+				if !hasAttribute(span.Attributes, want) {
+					t.Fatalf("span attributes %v lack %v", span.Attributes, want)
+				}
+			}
+			if span.Status.Code != tt.wantCode {
+				t.Fatalf("span status = %v, want %v", span.Status.Code, tt.wantCode)
+			}
+		})
+	}
+
+	if len(metrics.errors) != 1 || metrics.errors[0] != "container_inspect" {
+		t.Fatalf("docker errors recorded = %v, want one container_inspect", metrics.errors)
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCPUPercent(t *testing.T) {
+	stats := func(total, preTotal, system, preSystem uint64, online uint32, perCPU int) *types.Stats {
+		s := &types.Stats{}
+		s.CPUStats.CPUUsage.TotalUsage = total
+		s.PreCPUStats.CPUUsage.TotalUsage = preTotal
+		s.CPUStats.SystemUsage = system
+		s.PreCPUStats.SystemUsage = preSystem
+		s.CPUStats.OnlineCPUs = online
+		s.CPUStats.CPUUsage.PercpuUsage = make([]uint64, perCPU)
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		stats *types.Stats
+		want  float64
+	}{
+		{name: "half of one cpu", stats: stats(150, 100, 1100, 1000, 1, 0), want: 50},
+		{name: "scaled by online cpus", stats: stats(150, 100, 1100, 1000, 4, 0), want: 200},
+		{name: "percpu fallback when online is unknown", stats: stats(150, 100, 1100, 1000, 0, 2), want: 100},
+		{name: "first sample without precpu", stats: stats(150, 150, 1100, 1000, 1, 0), want: 0},
+		{name: "system counter did not move", stats: stats(150, 100, 1000, 1000, 1, 0), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cpuPercent(tt.stats); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("cpuPercent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryUsage(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats types.MemoryStats
+		want  uint64
+	}{
+		{name: "cgroup v1 excludes inactive file cache", stats: types.MemoryStats{Usage: 1000, Stats: map[string]uint64{"total_inactive_file": 300}}, want: 700},
+		{name: "cgroup v2 excludes inactive file cache", stats: types.MemoryStats{Usage: 1000, Stats: map[string]uint64{"inactive_file": 400}}, want: 600},
+		{name: "cache larger than usage is ignored", stats: types.MemoryStats{Usage: 100, Stats: map[string]uint64{"inactive_file": 400}}, want: 100},
+		{name: "no cache stats", stats: types.MemoryStats{Usage: 1000}, want: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := memoryUsage(&tt.stats); got != tt.want {
+				t.Fatalf("memoryUsage = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeRegistryAuth(t *testing.T) {
+	tests := []struct {
+		server     string
+		wantServer string
+	}{
+		{server: "ghcr.io", wantServer: "ghcr.io"},
+		{server: "docker.io", wantServer: "https://index.docker.io/v1/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			encoded, err := encodeRegistryAuth(&ports.RegistryAuth{ServerAddress: tt.server, Username: "bot", Password: "secret"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := registry.DecodeAuthConfig(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded.ServerAddress != tt.wantServer || decoded.Username != "bot" || decoded.Password != "secret" {
+				t.Fatalf("auth config = %+v", decoded)
+			}
+		})
+	}
+}
+
+// registryDaemon Docker Engine API minimal untuk image: inspect image lokal, pull dari "registry",
+// dan cek akses manifest ke registry (distribution inspect)
+type registryDaemon struct {
+	mu       sync.Mutex
+	local    map[string]types.ImageInspect // image yang sudah ada di host
+	registry map[string]types.ImageInspect // image yang bisa di-pull
+	private  map[string]string             // image private -> username yang boleh membaca
+	pulls    []string                      // image yang di-pull beserta header X-Registry-Auth
+	inspects []string                      // image yang dicek ke registry beserta username
+}
+
+func (d *registryDaemon) serve(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		w.Header().Set("Api-Version", "1.44")
+		w.Header().Set("Content-Type", "application/json")
+		_, path, _ := strings.Cut(r.URL.Path, "/images/")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			w.Write([]byte("OK"))
+		case strings.Contains(r.URL.Path, "/distribution/"):
+			_, name, _ := strings.Cut(strings.TrimSuffix(r.URL.Path, "/json"), "/distribution/")
+			var username string
+			if auth, err := registry.DecodeAuthConfig(r.Header.Get("X-Registry-Auth")); err == nil {
+				username = auth.Username
+			}
+			d.inspects = append(d.inspects, name+"|"+username)
+			if allowed, ok := d.private[name]; ok && username != allowed {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]string{"message": "unauthorized: authentication required"})
+				return
+			}
+			json.NewEncoder(w).Encode(registry.DistributionInspect{})
+		case r.Method == http.MethodPost && path == "create":
+			name := r.URL.Query().Get("fromImage") + ":" + r.URL.Query().Get("tag")
+			d.pulls = append(d.pulls, name+"|"+r.Header.Get("X-Registry-Auth"))
+			img, ok := d.registry[name]
+			if !ok {
+				json.NewEncoder(w).Encode(map[string]string{"error": "manifest unknown"})
+				return
+			}
+			d.local[name] = img
+			json.NewEncoder(w).Encode(map[string]string{"status": "Downloaded newer image"})
+		case r.Method == http.MethodGet && strings.HasSuffix(path, "/json"):
+			img, ok := d.local[strings.TrimSuffix(path, "/json")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"message": "No such image"})
+				return
+			}
+			json.NewEncoder(w).Encode(img)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPrepareImage(t *testing.T) {
+	const (
+		name      = "ghcr.io/acme/web:1"
+		oldDigest = "ghcr.io/acme/web@sha256:" + "1111111111111111111111111111111111111111111111111111111111111111"
+		newDigest = "ghcr.io/acme/web@sha256:" + "2222222222222222222222222222222222222222222222222222222222222222"
+	)
+	cached := types.ImageInspect{ID: "sha256:old", RepoDigests: []string{oldDigest}}
+	latest := types.ImageInspect{ID: "sha256:new", RepoDigests: []string{"mirror.local/web@sha256:" + strings.Repeat("3", 64), newDigest}}
+	auth := &ports.RegistryAuth{ServerAddress: "ghcr.io", Username: "bot", Password: "secret"}
+	otherTenant := &ports.RegistryAuth{ServerAddress: "ghcr.io", Username: "intruder", Password: "guess"}
+
+	tests := []struct {
+		name         string
+		policy       string
+		cached       bool
+		private      bool // registry hanya bisa dibaca user "bot"
+		local        bool // image cache tidak pernah di-pull (tanpa RepoDigests)
+		auth         *ports.RegistryAuth
+		wantPulls    int
+		wantInspects int
+		wantDigest   string
+		wantErr      error // nil = sukses
+	}{
+		{name: "always pulls even when cached", policy: "always", cached: true, auth: auth, wantPulls: 1, wantDigest: newDigest},
+		{name: "if-not-present uses cache", policy: "if-not-present", cached: true, wantInspects: 1, wantDigest: oldDigest},
+		{name: "if-not-present pulls when missing", policy: "if-not-present", auth: auth, wantPulls: 1, wantDigest: newDigest},
+		{name: "never uses cache", policy: "never", cached: true, wantInspects: 1, wantDigest: oldDigest},
+		{name: "never fails when missing", policy: "never", wantErr: ports.ErrImageNotPresent},
+		{name: "cached private image checks credential", policy: "if-not-present", cached: true, private: true, auth: auth, wantInspects: 1, wantDigest: oldDigest},
+		{name: "cached private image with never checks credential", policy: "never", cached: true, private: true, auth: auth, wantInspects: 1, wantDigest: oldDigest},
+		{name: "cached private image rejects other credential", policy: "if-not-present", cached: true, private: true, auth: otherTenant, wantInspects: 1, wantErr: ports.ErrRegistryAccessDenied},
+		{name: "cached private image without credential", policy: "if-not-present", cached: true, private: true, wantInspects: 1, wantErr: ports.ErrRegistryAccessDenied},
+		{name: "cached private image without credential and never", policy: "never", cached: true, private: true, wantInspects: 1, wantErr: ports.ErrRegistryAccessDenied},
+		{name: "local build is used without registry check", policy: "never", cached: true, local: true, wantDigest: "sha256:old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := &registryDaemon{local: map[string]types.ImageInspect{}, registry: map[string]types.ImageInspect{name: latest}, private: map[string]string{}}
+			if tt.cached {
+				daemon.local[name] = cached
+			}
+			if tt.local {
+				daemon.local[name] = types.ImageInspect{ID: "sha256:old"}
+			}
+			if tt.private {
+				daemon.private[name] = auth.Username
+			}
+			srv := daemon.serve(t)
+			d, err := NewDockerClient(&recordingMetrics{}, config.DockerConfig{}, client.WithHost("tcp://"+srv.Listener.Addr().String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ref, err := d.PrepareImage(context.Background(), name, tt.policy, tt.auth)
+			if len(daemon.inspects) != tt.wantInspects {
+				t.Fatalf("registry access checks = %v, want %d", daemon.inspects, tt.wantInspects)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ref.Pinned() != tt.wantDigest {
+				t.Fatalf("pinned = %q, want %q", ref.Pinned(), tt.wantDigest)
+			}
+			if len(daemon.pulls) != tt.wantPulls {
+				t.Fatalf("pulls = %v, want %d", daemon.pulls, tt.wantPulls)
+			}
+			if tt.wantPulls > 0 && tt.auth != nil && strings.HasSuffix(daemon.pulls[0], "|") {
+				t.Fatal("pull sent without registry credentials")
+			}
+		})
+	}
+}
+
+func TestRepoDigest(t *testing.T) {
+	digest := "@sha256:" + strings.Repeat("a", 64)
+	tests := []struct {
+		image       string
+		repoDigests []string
+		want        string
+	}{
+		{image: "nginx:alpine", repoDigests: []string{"nginx" + digest}, want: "docker.io/library/nginx" + digest},
+		{image: "ghcr.io/acme/web:1", repoDigests: []string{"ghcr.io/other/web" + digest, "ghcr.io/acme/web" + digest}, want: "ghcr.io/acme/web" + digest},
+		{image: "mth/local:build-1", repoDigests: nil, want: ""},
+		{image: "Invalid Name", repoDigests: []string{"nginx" + digest}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := repoDigest(tt.image, tt.repoDigests); got != tt.want {
+				t.Fatalf("repoDigest = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySecurity(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  ports.SecurityProfile
+		wantUser string
+		wantOpts []string
+		wantPids *int64
+	}{
+		{
+			name: "strict profile",
+			profile: ports.SecurityProfile{
+				NoNewPrivileges: true, CapDrop: []string{"ALL"}, ReadOnlyRootfs: true,
+				Tmpfs: map[string]string{"/tmp": "rw,noexec"}, User: "65534:65534", PidsLimit: 256, SeccompProfile: `{"x":1}`,
+			},
+			wantUser: "65534:65534",
+			wantOpts: []string{"no-new-privileges:true", `seccomp={"x":1}`},
+			wantPids: ptr(int64(256)),
+		},
+		{
+			name:    "relaxed profile keeps docker defaults",
+			profile: ports.SecurityProfile{CapAdd: []string{"NET_BIND_SERVICE"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &container.Config{User: "root"}
+			host := &container.HostConfig{}
+			applySecurity(cfg, host, tt.profile)
+
+			if cfg.User != tt.wantUser {
+				t.Fatalf("user = %q, want %q", cfg.User, tt.wantUser)
+			}
+			if !reflect.DeepEqual(host.SecurityOpt, tt.wantOpts) {
+				t.Fatalf("security opts = %v, want %v", host.SecurityOpt, tt.wantOpts)
+			}
+			if !reflect.DeepEqual(host.PidsLimit, tt.wantPids) {
+				t.Fatalf("pids limit = %v, want %v", host.PidsLimit, tt.wantPids)
+			}
+			if host.ReadonlyRootfs != tt.profile.ReadOnlyRootfs || !reflect.DeepEqual(host.CapDrop, strslice.StrSlice(tt.profile.CapDrop)) ||
+				!reflect.DeepEqual(host.CapAdd, strslice.StrSlice(tt.profile.CapAdd)) || !reflect.DeepEqual(host.Tmpfs, tt.profile.Tmpfs) {
+				t.Fatalf("host config = %+v does not match profile %+v", host, tt.profile)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,118 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/errdefs"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// managedNetworkLabel menandai network tenant yang dibuat platform; network lain tidak pernah dihapus
+const managedNetworkLabel = "mth.managed"
+
+// ensureNetwork membuat bridge network tenant jika belum ada lalu menyambungkan reverse proxy ke dalamnya.
+// Dipanggil dengan networkMu terkunci.
+func (d *DockerClient) ensureNetwork(ctx context.Context, name string) error {
+	ctx, span := startSpan(ctx, "network_ensure", attribute.String("docker.network", name))
+	defer span.End()
+
+	_, err := d.cli.NetworkInspect(ctx, name, types.NetworkInspectOptions{})
+	if errdefs.IsNotFound(err) {
+		_, err = d.cli.NetworkCreate(ctx, name, types.NetworkCreate{
+			Driver: "bridge",
+			Labels: map[string]string{managedNetworkLabel: "true"},
+		})
+		if err == nil {
+			slog.InfoContext(ctx, "tenant network created", slog.String("network", name))
+		}
+	}
+	if err != nil {
+		return d.track(ctx, "network_ensure", err)
+	}
+	return d.connectProxy(ctx, name)
+}
+
+// connectProxy Traefik harus ada di network tenant supaya bisa meneruskan request ke container
+func (d *DockerClient) connectProxy(ctx context.Context, name string) error {
+	if d.proxyContainer == "" {
+		return nil
+	}
+	proxy, err := d.cli.ContainerInspect(ctx, d.proxyContainer)
+	if err != nil {
+		return d.track(ctx, "network_connect", err)
+	}
+	if proxy.NetworkSettings != nil {
+		if _, ok := proxy.NetworkSettings.Networks[name]; ok {
+			return nil
+		}
+	}
+	if err := d.cli.NetworkConnect(ctx, name, proxy.ID, nil); err != nil && !errdefs.IsConflict(err) && !errdefs.IsForbidden(err) {
+		return d.track(ctx, "network_connect", err)
+	}
+	return nil
+}
+
+// RemoveNetworkIfUnused implementasi ports.ContainerRuntime. Network tetap dipertahankan selama masih
+// ada container tenant (termasuk yang berhenti) yang memakainya; reverse proxy dilepas lebih dulu.
+func (d *DockerClient) RemoveNetworkIfUnused(ctx context.Context, name string) error {
+	ctx, span := startSpan(ctx, "network_remove", attribute.String("docker.network", name))
+	defer span.End()
+
+	d.networkMu.Lock()
+	defer d.networkMu.Unlock()
+
+	network, err := d.cli.NetworkInspect(ctx, name, types.NetworkInspectOptions{})
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return d.track(ctx, "network_remove", err)
+	}
+	if network.Labels[managedNetworkLabel] != "true" {
+		return nil
+	}
+
+	containers, err := d.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("network", network.ID)),
+	})
+	if err != nil {
+		return d.track(ctx, "network_remove", err)
+	}
+	var proxyID string
+	for _, c := range containers {
+		if d.isProxy(c) {
+			proxyID = c.ID
+			continue
+		}
+		return nil
+	}
+
+	if proxyID != "" {
+		if err := d.cli.NetworkDisconnect(ctx, network.ID, proxyID, true); err != nil && !errdefs.IsNotFound(err) {
+			return d.track(ctx, "network_remove", err)
+		}
+	}
+	if err := d.cli.NetworkRemove(ctx, network.ID); err != nil && !errdefs.IsNotFound(err) {
+		return d.track(ctx, "network_remove", err)
+	}
+	slog.InfoContext(ctx, "tenant network removed", slog.String("network", name))
+	return nil
+}
+
+func (d *DockerClient) isProxy(c types.Container) bool {
+	if d.proxyContainer == "" {
+		return false
+	}
+	// proxyContainer boleh berupa nama atau (prefix) ID container
+	if len(d.proxyContainer) >= 12 && strings.HasPrefix(c.ID, d.proxyContainer) {
+		return true
+	}
+	return slices.Contains(c.Names, "/"+d.proxyContainer)
+}
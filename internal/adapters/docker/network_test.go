@@ -0,0 +1,173 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/config"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// networkDaemon Docker Engine API minimal untuk network tenant; setiap perubahan dicatat di calls
+type networkDaemon struct {
+	mu           sync.Mutex
+	networks     map[string]types.NetworkResource // key nama network (ID = nama)
+	containers   []types.Container                // container yang tersambung ke network
+	proxyNetwork []string                         // network tempat proxy sudah tersambung
+	calls        []string
+}
+
+func (d *networkDaemon) serve(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		w.Header().Set("Api-Version", "1.44")
+		w.Header().Set("Content-Type", "application/json")
+		var body struct {
+			Name      string
+			Labels    map[string]string
+			Container string
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		_, netPath, isNetwork := strings.Cut(r.URL.Path, "/networks/")
+		_, ctrPath, isContainer := strings.Cut(r.URL.Path, "/containers/")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			w.Write([]byte("OK"))
+		case isNetwork && netPath == "create":
+			d.networks[body.Name] = types.NetworkResource{Name: body.Name, ID: body.Name, Labels: body.Labels}
+			d.calls = append(d.calls, "create "+body.Name+" "+body.Labels[managedNetworkLabel])
+			json.NewEncoder(w).Encode(types.NetworkCreateResponse{ID: body.Name})
+		case isNetwork && strings.HasSuffix(netPath, "/connect"):
+			d.calls = append(d.calls, "connect "+strings.TrimSuffix(netPath, "/connect")+" "+body.Container)
+		case isNetwork && strings.HasSuffix(netPath, "/disconnect"):
+			d.calls = append(d.calls, "disconnect "+strings.TrimSuffix(netPath, "/disconnect")+" "+body.Container)
+		case isNetwork && r.Method == http.MethodDelete:
+			d.calls = append(d.calls, "remove "+netPath)
+		case isNetwork && r.Method == http.MethodGet:
+			nw, ok := d.networks[netPath]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"message": "network not found"})
+				return
+			}
+			json.NewEncoder(w).Encode(nw)
+		case isContainer && ctrPath == "json":
+			json.NewEncoder(w).Encode(d.containers)
+		case isContainer && strings.HasSuffix(ctrPath, "/json"):
+			networks := map[string]*network.EndpointSettings{}
+			for _, name := range d.proxyNetwork {
+				networks[name] = &network.EndpointSettings{}
+			}
+			json.NewEncoder(w).Encode(types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{ID: "proxy-id", Name: "/traefik"},
+				NetworkSettings:   &types.NetworkSettings{Networks: networks},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newNetworkClient(t *testing.T, daemon *networkDaemon, proxyContainer string) *DockerClient {
+	t.Helper()
+	srv := daemon.serve(t)
+	d, err := NewDockerClient(&recordingMetrics{}, config.DockerConfig{ProxyContainer: proxyContainer}, client.WithHost("tcp://"+srv.Listener.Addr().String()))
+	if err != nil {
+		t.Fatalf("NewDockerClient: %v", err)
+	}
+	return d
+}
+
+func TestEnsureNetwork(t *testing.T) {
+	managed := types.NetworkResource{Name: "mth-a", ID: "mth-a", Labels: map[string]string{managedNetworkLabel: "true"}}
+
+	tests := []struct {
+		name         string
+		proxy        string
+		networks     map[string]types.NetworkResource
+		proxyNetwork []string
+		wantCalls    []string
+	}{
+		{name: "creates network and connects proxy", proxy: "traefik", networks: map[string]types.NetworkResource{}, wantCalls: []string{"create mth-a true", "connect mth-a proxy-id"}},
+		{name: "no proxy configured", networks: map[string]types.NetworkResource{}, wantCalls: []string{"create mth-a true"}},
+		{name: "existing network connects missing proxy", proxy: "traefik", networks: map[string]types.NetworkResource{"mth-a": managed}, wantCalls: []string{"connect mth-a proxy-id"}},
+		{name: "proxy already connected", proxy: "traefik", networks: map[string]types.NetworkResource{"mth-a": managed}, proxyNetwork: []string{"mth-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := &networkDaemon{networks: tt.networks, proxyNetwork: tt.proxyNetwork}
+			d := newNetworkClient(t, daemon, tt.proxy)
+
+			if err := d.ensureNetwork(context.Background(), "mth-a"); err != nil {
+				t.Fatalf("ensureNetwork: %v", err)
+			}
+			if !reflect.DeepEqual(daemon.calls, tt.wantCalls) {
+				t.Fatalf("calls = %v, want %v", daemon.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRemoveNetworkIfUnused(t *testing.T) {
+	managed := map[string]types.NetworkResource{"mth-a": {Name: "mth-a", ID: "mth-a", Labels: map[string]string{managedNetworkLabel: "true"}}}
+	proxy := types.Container{ID: "proxy-id", Names: []string{"/traefik"}}
+
+	tests := []struct {
+		name       string
+		networks   map[string]types.NetworkResource
+		containers []types.Container
+		wantCalls  []string
+	}{
+		{name: "missing network", networks: map[string]types.NetworkResource{}},
+		{name: "unmanaged network is kept", networks: map[string]types.NetworkResource{"mth-a": {Name: "mth-a", ID: "mth-a"}}},
+		{name: "tenant container still attached", networks: managed, containers: []types.Container{proxy, {ID: "c1", Names: []string{"/web"}}}},
+		{name: "only proxy left", networks: managed, containers: []types.Container{proxy}, wantCalls: []string{"disconnect mth-a proxy-id", "remove mth-a"}},
+		{name: "empty network", networks: managed, wantCalls: []string{"remove mth-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := &networkDaemon{networks: tt.networks, containers: tt.containers}
+			d := newNetworkClient(t, daemon, "traefik")
+
+			if err := d.RemoveNetworkIfUnused(context.Background(), "mth-a"); err != nil {
+				t.Fatalf("RemoveNetworkIfUnused: %v", err)
+			}
+			if !reflect.DeepEqual(daemon.calls, tt.wantCalls) {
+				t.Fatalf("calls = %v, want %v", daemon.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestIsProxy(t *testing.T) {
+	tests := []struct {
+		proxy string
+		c     types.Container
+		want  bool
+	}{
+		{proxy: "traefik", c: types.Container{ID: "0123456789abcdef", Names: []string{"/traefik"}}, want: true},
+		{proxy: "0123456789ab", c: types.Container{ID: "0123456789abcdef", Names: []string{"/edge"}}, want: true},
+		{proxy: "0123", c: types.Container{ID: "0123456789abcdef", Names: []string{"/edge"}}},
+		{proxy: "traefik", c: types.Container{ID: "fedcba", Names: []string{"/web"}}},
+		{proxy: "", c: types.Container{ID: "0123456789abcdef", Names: []string{"/"}}},
+	}
+	for _, tt := range tests {
+		d := &DockerClient{proxyContainer: tt.proxy}
+		if got := d.isProxy(tt.c); got != tt.want {
+			t.Errorf("isProxy(%q, %v) = %v, want %v", tt.proxy, tt.c.Names, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	ErrInvalidURL    = errors.New("unsupported git repository URL (expected https://host/path)")
+	ErrForbiddenHost = errors.New("git repository host resolves to a private, loopback or reserved address")
+	ErrInvalidBranch = errors.New("invalid git branch name")
+)
+
+// reservedPrefixes range yang tidak boleh dijangkau clone selain loopback/private/link-local
+// (yang terakhir termasuk metadata cloud 169.254.169.254)
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 bisa menunjuk alamat IPv4 internal
+}
+
+// CLIClient implementasi ports.GitClient memakai binary git di host
+type CLIClient struct {
+	binary     string
+	allowLocal bool
+	lookupIP   func(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+// NewCLIClient allowLocal mengizinkan URL file:// dan path lokal; hanya untuk development,
+// di production tenant bisa membaca repository di filesystem control plane.
+func NewCLIClient(allowLocal bool) *CLIClient {
+	return &CLIClient{binary: "git", allowLocal: allowLocal, lookupIP: lookupIP}
+}
+
+func lookupIP(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+}
+
+// Clone shallow clone satu branch lalu mengembalikan commit SHA HEAD
+func (c *CLIClient) Clone(ctx context.Context, repoURL, branch, dir string) (commit string, err error) {
+	ctx, span := tracing.Start(ctx, "git.Clone", attribute.String("git.branch", branch))
+	defer func() { tracing.End(span, err) }()
+
+	pin, err := c.validateURL(ctx, repoURL)
+	if err != nil {
+		return "", err
+	}
+	if branch != "" && !validBranch(branch) {
+		return "", ErrInvalidBranch
+	}
+
+	// Alamat yang sudah dicek dipakai langsung oleh curl (tidak resolve ulang, jadi aman dari DNS rebinding)
+	// dan redirect tidak diikuti supaya tidak bisa dibelokkan ke host internal
+	args := []string{"-c", "http.followRedirects=false"}
+	if pin != "" {
+		args = append(args, "-c", "http.curloptResolve="+pin)
+	}
+	args = append(args, "clone", "--depth", "1", "--single-branch", "--no-tags")
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	args = append(args, "--", repoURL, dir)
+	if _, err := c.run(ctx, "", args...); err != nil {
+		return "", err
+	}
+
+	out, err := c.run(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (c *CLIClient) run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, c.binary, args...)
+	cmd.Dir = dir
+	cmd.Env = c.env()
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", gitCommand(args), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// env lingkungan minimal untuk git: tanpa env server (credential, proxy, socket agent), tanpa
+// config system/global, tidak pernah prompt, dan hanya protokol yang diizinkan (juga untuk submodule)
+func (c *CLIClient) env() []string {
+	protocols := "https"
+	if c.allowLocal {
+		protocols += ":file"
+	}
+	return []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + os.TempDir(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL=/dev/null",
+		"GIT_ALLOW_PROTOCOL=" + protocols,
+	}
+}
+
+// gitCommand subcommand git (melewati opsi -c di depan) untuk pesan error
+func gitCommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-c" {
+			i++
+			continue
+		}
+		return args[i]
+	}
+	return ""
+}
+
+// validateURL hanya https ke host publik. Mengembalikan entry curl resolve (host:port:addr) untuk
+// alamat yang sudah dicek; kosong untuk repository lokal.
+func (c *CLIClient) validateURL(ctx context.Context, repoURL string) (string, error) {
+	if repoURL == "" || strings.HasPrefix(repoURL, "-") {
+		return "", ErrInvalidURL
+	}
+
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+	switch u.Scheme {
+	case "https":
+	case "file", "":
+		if c.allowLocal {
+			return "", nil
+		}
+		return "", ErrInvalidURL
+	default:
+		return "", ErrInvalidURL
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return "", ErrInvalidURL
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	addrs, err := c.lookupIP(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return "", fmt.Errorf("resolve git host %s: %w", host, err)
+	}
+	// Semua alamat harus publik: curl bisa memilih yang mana saja
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return "", ErrForbiddenHost
+		}
+	}
+	addr := addrs[0].Unmap().String()
+	if addrs[0].Unmap().Is6() {
+		addr = "[" + addr + "]"
+	}
+	return host + ":" + port + ":" + addr, nil
+}
+
+// publicAddr false untuk loopback, private, link-local (termasuk metadata cloud), multicast dan range reserved
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// validBranch subset aman dari aturan git check-ref-format
+func validBranch(branch string) bool {
+	if strings.HasPrefix(branch, "-") || strings.Contains(branch, "..") || len(branch) > 100 {
+		return false
+	}
+	for _, r := range branch {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '/' || r == '.' || r == '_' || r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
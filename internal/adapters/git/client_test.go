@@ -0,0 +1,234 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runGit menjalankan git untuk menyiapkan fixture (identitas commit di-set lewat env)
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// bareRepo repository bare berisi branch main dan feature; mengembalikan path dan SHA tiap branch
+func bareRepo(t *testing.T) (string, map[string]string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	work := filepath.Join(root, "work")
+	runGit(t, root, "init", "--bare", "--initial-branch=main", remote)
+	runGit(t, root, "init", "--initial-branch=main", work)
+
+	commit := func(file, content string) string {
+		if err := os.WriteFile(filepath.Join(work, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, work, "add", file)
+		runGit(t, work, "commit", "-m", "add "+file)
+		return runGit(t, work, "rev-parse", "HEAD")
+	}
+
+	heads := map[string]string{"main": commit("Dockerfile", "FROM scratch\n")}
+	runGit(t, work, "checkout", "-b", "feature")
+	heads["feature"] = commit("feature.txt", "feature\n")
+	runGit(t, work, "push", remote, "main", "feature")
+	return remote, heads
+}
+
+func TestCloneCheckoutByRef(t *testing.T) {
+	remote, heads := bareRepo(t)
+	c := NewCLIClient(true)
+
+	tests := []struct {
+		branch     string
+		wantCommit string
+		wantFile   string
+		absentFile string
+	}{
+		{branch: "", wantCommit: heads["main"], wantFile: "Dockerfile", absentFile: "feature.txt"},
+		{branch: "main", wantCommit: heads["main"], wantFile: "Dockerfile", absentFile: "feature.txt"},
+		{branch: "feature", wantCommit: heads["feature"], wantFile: "feature.txt"},
+	}
+	for _, tt := range tests {
+		t.Run("branch="+tt.branch, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "src")
+			commit, err := c.Clone(context.Background(), "file://"+remote, tt.branch, dir)
+			if err != nil {
+				t.Fatalf("clone: %v", err)
+			}
+			if commit != tt.wantCommit {
+				t.Fatalf("commit = %s, want %s", commit, tt.wantCommit)
+			}
+			if _, err := os.Stat(filepath.Join(dir, tt.wantFile)); err != nil {
+				t.Fatalf("%s not checked out: %v", tt.wantFile, err)
+			}
+			if tt.absentFile != "" {
+				if _, err := os.Stat(filepath.Join(dir, tt.absentFile)); !errors.Is(err, os.ErrNotExist) {
+					t.Fatalf("%s from another branch is checked out", tt.absentFile)
+				}
+			}
+		})
+	}
+}
+
+func TestCloneRejectsBadRefs(t *testing.T) {
+	remote, _ := bareRepo(t)
+	c := NewCLIClient(true)
+
+	tests := []struct {
+		name    string
+		branch  string
+		wantErr error // nil = cukup error apa pun dari git
+	}{
+		{name: "option injection", branch: "--upload-pack=touch /tmp/pwned", wantErr: ErrInvalidBranch},
+		{name: "dash prefix", branch: "-b", wantErr: ErrInvalidBranch},
+		{name: "parent traversal", branch: "main..feature", wantErr: ErrInvalidBranch},
+		{name: "shell characters", branch: "main;id", wantErr: ErrInvalidBranch},
+		{name: "unknown branch", branch: "does-not-exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "src")
+			_, err := c.Clone(context.Background(), "file://"+remote, tt.branch, dir)
+			if err == nil {
+				t.Fatal("clone succeeded")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCloneValidatesURL(t *testing.T) {
+	remote, _ := bareRepo(t)
+
+	tests := []struct {
+		name       string
+		allowLocal bool
+		url        string
+		wantErr    bool
+	}{
+		{name: "local path allowed in development", allowLocal: true, url: remote},
+		{name: "file URL rejected in production", url: "file://" + remote, wantErr: true},
+		{name: "local path rejected in production", url: remote, wantErr: true},
+		{name: "option injection", allowLocal: true, url: "--upload-pack=id", wantErr: true},
+		{name: "unsupported scheme", url: "ftp://example.com/repo.git", wantErr: true},
+		{name: "plain http", url: "http://example.com/repo.git", wantErr: true},
+		{name: "git protocol", url: "git://example.com/repo.git", wantErr: true},
+		{name: "ssh URL", url: "ssh://git@example.com/repo.git", wantErr: true},
+		{name: "scp-like ssh", url: "git@example.com:org/repo.git", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "src")
+			_, err := NewCLIClient(tt.allowLocal).Clone(context.Background(), tt.url, "", dir)
+			if tt.wantErr && !errors.Is(err, ErrInvalidURL) {
+				t.Fatalf("err = %v, want ErrInvalidURL", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("clone: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateURLResolvesPublicHostsOnly(t *testing.T) {
+	hosts := map[string][]string{
+		"git.example.com":   {"93.184.216.34"},
+		"v6.example.com":    {"2606:2800:220:1::1"},
+		"mixed.example.com": {"93.184.216.34", "10.0.0.5"},
+		"internal.example":  {"10.1.2.3"},
+		"rebind.example":    {"127.0.0.1"},
+		"metadata.example":  {"169.254.169.254"},
+		"ula.example":       {"fd00:ec2::254"},
+		"cgnat.example":     {"100.64.0.1"},
+		"mapped.example":    {"::ffff:192.168.1.1"},
+	}
+	c := NewCLIClient(false)
+	c.lookupIP = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		if addr, err := netip.ParseAddr(host); err == nil {
+			return []netip.Addr{addr}, nil
+		}
+		var addrs []netip.Addr
+		for _, a := range hosts[host] {
+			addrs = append(addrs, netip.MustParseAddr(a))
+		}
+		return addrs, nil
+	}
+
+	tests := []struct {
+		url     string
+		wantPin string
+		wantErr error
+	}{
+		{url: "https://git.example.com/org/repo.git", wantPin: "git.example.com:443:93.184.216.34"},
+		{url: "https://token@git.example.com:8443/org/repo.git", wantPin: "git.example.com:8443:93.184.216.34"},
+		{url: "https://v6.example.com/repo.git", wantPin: "v6.example.com:443:[2606:2800:220:1::1]"},
+		{url: "https://mixed.example.com/repo.git", wantErr: ErrForbiddenHost},
+		{url: "https://internal.example/repo.git", wantErr: ErrForbiddenHost},
+		{url: "https://rebind.example/repo.git", wantErr: ErrForbiddenHost},
+		{url: "https://metadata.example/latest/meta-data", wantErr: ErrForbiddenHost},
+		{url: "https://ula.example/repo.git", wantErr: ErrForbiddenHost},
+		{url: "https://cgnat.example/repo.git", wantErr: ErrForbiddenHost},
+		{url: "https://mapped.example/repo.git", wantErr: ErrForbiddenHost},
+		{url: "https://127.0.0.1/repo.git", wantErr: ErrForbiddenHost},
+		{url: "https://[::1]/repo.git", wantErr: ErrForbiddenHost},
+		{url: "https://169.254.169.254/repo.git", wantErr: ErrForbiddenHost},
+		{url: "https:///repo.git", wantErr: ErrInvalidURL},
+	}
+	for _, tt := range tests {
+		pin, err := c.validateURL(context.Background(), tt.url)
+		if !errors.Is(err, tt.wantErr) || pin != tt.wantPin {
+			t.Errorf("validateURL(%q) = %q, %v; want %q, %v", tt.url, pin, err, tt.wantPin, tt.wantErr)
+		}
+	}
+}
+
+func TestGitEnvIsMinimal(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "secret")
+	t.Setenv("GIT_SSH_COMMAND", "ssh -i /root/.ssh/id_rsa")
+	t.Setenv("HTTPS_PROXY", "http://proxy.internal:3128")
+
+	tests := []struct {
+		allowLocal bool
+		protocols  string
+	}{
+		{protocols: "GIT_ALLOW_PROTOCOL=https"},
+		{allowLocal: true, protocols: "GIT_ALLOW_PROTOCOL=https:file"},
+	}
+	for _, tt := range tests {
+		env := strings.Join(NewCLIClient(tt.allowLocal).env(), "\n")
+		for _, leaked := range []string{"GITHUB_TOKEN", "GIT_SSH_COMMAND", "HTTPS_PROXY"} {
+			if strings.Contains(env, leaked) {
+				t.Errorf("server env %s passed to git:\n%s", leaked, env)
+			}
+		}
+		for _, want := range []string{"GIT_TERMINAL_PROMPT=0", "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL=/dev/null", tt.protocols} {
+			if !strings.Contains(env+"\n", want+"\n") {
+				t.Errorf("git env missing %s:\n%s", want, env)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// parseAuditFilter membaca ?action=&actor_id=&target_type=&target_id=&outcome=&since=&until=&limit=&offset=
+// (since/until format RFC3339, limit default 50, maks 200)
+func parseAuditFilter(c *gin.Context) (ports.AuditFilter, bool) {
+	filter := ports.AuditFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		Outcome:    c.Query("outcome"),
+		Limit:      defaultPageSize,
+	}
+
+	if v := c.Query("actor_id"); v != "" {
+		actorID, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid actor_id"})
+			return filter, false
+		}
+		filter.ActorID = &actorID
+	}
+	for param, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + " (expected RFC3339)"})
+				return filter, false
+			}
+			*dst = t
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return filter, false
+		}
+		filter.Limit = min(limit, maxPageSize)
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+			return filter, false
+		}
+		filter.Offset = offset
+	}
+	return filter, true
+}
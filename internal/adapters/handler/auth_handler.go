@@ -1,19 +1,23 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
-	"os"
+	"time"
 
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
 	"github.com/damantine/multi-tenant-hosting/internal/core/services"
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	svc *services.AuthService
+	svc        *services.AuthService
+	throttle   *services.LoginThrottle
+	baseDomain string
 }
 
-func NewAuthHandler(svc *services.AuthService) *AuthHandler {
-	return &AuthHandler{svc: svc}
+func NewAuthHandler(svc *services.AuthService, throttle *services.LoginThrottle, baseDomain string) *AuthHandler {
+	return &AuthHandler{svc: svc, throttle: throttle, baseDomain: baseDomain}
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -24,7 +28,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	if err := h.svc.Register(c.Request.Context(), input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondAccountError(c, err)
 		return
 	}
 
@@ -41,33 +45,350 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.svc.Login(c.Request.Context(), input.Username, input.Password)
+	// Username yang sedang dikunci ditolak sebelum password dicek
+	if retryAfter, err := h.throttle.CheckLockout(c.Request.Context(), input.Username); err != nil {
+		abortRateLimited(c, retryAfter)
+		return
+	}
+
+	result, err := h.svc.Login(c.Request.Context(), input.Username, input.Password, clientInfo(c))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			if lockout := h.throttle.LoginFailed(c.Request.Context(), input.Username); lockout > 0 {
+				abortRateLimited(c, lockout)
+				return
+			}
+		}
+		if errors.Is(err, services.ErrAccountSuspended) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	// Counter kegagalan baru di-reset setelah login benar-benar selesai (termasuk langkah 2FA)
+	if result.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":    true,
+			"challenge_token": result.ChallengeToken,
+		})
+		return
+	}
+	h.throttle.LoginSucceeded(c.Request.Context(), input.Username)
+	c.JSON(http.StatusOK, result.Tokens)
+}
+
+// mfaAttemptLimit percobaan kode per challenge token; setelah itu user harus login ulang dengan password
+var mfaAttemptLimit = services.RateLimitPolicy{Name: "mfa-challenge", Limit: 5, Window: 5 * time.Minute}
+
+// MFAChallenge langkah kedua login saat 2FA aktif. Kode yang salah dihitung ke lockout username
+// yang sama dengan login password, jadi ganti-ganti IP tidak menambah jumlah tebakan.
+func (h *AuthHandler) MFAChallenge(c *gin.Context) {
+	var input struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Code == "" && input.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code or recovery_code is required"})
+		return
+	}
+
+	challenge, err := h.svc.ParseChallenge(input.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if retryAfter, err := h.throttle.CheckLockout(c.Request.Context(), challenge.Username); err != nil {
+		abortRateLimited(c, retryAfter)
+		return
+	}
+	if retryAfter, err := h.throttle.Allow(c.Request.Context(), mfaAttemptLimit, challenge.ID); err != nil {
+		abortRateLimited(c, retryAfter)
+		return
+	}
+
+	tokens, err := h.svc.CompleteMFA(c.Request.Context(), input.ChallengeToken, input.Code, input.RecoveryCode, clientInfo(c))
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidTOTPCode) {
+			if lockout := h.throttle.LoginFailed(c.Request.Context(), challenge.Username); lockout > 0 {
+				abortRateLimited(c, lockout)
+				return
+			}
+		}
+		if errors.Is(err, services.ErrAccountSuspended) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
+	h.throttle.LoginSucceeded(c.Request.Context(), challenge.Username)
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	enrollment, err := h.svc.EnrollTOTP(c.Request.Context(), getUserID(c))
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *AuthHandler) VerifyTOTP(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	codes, err := h.svc.VerifyTOTP(c.Request.Context(), getUserID(c), input.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "recovery_codes": codes})
+}
+
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.DisableTOTP(c.Request.Context(), getUserID(c), input.Code); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": false})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.svc.RegenerateRecoveryCodes(c.Request.Context(), getUserID(c), input.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
 }
 
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTOTPCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTOTPNotEnrolled), errors.Is(err, services.ErrTOTPAlreadyEnabled), errors.Is(err, services.ErrTOTPNotEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTwoFactorEnforcedOrg):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.svc.Refresh(c.Request.Context(), input.RefreshToken, clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := h.svc.Logout(c.Request.Context(), getSessionID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	if err := h.svc.LogoutAll(c.Request.Context(), getUserID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out from all devices"})
+}
+
+// VerifyEmail dipanggil frontend dengan token dari link verifikasi
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.VerifyEmail(c.Request.Context(), input.Token); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	if err := h.svc.SendVerificationEmail(c.Request.Context(), getUserID(c)); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+// ForgotPassword selalu 202 supaya tidak membocorkan email mana yang terdaftar
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.RequestPasswordReset(c.Request.Context(), input.Email); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the email is registered, a reset link has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var input struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.ResetPassword(c.Request.Context(), input.Token, input.Password); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset, please log in again"})
+}
+
+func respondAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidToken), errors.Is(err, services.ErrWeakPassword), errors.Is(err, services.ErrInvalidUsername):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEmailAlreadyVerified), errors.Is(err, services.ErrUsernameTaken), errors.Is(err, services.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// JWKS public key (format JWK Set) supaya service internal lain bisa memverifikasi access token
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.svc.JWKS())
+}
+
+func clientInfo(c *gin.Context) services.ClientInfo {
+	return services.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
+
+// Me profil user yang sedang login
 func (h *AuthHandler) Me(c *gin.Context) {
-	// Ambil userID dari context (diset oleh AuthMiddleware)
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	user, err := h.svc.GetProfile(c.Request.Context(), getUserID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 		return
 	}
 
-	// Idealnya fetch user detail dari DB via AuthService.
-	// Untuk sekarang kembalikan ID saja.
-	baseDomain := os.Getenv("BASE_DOMAIN")
-	if baseDomain == "" {
-		baseDomain = "localhost"
+	c.JSON(http.StatusOK, profileResponse(user, h.baseDomain))
+}
+
+// UpdateMe mengubah username dan/atau email
+func (h *AuthHandler) UpdateMe(c *gin.Context) {
+	var input services.UpdateProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.svc.UpdateProfile(c.Request.Context(), getUserID(c), input)
+	if err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profileResponse(user, h.baseDomain))
+}
+
+// ChangePassword butuh password lama; session lain otomatis di-logout
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var input struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":          userID,
-		"username":    "User (ID: " + userID.(interface{ String() string }).String()[:8] + ")", // Placeholder name
-		"base_domain": baseDomain,
-	})
+	if err := h.svc.ChangePassword(c.Request.Context(), getUserID(c), getSessionID(c), input.CurrentPassword, input.NewPassword); err != nil {
+		respondAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed"})
+}
+
+func profileResponse(user *domain.User, baseDomain string) gin.H {
+	return gin.H{
+		"id":                 user.ID,
+		"username":           user.Username,
+		"email":              user.Email,
+		"email_verified":     user.EmailVerified,
+		"two_factor_enabled": user.TOTPEnabled,
+		"is_platform_admin":  user.IsPlatformAdmin,
+		"created_at":         user.CreatedAt,
+		"base_domain":        baseDomain,
+	}
 }
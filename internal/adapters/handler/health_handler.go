@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	svc *services.HealthService
+}
+
+func NewHealthHandler(svc *services.HealthService) *HealthHandler {
+	return &HealthHandler{svc: svc}
+}
+
+// Liveness hanya memastikan proses masih bisa melayani HTTP, tanpa cek dependency
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness cek Postgres, Docker daemon dan status migrasi
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	ready, checks := h.svc.Readiness(c.Request.Context())
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
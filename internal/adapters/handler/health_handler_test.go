@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHealthHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dockerErr := errors.New("docker down")
+	var docker error
+	svc := services.NewHealthService(time.Second, services.HealthCheck{
+		Name:  "docker",
+		Check: func(ctx context.Context) error { return docker },
+	})
+	h := NewHealthHandler(svc)
+	r := gin.New()
+	r.GET("/healthz", h.Liveness)
+	r.GET("/readyz", h.Readiness)
+
+	get := func(path string) (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]any
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	// Liveness tidak bergantung pada dependency maupun migrasi
+	docker = dockerErr
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("healthz = %d, want 200", code)
+	}
+
+	tests := []struct {
+		name   string
+		ready  bool
+		docker error
+		want   int
+	}{
+		{name: "before migrations", ready: false, want: http.StatusServiceUnavailable},
+		{name: "dependency down", ready: true, docker: dockerErr, want: http.StatusServiceUnavailable},
+		{name: "ready", ready: true, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.ready {
+				svc.MarkReady()
+			} else {
+				svc.MarkNotReady()
+			}
+			docker = tt.docker
+
+			code, body := get("/readyz")
+			if code != tt.want {
+				t.Fatalf("readyz = %d, want %d (%v)", code, tt.want, body)
+			}
+			if _, ok := body["checks"].(map[string]any)["docker"]; !ok {
+				t.Fatalf("readyz body %v lacks per-dependency checks", body)
+			}
+		})
+	}
+}
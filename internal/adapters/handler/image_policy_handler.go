@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ImagePolicyHandler endpoint platform admin untuk policy image (platform & per plan)
+type ImagePolicyHandler struct {
+	svc *services.ImagePolicyService
+}
+
+func NewImagePolicyHandler(svc *services.ImagePolicyService) *ImagePolicyHandler {
+	return &ImagePolicyHandler{svc: svc}
+}
+
+func (h *ImagePolicyHandler) List(c *gin.Context) {
+	policies, err := h.svc.ListPolicies(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// PutPlatform policy yang berlaku untuk semua tenant
+func (h *ImagePolicyHandler) PutPlatform(c *gin.Context) {
+	h.put(c, "")
+}
+
+// PutPlan policy tambahan untuk tenant dengan plan :plan
+func (h *ImagePolicyHandler) PutPlan(c *gin.Context) {
+	h.put(c, c.Param("plan"))
+}
+
+func (h *ImagePolicyHandler) DeletePlatform(c *gin.Context) {
+	h.delete(c, "")
+}
+
+func (h *ImagePolicyHandler) DeletePlan(c *gin.Context) {
+	h.delete(c, c.Param("plan"))
+}
+
+func (h *ImagePolicyHandler) put(c *gin.Context, plan string) {
+	var input struct {
+		AllowedRegistries   []string `json:"allowed_registries"`
+		BlockedRepositories []string `json:"blocked_repositories"`
+		RequireDigest       bool     `json:"require_digest"`
+		MaxImageSizeMB      int64    `json:"max_image_size_mb" binding:"min=0"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.svc.PutPolicy(c.Request.Context(), getUserID(c), plan, services.ImagePolicyInput{
+		AllowedRegistries:   input.AllowedRegistries,
+		BlockedRepositories: input.BlockedRepositories,
+		RequireDigest:       input.RequireDigest,
+		MaxImageSizeMB:      input.MaxImageSizeMB,
+	})
+	if err != nil {
+		respondImagePolicyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *ImagePolicyHandler) delete(c *gin.Context, plan string) {
+	if err := h.svc.DeletePolicy(c.Request.Context(), plan); err != nil {
+		respondImagePolicyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "image policy deleted"})
+}
+
+func respondImagePolicyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPlan), errors.Is(err, services.ErrInvalidImagePolicy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrImagePolicyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,15 +1,25 @@
 package handler
 
 import (
+	"errors"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
 	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/damantine/multi-tenant-hosting/internal/logging"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func AuthMiddleware(authSvc *services.AuthService) gin.HandlerFunc {
+// AuthMiddleware menerima JWT (login interaktif) maupun API token personal (prefix "mth_").
+// Untuk API token, scope-nya disimpan di context dan dicek per route oleh RequireScope.
+func AuthMiddleware(authSvc *services.AuthService, tokenSvc *services.TokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -23,13 +33,161 @@ func AuthMiddleware(authSvc *services.AuthService) gin.HandlerFunc {
 			return
 		}
 
-		userID, err := authSvc.ValidateToken(parts[1])
+		var userID uuid.UUID
+		if strings.HasPrefix(parts[1], services.APITokenPrefix) {
+			token, err := tokenSvc.ValidateToken(c.Request.Context(), parts[1])
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				return
+			}
+			userID = token.UserID
+			c.Set("apiTokenID", token.ID)
+			c.Set("scopes", token.Scopes)
+		} else {
+			claims, err := authSvc.ValidateToken(c.Request.Context(), parts[1])
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				return
+			}
+			userID = claims.UserID
+			c.Set("sessionID", claims.SessionID)
+			if claims.ImpersonatorID != nil {
+				c.Set("impersonatorID", *claims.ImpersonatorID)
+				c.Request = c.Request.WithContext(logging.With(c.Request.Context(), slog.String(logging.KeyImpersonator, claims.ImpersonatorID.String())))
+			}
+		}
+
+		c.Set("userID", userID)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), slog.String(logging.KeyUserID, userID.String())))
+
+		actor := services.ActorFromContext(c.Request.Context())
+		actor.UserID = userID
+		if impersonatorID, ok := getImpersonatorID(c); ok {
+			actor.ImpersonatorID = &impersonatorID
+		}
+		c.Request = c.Request.WithContext(services.WithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
+
+// RequireScope membatasi route untuk API token sesuai scope-nya. Login JWT tidak dibatasi.
+// Untuk route dengan :id, scope boleh menyebut ID atau subdomain project.
+func RequireScope(action string, projectSvc *services.ProjectService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, isToken := getScopes(c)
+		if !isToken {
+			c.Next()
+			return
+		}
+
+		var resources []string
+		if idStr := c.Param("id"); idStr != "" {
+			resources = append(resources, idStr)
+			if id, err := uuid.Parse(idStr); err == nil {
+				if project, err := projectSvc.GetProject(c.Request.Context(), id); err == nil {
+					resources = append(resources, project.Subdomain)
+				}
+			}
+		}
+
+		if !domain.ScopeAllows(scopes, action, resources...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token scope does not allow " + action + " on this resource"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireProjectRole memastikan user adalah member organization pemilik project (:id)
+// dengan minimal role tertentu. Non-member mendapat 404 supaya keberadaan project tidak bocor.
+func RequireProjectRole(min domain.Role, projectSvc *services.ProjectService, orgSvc *services.OrganizationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 			return
 		}
 
-		c.Set("userID", userID)
+		project, err := projectSvc.GetProject(c.Request.Context(), id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+
+		role, err := orgSvc.Authorize(c.Request.Context(), project.OrganizationID, getUserID(c), min)
+		if err != nil {
+			abortWithOrgError(c, err)
+			return
+		}
+		c.Set("projectRole", role)
+		c.Next()
+	}
+}
+
+// RequireOrgRole sama seperti RequireProjectRole tapi untuk route /orgs/:id
+func RequireOrgRole(min domain.Role, orgSvc *services.OrganizationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+
+		if _, err := orgSvc.Authorize(c.Request.Context(), id, getUserID(c), min); err != nil {
+			abortWithOrgError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+func abortWithOrgError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotMember):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, services.ErrForbidden), errors.Is(err, services.ErrTwoFactorRequired):
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// RequireNotImpersonated menolak session impersonation untuk aksi keamanan akun
+// (ganti password, 2FA, token, dan API admin)
+func RequireNotImpersonated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonated := getImpersonatorID(c); impersonated {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this action is not allowed while impersonating"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePlatformAdmin hanya untuk platform admin (dicek ke DB setiap request supaya pencabutan langsung berlaku)
+func RequirePlatformAdmin(adminSvc *services.AdminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, err := adminSvc.IsAdmin(c.Request.Context(), getUserID(c))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": services.ErrNotPlatformAdmin.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession menolak API token untuk route yang hanya boleh lewat login interaktif
+// (mis. manajemen token dan logout)
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isToken := getScopes(c); isToken {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this endpoint requires an interactive login"})
+			return
+		}
 		c.Next()
 	}
 }
@@ -38,3 +196,130 @@ func getUserID(c *gin.Context) uuid.UUID {
 	id, _ := c.Get("userID")
 	return id.(uuid.UUID)
 }
+
+func getSessionID(c *gin.Context) uuid.UUID {
+	id, _ := c.Get("sessionID")
+	return id.(uuid.UUID)
+}
+
+// getImpersonatorID ID admin jika request memakai session impersonation
+func getImpersonatorID(c *gin.Context) (uuid.UUID, bool) {
+	id, exists := c.Get("impersonatorID")
+	if !exists {
+		return uuid.Nil, false
+	}
+	return id.(uuid.UUID), true
+}
+
+// getProjectRole role user di organization pemilik project; diisi RequireProjectRole
+func getProjectRole(c *gin.Context) domain.Role {
+	role, _ := c.Get("projectRole")
+	r, _ := role.(domain.Role)
+	return r
+}
+
+func getScopes(c *gin.Context) ([]string, bool) {
+	scopes, exists := c.Get("scopes")
+	if !exists {
+		return nil, false
+	}
+	return scopes.([]string), true
+}
+
+// RequireMigrated menolak request (503) sampai migrasi startup selesai; probe tetap dilayani
+// supaya /healthz dan /readyz bisa dijawab selama migrasi
+func RequireMigrated(health *services.HealthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if health.Migrated() || c.Request.URL.Path == "/healthz" || c.Request.URL.Path == "/readyz" {
+			c.Next()
+			return
+		}
+		c.Header("Retry-After", "5")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is starting, try again shortly"})
+	}
+}
+
+// Tracing membuat span server per request (nama span = method + route) dari TracerProvider global;
+// endpoint probe tidak di-trace
+func Tracing() gin.HandlerFunc {
+	return otelgin.Middleware("multi-tenant-hosting", otelgin.WithFilter(func(req *http.Request) bool {
+		return req.URL.Path != "/healthz" && req.URL.Path != "/readyz"
+	}))
+}
+
+// RequestID memakai header X-Request-ID dari client (atau generate baru)
+// lalu menaruhnya di context request dan header response
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.NewString()
+		}
+
+		c.Header("X-Request-ID", requestID)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), slog.String(logging.KeyRequestID, requestID)))
+		c.Next()
+	}
+}
+
+// AuditContext menaruh IP dan User-Agent client di context untuk audit log.
+// User ID actor ditambahkan AuthMiddleware setelah token tervalidasi.
+func AuditContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(services.WithActor(c.Request.Context(), services.Actor{
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}))
+		c.Next()
+	}
+}
+
+// RequestLogger pengganti logger bawaan gin, menulis satu baris JSON per request
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		level := slog.LevelInfo
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "http request", attrs...)
+	}
+}
+
+// RateLimit membatasi jumlah request per IP client sesuai policy
+func RateLimit(throttle *services.LoginThrottle, policy services.RateLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if retryAfter, err := throttle.Allow(c.Request.Context(), policy, c.ClientIP()); err != nil {
+			abortRateLimited(c, retryAfter)
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortRateLimited balas 429 dengan header Retry-After (detik, dibulatkan ke atas)
+func abortRateLimited(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": services.ErrRateLimited.Error(), "retry_after": seconds})
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/damantine/multi-tenant-hosting/internal/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/", func(c *gin.Context) {
+		v, _ := logging.Value(c.Request.Context(), logging.KeyRequestID)
+		c.String(http.StatusOK, v.String())
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "client id is reused", header: "abc-123", keep: true},
+		{name: "missing id is generated"},
+		{name: "oversized id is replaced", header: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Request-ID", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			got := rec.Header().Get("X-Request-ID")
+			if rec.Body.String() != got {
+				t.Fatalf("context request_id %q differs from header %q", rec.Body.String(), got)
+			}
+			if tt.keep {
+				if got != tt.header {
+					t.Fatalf("X-Request-ID = %q, want %q", got, tt.header)
+				}
+				return
+			}
+			if _, err := uuid.Parse(got); err != nil {
+				t.Fatalf("X-Request-ID = %q, want a generated uuid", got)
+			}
+		})
+	}
+}
+
+func TestRequireScopeAndSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		scopes      []string // nil = login interaktif
+		wantList    int
+		wantSession int
+	}{
+		{name: "interactive login", wantList: http.StatusOK, wantSession: http.StatusOK},
+		{name: "token with wildcard read", scopes: []string{"read:*"}, wantList: http.StatusOK, wantSession: http.StatusForbidden},
+		{name: "token scoped to one project", scopes: []string{"read:web"}, wantList: http.StatusForbidden, wantSession: http.StatusForbidden},
+		{name: "token with another action", scopes: []string{"deploy:*"}, wantList: http.StatusForbidden, wantSession: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tt.scopes != nil {
+					c.Set("scopes", tt.scopes)
+				}
+			})
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			r.GET("/projects", RequireScope(domain.ScopeRead, nil), ok)
+			r.GET("/tokens", RequireSession(), ok)
+
+			for path, want := range map[string]int{"/projects": tt.wantList, "/tokens": tt.wantSession} {
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+				if rec.Code != want {
+					t.Fatalf("%s = %d, want %d", path, rec.Code, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireNotImpersonated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for name, impersonated := range map[string]bool{"own session": false, "impersonation session": true} {
+		t.Run(name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if impersonated {
+					c.Set("impersonatorID", uuid.New())
+				}
+			})
+			r.POST("/password", RequireNotImpersonated(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/password", nil))
+			want := http.StatusOK
+			if impersonated {
+				want = http.StatusForbidden
+			}
+			if rec.Code != want {
+				t.Fatalf("status = %d, want %d", rec.Code, want)
+			}
+		})
+	}
+}
+
+func TestRequireMigrated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	health := services.NewHealthService(time.Second)
+	r := gin.New()
+	r.Use(RequireMigrated(health))
+	for _, path := range []string{"/healthz", "/readyz", "/api/v1/projects"} {
+		r.GET(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+
+	tests := []struct {
+		path     string
+		migrated bool
+		want     int
+	}{
+		{path: "/healthz", want: http.StatusOK},
+		{path: "/readyz", want: http.StatusOK},
+		{path: "/api/v1/projects", want: http.StatusServiceUnavailable},
+		{path: "/api/v1/projects", migrated: true, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		if tt.migrated {
+			health.MarkReady()
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Fatalf("GET %s (migrated %v) = %d, want %d", tt.path, tt.migrated, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") == "" {
+			t.Fatalf("GET %s: 503 without Retry-After", tt.path)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+)
+
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	svc *services.OIDCService
+	// postLoginRedirect jika diisi, callback me-redirect ke URL ini dengan token di fragment
+	postLoginRedirect string
+}
+
+func NewOIDCHandler(svc *services.OIDCService, postLoginRedirect string) *OIDCHandler {
+	return &OIDCHandler{svc: svc, postLoginRedirect: postLoginRedirect}
+}
+
+// Login mengarahkan browser ke identity provider (authorization code + PKCE)
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, state, err := h.svc.BeginLogin(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	cookie, err := h.svc.EncodeState(state)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, cookie, 600, "/api/v1/auth/oidc", "", isHTTPS(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback menerima authorization code dari identity provider
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if errParam := c.Query("error"); errParam != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errParam, "description": c.Query("error_description")})
+		return
+	}
+
+	raw, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		h.respondError(c, services.ErrOIDCState)
+		return
+	}
+	// Cookie state hanya sekali pakai
+	c.SetCookie(oidcStateCookie, "", -1, "/api/v1/auth/oidc", "", isHTTPS(c), true)
+
+	state, err := h.svc.DecodeState(raw)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	result, err := h.svc.CompleteLogin(c.Request.Context(), state, c.Query("state"), c.Query("code"), clientInfo(c))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// User dengan 2FA aktif menerima challenge token, lalu melanjutkan lewat /auth/2fa/challenge
+	if h.postLoginRedirect != "" {
+		fragment := url.Values{}
+		if result.MFARequired {
+			fragment.Set("mfa_required", "true")
+			fragment.Set("challenge_token", result.ChallengeToken)
+		} else {
+			fragment.Set("token", result.Tokens.AccessToken)
+			fragment.Set("refresh_token", result.Tokens.RefreshToken)
+		}
+		c.Redirect(http.StatusFound, h.postLoginRedirect+"#"+fragment.Encode())
+		return
+	}
+	if result.MFARequired {
+		c.JSON(http.StatusOK, gin.H{"mfa_required": true, "challenge_token": result.ChallengeToken})
+		return
+	}
+	c.JSON(http.StatusOK, result.Tokens)
+}
+
+func (h *OIDCHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOIDCDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOIDCState), errors.Is(err, services.ErrOIDCEmailRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOIDCDomain), errors.Is(err, services.ErrOIDCAccountUnverified), errors.Is(err, services.ErrAccountSuspended):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	}
+}
+
+func isHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
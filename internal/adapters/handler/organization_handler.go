@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type OrganizationHandler struct {
+	svc *services.OrganizationService
+}
+
+func NewOrganizationHandler(svc *services.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{svc: svc}
+}
+
+func (h *OrganizationHandler) Create(c *gin.Context) {
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, err := h.svc.CreateOrganization(c.Request.Context(), getUserID(c), input.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, org)
+}
+
+func (h *OrganizationHandler) List(c *gin.Context) {
+	orgs, err := h.svc.ListForUser(c.Request.Context(), getUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, orgs)
+}
+
+func (h *OrganizationHandler) Update(c *gin.Context) {
+	orgID, _ := uuid.Parse(c.Param("id")) // sudah divalidasi RequireOrgRole
+
+	var input struct {
+		Name       *string `json:"name"`
+		Require2FA *bool   `json:"require_2fa"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, err := h.svc.UpdateOrganization(c.Request.Context(), orgID, getUserID(c), services.UpdateOrganizationInput{
+		Name:       input.Name,
+		Require2FA: input.Require2FA,
+	})
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+func (h *OrganizationHandler) ListMembers(c *gin.Context) {
+	orgID, _ := uuid.Parse(c.Param("id")) // sudah divalidasi RequireOrgRole
+
+	members, err := h.svc.ListMembers(c.Request.Context(), orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	orgID, _ := uuid.Parse(c.Param("id"))
+	memberID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var input struct {
+		Role domain.Role `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.UpdateMemberRole(c.Request.Context(), orgID, getUserID(c), memberID, input.Role); err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member updated"})
+}
+
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	orgID, _ := uuid.Parse(c.Param("id"))
+	memberID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.svc.RemoveMember(c.Request.Context(), orgID, getUserID(c), memberID); err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed"})
+}
+
+func (h *OrganizationHandler) Invite(c *gin.Context) {
+	orgID, _ := uuid.Parse(c.Param("id"))
+
+	var input struct {
+		Email string      `json:"email" binding:"required"`
+		Role  domain.Role `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	inv, token, err := h.svc.Invite(c.Request.Context(), orgID, getUserID(c), input.Email, input.Role)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"invitation": inv,
+		"token":      token,
+	})
+}
+
+func (h *OrganizationHandler) ListInvitations(c *gin.Context) {
+	orgID, _ := uuid.Parse(c.Param("id"))
+
+	invs, err := h.svc.ListInvitations(c.Request.Context(), orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, invs)
+}
+
+func (h *OrganizationHandler) RevokeInvitation(c *gin.Context) {
+	orgID, _ := uuid.Parse(c.Param("id"))
+	invID, err := uuid.Parse(c.Param("invitation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation id"})
+		return
+	}
+
+	if err := h.svc.RevokeInvitation(c.Request.Context(), orgID, invID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "invitation revoked"})
+}
+
+func (h *OrganizationHandler) AcceptInvitation(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	membership, err := h.svc.AcceptInvitation(c.Request.Context(), getUserID(c), input.Token)
+	if err != nil {
+		respondOrgError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, membership)
+}
+
+// respondOrgError memetakan error OrganizationService ke status HTTP
+func (h *OrganizationHandler) ListAudit(c *gin.Context) {
+	orgID, _ := uuid.Parse(c.Param("id")) // sudah divalidasi RequireOrgRole
+
+	filter, ok := parseAuditFilter(c)
+	if !ok {
+		return
+	}
+
+	events, total, err := h.svc.ListAudit(c.Request.Context(), orgID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events, "total": total, "limit": filter.Limit, "offset": filter.Offset})
+}
+
+func respondOrgError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrLastOwner), errors.Is(err, services.ErrInvalidToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvitationEmail), errors.Is(err, services.ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		abortWithOrgError(c, err)
+	}
+}
@@ -1,27 +1,54 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
 	"github.com/damantine/multi-tenant-hosting/internal/core/services"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type ProjectHandler struct {
-	svc *services.ProjectService
+	svc      *services.ProjectService
+	buildSvc *services.BuildService
+	orgSvc   *services.OrganizationService
+	stats    *services.StatsCollector
 }
 
-func NewProjectHandler(svc *services.ProjectService) *ProjectHandler {
-	return &ProjectHandler{svc: svc}
+func NewProjectHandler(svc *services.ProjectService, buildSvc *services.BuildService, orgSvc *services.OrganizationService, stats *services.StatsCollector) *ProjectHandler {
+	return &ProjectHandler{svc: svc, buildSvc: buildSvc, orgSvc: orgSvc, stats: stats}
+}
+
+// gitSourceInput sumber Git opsional pada create/update project
+type gitSourceInput struct {
+	URL        string `json:"url" binding:"required,max=255"`
+	Branch     string `json:"branch" binding:"max=100"`
+	Subdir     string `json:"subdir" binding:"max=255"`
+	Dockerfile string `json:"dockerfile" binding:"max=255"`
+}
+
+func (in *gitSourceInput) toDomain() *domain.GitSource {
+	if in == nil {
+		return nil
+	}
+	return &domain.GitSource{GitURL: in.URL, GitBranch: in.Branch, GitSubdir: in.Subdir, DockerfilePath: in.Dockerfile}
 }
 
 func (h *ProjectHandler) Create(c *gin.Context) {
 	var input struct {
-		Name      string `json:"name"`
-		Image     string `json:"image"`
-		Subdomain string `json:"subdomain"`
-		Port      int    `json:"port"`
+		OrganizationID *uuid.UUID      `json:"organization_id"` // default: personal organization
+		Name           string          `json:"name"`
+		Image          string          `json:"image"`
+		Subdomain      string          `json:"subdomain"`
+		Port           int             `json:"port"`
+		PullPolicy     string          `json:"pull_policy"` // always, if-not-present (default), never
+		Git            *gitSourceInput `json:"git"`         // diisi untuk build dari repository Git
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -30,9 +57,26 @@ func (h *ProjectHandler) Create(c *gin.Context) {
 	}
 
 	userID := getUserID(c)
-	project, err := h.svc.CreateProject(c.Request.Context(), userID, input.Name, input.Image, input.Subdomain, input.Port)
+	var orgID uuid.UUID
+	if input.OrganizationID != nil {
+		orgID = *input.OrganizationID
+	} else {
+		org, err := h.orgSvc.PersonalOrganization(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "personal organization not found"})
+			return
+		}
+		orgID = org.ID
+	}
+
+	if _, err := h.orgSvc.Authorize(c.Request.Context(), orgID, userID, domain.RoleDeveloper); err != nil {
+		abortWithOrgError(c, err)
+		return
+	}
+
+	project, err := h.svc.CreateProject(c.Request.Context(), userID, orgID, input.Name, input.Image, input.Subdomain, input.Port, input.PullPolicy, input.Git.toDomain())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondProjectError(c, err)
 		return
 	}
 
@@ -47,20 +91,41 @@ func (h *ProjectHandler) Deploy(c *gin.Context) {
 		return
 	}
 
-	// TODO: Verify project ownership using userID
-
 	deployment, err := h.svc.DeployProject(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondProjectError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, deployment)
 }
 
+// List mengambil project dari semua organization user, atau satu organization via ?organization_id=
 func (h *ProjectHandler) List(c *gin.Context) {
 	userID := getUserID(c)
-	projects, err := h.svc.ListProjects(c.Request.Context(), userID)
+
+	var orgIDs []uuid.UUID
+	if orgIDStr := c.Query("organization_id"); orgIDStr != "" {
+		orgID, err := uuid.Parse(orgIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization_id"})
+			return
+		}
+		if _, err := h.orgSvc.Authorize(c.Request.Context(), orgID, userID, domain.RoleViewer); err != nil {
+			abortWithOrgError(c, err)
+			return
+		}
+		orgIDs = []uuid.UUID{orgID}
+	} else {
+		ids, err := h.orgSvc.OrganizationIDsForUser(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		orgIDs = ids
+	}
+
+	projects, err := h.svc.ListProjects(c.Request.Context(), orgIDs)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -77,17 +142,42 @@ func (h *ProjectHandler) Get(c *gin.Context) {
 		return
 	}
 
-	// TODO: Check ownership
-
 	project, err := h.svc.GetProject(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
 		return
 	}
+	if !canReadEnvValues(c, project) {
+		project.EnvVars = redactEnvVars(project.EnvVars)
+	}
 
 	c.JSON(http.StatusOK, project)
 }
 
+// redactedEnvValue pengganti nilai env var untuk viewer dan token read-only
+const redactedEnvValue = "********"
+
+// canReadEnvValues nilai env var (sering berisi secret) hanya untuk developer ke atas;
+// API token juga harus punya scope write pada project
+func canReadEnvValues(c *gin.Context, project *domain.Project) bool {
+	if !getProjectRole(c).AtLeast(domain.RoleDeveloper) {
+		return false
+	}
+	if scopes, isToken := getScopes(c); isToken {
+		return domain.ScopeAllows(scopes, domain.ScopeWrite, project.ID.String(), project.Subdomain)
+	}
+	return true
+}
+
+func redactEnvVars(envs []domain.EnvVar) []domain.EnvVar {
+	redacted := make([]domain.EnvVar, len(envs))
+	for i, env := range envs {
+		env.Value = redactedEnvValue
+		redacted[i] = env
+	}
+	return redacted
+}
+
 func (h *ProjectHandler) Update(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
@@ -97,25 +187,135 @@ func (h *ProjectHandler) Update(c *gin.Context) {
 	}
 
 	var input struct {
-		Name      string `json:"name"`
-		Image     string `json:"image"`
-		Subdomain string `json:"subdomain"`
-		Port      int    `json:"port"`
+		Name       string          `json:"name"`
+		Image      string          `json:"image"`
+		Subdomain  string          `json:"subdomain"`
+		Port       int             `json:"port"`
+		PullPolicy string          `json:"pull_policy"` // kosong = tidak diubah
+		SourceType string          `json:"source_type"` // kosong = tidak diubah; "image" mengembalikan project git/upload ke image
+		Git        *gitSourceInput `json:"git"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	project, err := h.svc.UpdateProject(c.Request.Context(), id, input.Name, input.Image, input.Subdomain, input.Port)
+	project, err := h.svc.UpdateProject(c.Request.Context(), id, input.Name, input.Image, input.Subdomain, input.Port, input.PullPolicy, input.SourceType, input.Git.toDomain())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondProjectError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, project)
 }
 
+// Build clone repository project, build image, lalu deploy. Log build di-stream sebagai text/plain;
+// status akhir ada di baris terakhir karena status HTTP sudah terkirim saat streaming dimulai.
+func (h *ProjectHandler) Build(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	logs := newStreamWriter(c)
+	// Build tetap jalan sampai selesai walau client memutus koneksi
+	build, deployment, err := h.buildSvc.BuildFromGit(context.WithoutCancel(c.Request.Context()), id, logs)
+	h.finishBuildStream(c, logs, build, deployment, err)
+}
+
+// UploadBuild build dari build context tar.gz di body request (Content-Type application/gzip).
+// Query: dockerfile=path/ke/Dockerfile, build_arg=KEY=VALUE (boleh berulang).
+func (h *ProjectHandler) UploadBuild(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	opts := services.BuildOptions{Dockerfile: c.Query("dockerfile"), BuildArgs: map[string]string{}}
+	for _, arg := range c.QueryArray("build_arg") {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "build_arg must be KEY=VALUE"})
+			return
+		}
+		opts.BuildArgs[key] = value
+	}
+
+	limits := h.buildSvc.Limits()
+	if c.Request.ContentLength > limits.MaxUploadBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrBuildContextTooLarge.Error()})
+		return
+	}
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxUploadBytes)
+
+	logs := newStreamWriter(c)
+	build, deployment, err := h.buildSvc.BuildFromArchive(context.WithoutCancel(c.Request.Context()), id, body, opts, logs)
+	h.finishBuildStream(c, logs, build, deployment, err)
+}
+
+// finishBuildStream error sebelum log pertama dibalas JSON dengan status yang sesuai,
+// setelah streaming dimulai hasil akhir ditulis sebagai baris terakhir
+func (h *ProjectHandler) finishBuildStream(c *gin.Context, logs *streamWriter, build *domain.Build, deployment *domain.Deployment, err error) {
+	if err != nil {
+		if !logs.started {
+			respondProjectError(c, err)
+			return
+		}
+		fmt.Fprintf(logs, "ERROR: %s\n", err)
+		return
+	}
+	fmt.Fprintf(logs, "SUCCESS: build %s deployed as %s\n", build.ID, deployment.ID)
+}
+
+// SetEnv mengganti seluruh env var project; berlaku pada deploy berikutnya
+func (h *ProjectHandler) SetEnv(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var input struct {
+		Env map[string]string `json:"env" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.svc.SetEnvVars(c.Request.Context(), id, input.Env); err != nil {
+		respondProjectError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": len(input.Env)})
+}
+
+// SetSecurity mengatur pelonggaran hardening container; hanya yang diizinkan plan tenant yang diterima
+func (h *ProjectHandler) SetSecurity(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var input domain.SecurityRelaxations
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.svc.SetSecurity(c.Request.Context(), id, input)
+	if err != nil {
+		respondProjectError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, project.Security)
+}
+
 func (h *ProjectHandler) Delete(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
@@ -141,7 +341,7 @@ func (h *ProjectHandler) Start(c *gin.Context) {
 	}
 
 	if err := h.svc.StartProject(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondProjectError(c, err)
 		return
 	}
 
@@ -163,3 +363,76 @@ func (h *ProjectHandler) Stop(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "project stopped"})
 }
+
+func (h *ProjectHandler) Metrics(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	// Project yang belum pernah di-deploy tidak punya container: current kosong, bukan error
+	current, err := h.svc.GetProjectStats(c.Request.Context(), id)
+	if err != nil && !errors.Is(err, services.ErrNoDeployment) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"current": current,
+		"history": h.stats.History(id),
+	})
+}
+
+// respondProjectError 400 untuk input tidak valid, 403 untuk kuota/suspend/policy, selain itu 500
+func respondProjectError(c *gin.Context, err error) {
+	switch {
+	case errors.As(err, new(*http.MaxBytesError)), errors.Is(err, services.ErrBuildContextTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrBuildContextTooLarge.Error()})
+	case errors.Is(err, services.ErrInvalidEnvKey), errors.Is(err, services.ErrGitURLRequired), errors.Is(err, services.ErrInvalidBuildPath),
+		errors.Is(err, services.ErrInvalidBuildArg), errors.Is(err, services.ErrInvalidArchive), errors.Is(err, services.ErrNotGitProject),
+		errors.Is(err, services.ErrInvalidPullPolicy), errors.Is(err, services.ErrInvalidCapability),
+		errors.Is(err, services.ErrInvalidSourceType), errors.Is(err, services.ErrReservedImage),
+		errors.Is(err, services.ErrImageIDReference):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrProjectQuotaExceeded), errors.Is(err, services.ErrTenantSuspended), errors.Is(err, services.ErrImageRejected),
+		errors.Is(err, services.ErrRelaxationNotAllowed), errors.Is(err, ports.ErrRegistryAccessDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrProjectNotBuilt), errors.Is(err, ports.ErrImageNotPresent):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// streamWriter meneruskan setiap write langsung ke client (flush). Header response baru dikirim
+// pada write pertama. Setelah client putus, write berikutnya diabaikan supaya proses yang menulis log tidak ikut gagal.
+type streamWriter struct {
+	c       *gin.Context
+	started bool
+	closed  bool
+}
+
+func newStreamWriter(c *gin.Context) *streamWriter {
+	return &streamWriter{c: c}
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", "text/plain; charset=utf-8")
+		w.c.Header("X-Content-Type-Options", "nosniff")
+		w.c.Header("Cache-Control", "no-cache")
+		w.c.Status(http.StatusOK)
+	}
+	if w.closed {
+		return len(p), nil
+	}
+	if _, err := w.c.Writer.Write(p); err != nil {
+		w.closed = true
+		return len(p), nil
+	}
+	w.c.Writer.Flush()
+	return len(p), nil
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestCanReadEnvValues(t *testing.T) {
+	project := &domain.Project{ID: uuid.New(), Subdomain: "blog"}
+
+	tests := []struct {
+		name   string
+		role   domain.Role
+		scopes []string // nil = login interaktif
+		want   bool
+	}{
+		{name: "viewer", role: domain.RoleViewer, want: false},
+		{name: "developer", role: domain.RoleDeveloper, want: true},
+		{name: "owner", role: domain.RoleOwner, want: true},
+		{name: "no role in context", want: false},
+		{name: "developer with read-only token", role: domain.RoleDeveloper, scopes: []string{"read:*"}, want: false},
+		{name: "developer with deploy token", role: domain.RoleDeveloper, scopes: []string{"read:blog", "deploy:blog"}, want: false},
+		{name: "developer with write token for another project", role: domain.RoleDeveloper, scopes: []string{"write:other"}, want: false},
+		{name: "developer with write token", role: domain.RoleDeveloper, scopes: []string{"read:blog", "write:blog"}, want: true},
+		{name: "viewer with write token", role: domain.RoleViewer, scopes: []string{"write:*"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			if tt.role != "" {
+				c.Set("projectRole", tt.role)
+			}
+			if tt.scopes != nil {
+				c.Set("scopes", tt.scopes)
+			}
+			if got := canReadEnvValues(c, project); got != tt.want {
+				t.Fatalf("canReadEnvValues = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactEnvVars(t *testing.T) {
+	envs := []domain.EnvVar{{Key: "DATABASE_URL", Value: "postgres://secret"}, {Key: "DEBUG", Value: "1"}}
+	redacted := redactEnvVars(envs)
+
+	for i, env := range redacted {
+		if env.Key != envs[i].Key || env.Value != redactedEnvValue {
+			t.Fatalf("redacted[%d] = %+v", i, env)
+		}
+	}
+	if envs[0].Value != "postgres://secret" {
+		t.Fatal("redactEnvVars modified its input")
+	}
+}
+
+// singleProjectRepo selalu mengembalikan project yang sama
+type singleProjectRepo struct {
+	ports.ProjectRepository
+	project *domain.Project
+}
+
+func (r *singleProjectRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Project, error) {
+	return r.project, nil
+}
+
+func TestMetricsWithoutDeployments(t *testing.T) {
+	project := &domain.Project{ID: uuid.New(), Subdomain: "blog"}
+	repo := &singleProjectRepo{project: project}
+	svc := services.NewProjectService(repo, nil, nil, nil, nil, nil, nil, nil, services.ProjectConfig{})
+	h := NewProjectHandler(svc, nil, nil, services.NewStatsCollector(repo, nil, time.Minute, 10))
+
+	r := gin.New()
+	r.GET("/projects/:id/metrics", h.Metrics)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/projects/"+project.ID.String()+"/metrics", nil))
+
+	var body struct {
+		Current *ports.ContainerStats  `json:"current"`
+		History []ports.ContainerStats `json:"history"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s, want 200", w.Code, w.Body)
+	}
+	if body.Current != nil || body.History == nil || len(body.History) != 0 {
+		t.Fatalf("body = %s, want no current sample and an empty history", w.Body)
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type RegistryHandler struct {
+	svc *services.RegistryService
+}
+
+func NewRegistryHandler(svc *services.RegistryService) *RegistryHandler {
+	return &RegistryHandler{svc: svc}
+}
+
+// Create menyimpan kredensial registry. Token tidak pernah dikembalikan, termasuk di response ini.
+func (h *RegistryHandler) Create(c *gin.Context) {
+	orgID, _ := uuid.Parse(c.Param("id")) // sudah divalidasi RequireOrgRole
+
+	var input struct {
+		Registry string `json:"registry" binding:"required"`
+		Username string `json:"username" binding:"required"`
+		Token    string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cred, err := h.svc.CreateCredential(c.Request.Context(), orgID, getUserID(c), services.CreateRegistryCredentialInput{
+		Registry: input.Registry,
+		Username: input.Username,
+		Token:    input.Token,
+	})
+	if err != nil {
+		respondRegistryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, cred)
+}
+
+func (h *RegistryHandler) List(c *gin.Context) {
+	orgID, _ := uuid.Parse(c.Param("id"))
+
+	creds, err := h.svc.ListCredentials(c.Request.Context(), orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, creds)
+}
+
+func (h *RegistryHandler) Delete(c *gin.Context) {
+	orgID, _ := uuid.Parse(c.Param("id"))
+	credID, err := uuid.Parse(c.Param("credential_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credential id"})
+		return
+	}
+
+	if err := h.svc.DeleteCredential(c.Request.Context(), orgID, credID); err != nil {
+		respondRegistryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "registry credential deleted"})
+}
+
+// respondRegistryError memetakan error RegistryService ke status HTTP
+func respondRegistryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRegistry), errors.Is(err, services.ErrRegistryCredentialInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRegistryCredentialExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRegistryCredentialNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
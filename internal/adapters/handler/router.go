@@ -1,33 +1,145 @@
 package handler
 
 import (
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/metrics"
+	"github.com/damantine/multi-tenant-hosting/internal/config"
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
 	"github.com/damantine/multi-tenant-hosting/internal/core/services"
 	"github.com/gin-gonic/gin"
 )
 
-func NewRouter(authSvc *services.AuthService, projectSvc *services.ProjectService) *gin.Engine {
-	r := gin.Default()
+// Policy rate limit per IP untuk endpoint auth publik
+var (
+	loginRateLimit    = services.RateLimitPolicy{Name: "login", Limit: 20, Window: time.Minute}
+	registerRateLimit = services.RateLimitPolicy{Name: "register", Limit: 5, Window: time.Hour}
+	recoveryRateLimit = services.RateLimitPolicy{Name: "recovery", Limit: 5, Window: 15 * time.Minute}
+	verifyRateLimit   = services.RateLimitPolicy{Name: "verify", Limit: 30, Window: 15 * time.Minute}
+)
+
+func NewRouter(cfg *config.Config, authSvc *services.AuthService, throttle *services.LoginThrottle, oidcHandler *OIDCHandler, passwordLogin bool, tokenSvc *services.TokenService, adminSvc *services.AdminService, imagePolicySvc *services.ImagePolicyService, hardeningSvc *services.HardeningService, orgSvc *services.OrganizationService, registrySvc *services.RegistryService, projectSvc *services.ProjectService, buildSvc *services.BuildService, statsCollector *services.StatsCollector, promMetrics *metrics.PrometheusMetrics, healthSvc *services.HealthService) *gin.Engine {
+	r := gin.New()
+	r.Use(
+		gin.Recovery(),
+		Tracing(),
+		RequestID(),
+		AuditContext(),
+		RequestLogger(),
+		promMetrics.GinMiddleware(),
+		RequireMigrated(healthSvc),
+	)
 
-	authHandler := NewAuthHandler(authSvc)
-	projectHandler := NewProjectHandler(projectSvc)
+	authHandler := NewAuthHandler(authSvc, throttle, cfg.BaseDomain)
+	projectHandler := NewProjectHandler(projectSvc, buildSvc, orgSvc, statsCollector)
+	orgHandler := NewOrganizationHandler(orgSvc)
+	registryHandler := NewRegistryHandler(registrySvc)
+	tokenHandler := NewTokenHandler(tokenSvc)
+	adminHandler := NewAdminHandler(adminSvc)
+	imagePolicyHandler := NewImagePolicyHandler(imagePolicySvc)
+	securityPolicyHandler := NewSecurityPolicyHandler(hardeningSvc)
+	healthHandler := NewHealthHandler(healthSvc)
+
+	// Liveness & readiness probe
+	r.GET("/healthz", healthHandler.Liveness)
+	r.GET("/readyz", healthHandler.Readiness)
+
+	// Public key untuk verifikasi JWT
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
 
 	// Public routes
-	r.POST("/api/v1/auth/register", authHandler.Register)
-	r.POST("/api/v1/auth/login", authHandler.Login)
+	if passwordLogin {
+		r.POST("/api/v1/auth/register", RateLimit(throttle, registerRateLimit), authHandler.Register)
+		r.POST("/api/v1/auth/login", RateLimit(throttle, loginRateLimit), authHandler.Login)
+		r.POST("/api/v1/auth/forgot", RateLimit(throttle, recoveryRateLimit), authHandler.ForgotPassword)
+		r.POST("/api/v1/auth/reset", RateLimit(throttle, recoveryRateLimit), authHandler.ResetPassword)
+	}
+	// Login SSO user dengan 2FA juga berakhir di challenge TOTP, jadi tetap ada tanpa login password
+	r.POST("/api/v1/auth/2fa/challenge", RateLimit(throttle, loginRateLimit), authHandler.MFAChallenge)
+	r.POST("/api/v1/auth/verify", RateLimit(throttle, verifyRateLimit), authHandler.VerifyEmail)
+	r.POST("/api/v1/auth/refresh", authHandler.Refresh)
+	r.GET("/api/v1/auth/oidc/login", oidcHandler.Login)
+	r.GET("/api/v1/auth/oidc/callback", oidcHandler.Callback)
 
 	// Protected routes
 	api := r.Group("/api/v1")
-	api.Use(AuthMiddleware(authSvc))
+	api.Use(AuthMiddleware(authSvc, tokenSvc))
 	{
-		api.GET("/auth/me", authHandler.Me) // New Me endpoint
-		api.POST("/projects", projectHandler.Create)
-		api.POST("/projects/:id/deploy", projectHandler.Deploy)
-		api.POST("/projects/:id/start", projectHandler.Start)
-		api.POST("/projects/:id/stop", projectHandler.Stop)
-		api.GET("/projects", projectHandler.List)
-		api.GET("/projects/:id", projectHandler.Get)
-		api.PUT("/projects/:id", projectHandler.Update)
-		api.DELETE("/projects/:id", projectHandler.Delete)
+		read := RequireScope(domain.ScopeRead, projectSvc)
+		write := RequireScope(domain.ScopeWrite, projectSvc)
+		deploy := RequireScope(domain.ScopeDeploy, projectSvc)
+
+		api.GET("/auth/me", authHandler.Me)
+		api.PATCH("/auth/me", RequireSession(), RequireNotImpersonated(), authHandler.UpdateMe)
+		api.POST("/auth/password", RequireSession(), RequireNotImpersonated(), authHandler.ChangePassword)
+		api.POST("/auth/logout", RequireSession(), authHandler.Logout)
+		api.POST("/auth/logout-all", RequireSession(), authHandler.LogoutAll)
+		api.POST("/auth/verify/resend", RequireSession(), authHandler.ResendVerification)
+		api.POST("/auth/2fa/enroll", RequireSession(), RequireNotImpersonated(), authHandler.EnrollTOTP)
+		api.POST("/auth/2fa/verify", RequireSession(), RequireNotImpersonated(), authHandler.VerifyTOTP)
+		api.POST("/auth/2fa/disable", RequireSession(), RequireNotImpersonated(), authHandler.DisableTOTP)
+		api.POST("/auth/2fa/recovery-codes", RequireSession(), RequireNotImpersonated(), authHandler.RegenerateRecoveryCodes)
+		// Role minimal di organization pemilik project
+		viewer := RequireProjectRole(domain.RoleViewer, projectSvc, orgSvc)
+		developer := RequireProjectRole(domain.RoleDeveloper, projectSvc, orgSvc)
+		admin := RequireProjectRole(domain.RoleAdmin, projectSvc, orgSvc)
+
+		api.POST("/projects", write, projectHandler.Create)
+		api.POST("/projects/:id/deploy", deploy, developer, projectHandler.Deploy)
+		api.POST("/projects/:id/build", deploy, developer, projectHandler.Build)
+		api.POST("/projects/:id/builds", deploy, developer, projectHandler.UploadBuild)
+		api.POST("/projects/:id/start", deploy, developer, projectHandler.Start)
+		api.POST("/projects/:id/stop", deploy, developer, projectHandler.Stop)
+		api.GET("/projects", read, projectHandler.List)
+		api.GET("/projects/:id", read, viewer, projectHandler.Get)
+		api.GET("/projects/:id/metrics", read, viewer, projectHandler.Metrics)
+		api.PUT("/projects/:id", write, developer, projectHandler.Update)
+		api.PUT("/projects/:id/env", write, developer, projectHandler.SetEnv)
+		api.PUT("/projects/:id/security", write, admin, projectHandler.SetSecurity)
+		api.DELETE("/projects/:id", write, admin, projectHandler.Delete)
+
+		// Organization & membership (hanya lewat login interaktif)
+		orgs := api.Group("/orgs", RequireSession())
+		orgs.GET("", orgHandler.List)
+		orgs.POST("", orgHandler.Create)
+		orgs.PATCH("/:id", RequireOrgRole(domain.RoleAdmin, orgSvc), orgHandler.Update)
+		orgs.GET("/:id/members", RequireOrgRole(domain.RoleViewer, orgSvc), orgHandler.ListMembers)
+		orgs.PATCH("/:id/members/:user_id", RequireOrgRole(domain.RoleAdmin, orgSvc), orgHandler.UpdateMember)
+		orgs.DELETE("/:id/members/:user_id", RequireOrgRole(domain.RoleViewer, orgSvc), orgHandler.RemoveMember) // viewer boleh keluar sendiri
+		orgs.GET("/:id/invitations", RequireOrgRole(domain.RoleAdmin, orgSvc), orgHandler.ListInvitations)
+		orgs.POST("/:id/invitations", RequireOrgRole(domain.RoleAdmin, orgSvc), orgHandler.Invite)
+		orgs.DELETE("/:id/invitations/:invitation_id", RequireOrgRole(domain.RoleAdmin, orgSvc), orgHandler.RevokeInvitation)
+		orgs.GET("/:id/audit", RequireOrgRole(domain.RoleAdmin, orgSvc), orgHandler.ListAudit)
+		orgs.GET("/:id/registry-credentials", RequireOrgRole(domain.RoleDeveloper, orgSvc), registryHandler.List)
+		orgs.POST("/:id/registry-credentials", RequireOrgRole(domain.RoleAdmin, orgSvc), registryHandler.Create)
+		orgs.DELETE("/:id/registry-credentials/:credential_id", RequireOrgRole(domain.RoleAdmin, orgSvc), registryHandler.Delete)
+		api.POST("/invitations/accept", RequireSession(), orgHandler.AcceptInvitation)
+
+		// Personal API token (hanya lewat login interaktif)
+		api.POST("/tokens", RequireSession(), RequireNotImpersonated(), tokenHandler.Create)
+		api.GET("/tokens", RequireSession(), tokenHandler.List)
+		api.DELETE("/tokens/:id", RequireSession(), tokenHandler.Revoke)
+
+		// Platform admin
+		platform := api.Group("/admin", RequireSession(), RequireNotImpersonated(), RequirePlatformAdmin(adminSvc))
+		platform.GET("/users", adminHandler.ListUsers)
+		platform.POST("/users/:id/suspend", adminHandler.Suspend)
+		platform.POST("/users/:id/unsuspend", adminHandler.Unsuspend)
+		platform.PUT("/users/:id/quota", adminHandler.SetQuota)
+		platform.PUT("/users/:id/plan", adminHandler.SetPlan)
+		platform.POST("/users/:id/impersonate", adminHandler.Impersonate)
+		platform.GET("/projects", adminHandler.ListProjects)
+		platform.POST("/projects/:id/stop", adminHandler.StopProject)
+		platform.POST("/projects/:id/redeploy", adminHandler.RedeployProject)
+		platform.GET("/audit", adminHandler.ListAudit)
+		platform.GET("/image-policies", imagePolicyHandler.List)
+		platform.PUT("/image-policies/platform", imagePolicyHandler.PutPlatform)
+		platform.DELETE("/image-policies/platform", imagePolicyHandler.DeletePlatform)
+		platform.PUT("/image-policies/plans/:plan", imagePolicyHandler.PutPlan)
+		platform.DELETE("/image-policies/plans/:plan", imagePolicyHandler.DeletePlan)
+		platform.GET("/security-policies", securityPolicyHandler.List)
+		platform.PUT("/security-policies/plans/:plan", securityPolicyHandler.Put)
+		platform.DELETE("/security-policies/plans/:plan", securityPolicyHandler.Delete)
 	}
 
 	return r
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/metrics"
+	"github.com/damantine/multi-tenant-hosting/internal/config"
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouterAuthRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		passwordLogin bool
+		want          map[string]bool // route -> terdaftar
+	}{
+		{
+			name:          "password login enabled",
+			passwordLogin: true,
+			want:          map[string]bool{"/api/v1/auth/login": true, "/api/v1/auth/register": true, "/api/v1/auth/2fa/challenge": true},
+		},
+		{
+			name: "SSO only",
+			want: map[string]bool{"/api/v1/auth/login": false, "/api/v1/auth/register": false, "/api/v1/auth/2fa/challenge": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter(config.Default(), nil, nil, NewOIDCHandler(nil, ""), tt.passwordLogin, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+				metrics.NewPrometheusMetrics(nil), services.NewHealthService(time.Second))
+
+			registered := make(map[string]bool)
+			for _, route := range r.Routes() {
+				if route.Method == http.MethodPost {
+					registered[route.Path] = true
+				}
+			}
+			for path, want := range tt.want {
+				if registered[path] != want {
+					t.Errorf("POST %s registered = %v, want %v", path, registered[path], want)
+				}
+			}
+		})
+	}
+}
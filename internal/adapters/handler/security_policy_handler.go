@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityPolicyHandler endpoint platform admin untuk pelonggaran hardening container per plan
+type SecurityPolicyHandler struct {
+	svc *services.HardeningService
+}
+
+func NewSecurityPolicyHandler(svc *services.HardeningService) *SecurityPolicyHandler {
+	return &SecurityPolicyHandler{svc: svc}
+}
+
+func (h *SecurityPolicyHandler) List(c *gin.Context) {
+	policies, err := h.svc.ListPolicies(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+func (h *SecurityPolicyHandler) Put(c *gin.Context) {
+	var input struct {
+		AllowWritableRootfs bool     `json:"allow_writable_rootfs"`
+		AllowImageUser      bool     `json:"allow_image_user"`
+		AllowedCapabilities []string `json:"allowed_capabilities"`
+		PidsLimit           int64    `json:"pids_limit" binding:"min=0"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.svc.PutPolicy(c.Request.Context(), getUserID(c), c.Param("plan"), services.SecurityPolicyInput{
+		AllowWritableRootfs: input.AllowWritableRootfs,
+		AllowImageUser:      input.AllowImageUser,
+		AllowedCapabilities: input.AllowedCapabilities,
+		PidsLimit:           input.PidsLimit,
+	})
+	if err != nil {
+		respondSecurityPolicyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *SecurityPolicyHandler) Delete(c *gin.Context) {
+	if err := h.svc.DeletePolicy(c.Request.Context(), c.Param("plan")); err != nil {
+		respondSecurityPolicyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "security policy deleted"})
+}
+
+func respondSecurityPolicyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPlan), errors.Is(err, services.ErrInvalidSecurityPolicy), errors.Is(err, services.ErrInvalidCapability):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSecurityPolicyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TokenHandler struct {
+	svc *services.TokenService
+}
+
+func NewTokenHandler(svc *services.TokenService) *TokenHandler {
+	return &TokenHandler{svc: svc}
+}
+
+func (h *TokenHandler) Create(c *gin.Context) {
+	var input struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, raw, err := h.svc.CreateToken(c.Request.Context(), getUserID(c), services.CreateTokenInput{
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresIn: time.Duration(input.ExpiresInDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Token mentah hanya ditampilkan sekali saat dibuat
+	c.JSON(http.StatusCreated, gin.H{
+		"token":     raw,
+		"api_token": token,
+		"warning":   "store this token now, it will not be shown again",
+	})
+}
+
+func (h *TokenHandler) List(c *gin.Context) {
+	tokens, err := h.svc.ListTokens(c.Request.Context(), getUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *TokenHandler) Revoke(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.svc.RevokeToken(c.Request.Context(), getUserID(c), id); err != nil {
+		if errors.Is(err, services.ErrTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "token revoked"})
+}
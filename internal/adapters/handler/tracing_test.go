@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingRecordsRouteSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.InstallExporter("test", exporter)
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Tracing())
+	r.GET("/api/v1/projects/:id", func(c *gin.Context) {
+		_, span := tracing.Start(c.Request.Context(), "ProjectService.GetProject")
+		span.End()
+		c.Status(http.StatusOK)
+	})
+	r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/api/v1/projects/42", "/healthz"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want server + service span (probe not traced): %+v", len(spans), spans.Snapshots())
+	}
+	service, server := spans[0], spans[1]
+	if server.Name != "GET /api/v1/projects/:id" || server.SpanKind != trace.SpanKindServer {
+		t.Fatalf("server span = %q (%s), want route span", server.Name, server.SpanKind)
+	}
+	if !hasAttribute(server.Attributes, attribute.String("http.route", "/api/v1/projects/:id")) {
+		t.Fatalf("server span attributes %v lack http.route", server.Attributes)
+	}
+	if service.Name != "ProjectService.GetProject" || service.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Fatalf("service span %q is not a child of the route span", service.Name)
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, a := range attrs {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+)
+
+// FileMailer menulis setiap email sebagai file .eml di satu direktori (untuk development)
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg ports.MailMessage) error {
+	recipient := strings.NewReplacer("@", "_at_", "/", "_").Replace(msg.To)
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000000"), recipient)
+	return os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, msg), 0o644)
+}
+
+// MemoryMailer menyimpan email di memory, dipakai di test untuk membaca token dari isi email
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []ports.MailMessage
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg ports.MailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages salinan semua email yang sudah "dikirim"
+func (m *MemoryMailer) Messages() []ports.MailMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]ports.MailMessage, len(m.messages))
+	copy(out, m.messages)
+	return out
+}
+
+// Last email terakhir untuk alamat tertentu
+func (m *MemoryMailer) Last(to string) (ports.MailMessage, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if strings.EqualFold(m.messages[i].To, to) {
+			return m.messages[i], true
+		}
+	}
+	return ports.MailMessage{}, false
+}
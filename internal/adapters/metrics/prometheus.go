@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "mth"
+
+// PrometheusMetrics implementasi ports.MetricsRecorder + endpoint /metrics
+type PrometheusMetrics struct {
+	registry *prometheus.Registry
+
+	httpDuration   *prometheus.HistogramVec
+	deployDuration *prometheus.HistogramVec
+	deploysTotal   *prometheus.CounterVec
+	imagePull      prometheus.Histogram
+	dockerErrors   *prometheus.CounterVec
+}
+
+// NewPrometheusMetrics membuat registry baru. repo dipakai untuk gauge jumlah project per status.
+func NewPrometheusMetrics(repo ports.ProjectRepository) *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		registry: prometheus.NewRegistry(),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by gin route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		deployDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "deploy_duration_seconds",
+			Help:      "Duration of project deployments by outcome.",
+			Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 60, 90, 120, 300},
+		}, []string{"outcome"}),
+		deploysTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "deploys_total",
+			Help:      "Number of project deployments by outcome.",
+		}, []string{"outcome"}),
+		imagePull: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "image_pull_duration_seconds",
+			Help:      "Time spent pulling container images.",
+			Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+		}),
+		dockerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "docker_api_errors_total",
+			Help:      "Docker API errors by operation.",
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpDuration,
+		m.deployDuration,
+		m.deploysTotal,
+		m.imagePull,
+		m.dockerErrors,
+		&projectStatusCollector{repo: repo},
+	)
+
+	return m
+}
+
+// RegisterContainerStats menambahkan gauge resource container per project dari StatsCollector
+func (m *PrometheusMetrics) RegisterContainerStats(stats *services.StatsCollector) {
+	m.registry.MustRegister(&containerStatsCollector{stats: stats})
+}
+
+func (m *PrometheusMetrics) ObserveDeploy(outcome string, duration time.Duration) {
+	m.deployDuration.WithLabelValues(outcome).Observe(duration.Seconds())
+	m.deploysTotal.WithLabelValues(outcome).Inc()
+}
+
+func (m *PrometheusMetrics) ObserveImagePull(duration time.Duration) {
+	m.imagePull.Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) IncDockerError(operation string) {
+	m.dockerErrors.WithLabelValues(operation).Inc()
+}
+
+// Handler mengekspos registry dalam format Prometheus
+func (m *PrometheusMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// GinMiddleware mencatat latency dan status tiap request berdasarkan route gin
+// (bukan path mentah, supaya /projects/:id tidak meledakkan cardinality)
+func (m *PrometheusMetrics) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.httpDuration.WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
+	}
+}
+
+// projectStatusCollector menghitung project per status dari DB saat di-scrape
+type projectStatusCollector struct {
+	repo ports.ProjectRepository
+}
+
+var projectsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "projects"),
+	"Number of projects by status.",
+	[]string{"status"}, nil,
+)
+
+func (c *projectStatusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- projectsDesc
+}
+
+func (c *projectStatusCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	counts, err := c.repo.CountByStatus(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "metrics: failed to count projects", slog.Any("error", err))
+		return
+	}
+	for status, n := range counts {
+		ch <- prometheus.MustNewConstMetric(projectsDesc, prometheus.GaugeValue, float64(n), status)
+	}
+}
+
+// containerStatsCollector mengekspos sampel terakhir StatsCollector per project
+type containerStatsCollector struct {
+	stats *services.StatsCollector
+}
+
+var (
+	containerCPUDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "container", "cpu_percent"),
+		"Container CPU usage in percent of one core.",
+		[]string{"project_id"}, nil,
+	)
+	containerMemoryDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "container", "memory_bytes"),
+		"Container memory usage without page cache.",
+		[]string{"project_id"}, nil,
+	)
+	containerNetworkDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "container", "network_bytes"),
+		"Container network bytes since start by direction.",
+		[]string{"project_id", "direction"}, nil,
+	)
+)
+
+func (c *containerStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- containerCPUDesc
+	ch <- containerMemoryDesc
+	ch <- containerNetworkDesc
+}
+
+func (c *containerStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	for projectID, s := range c.stats.Latest() {
+		id := projectID.String()
+		ch <- prometheus.MustNewConstMetric(containerCPUDesc, prometheus.GaugeValue, s.CPUPercent, id)
+		ch <- prometheus.MustNewConstMetric(containerMemoryDesc, prometheus.GaugeValue, float64(s.MemoryUsage), id)
+		ch <- prometheus.MustNewConstMetric(containerNetworkDesc, prometheus.GaugeValue, float64(s.NetworkRx), id, "rx")
+		ch <- prometheus.MustNewConstMetric(containerNetworkDesc, prometheus.GaugeValue, float64(s.NetworkTx), id, "tx")
+	}
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/damantine/multi-tenant-hosting/internal/core/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type fakeProjectRepo struct {
+	ports.ProjectRepository
+	counts  map[string]int64
+	running []domain.Project
+}
+
+func (r *fakeProjectRepo) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	return r.counts, nil
+}
+
+func (r *fakeProjectRepo) ListByStatus(ctx context.Context, status string) ([]domain.Project, error) {
+	return r.running, nil
+}
+
+type fakeRuntime struct {
+	ports.ContainerRuntime
+}
+
+func (fakeRuntime) Stats(ctx context.Context, containerID string) (*ports.ContainerStats, error) {
+	return &ports.ContainerStats{CPUPercent: 12.5, MemoryUsage: 2048, NetworkRx: 10, NetworkTx: 20}, nil
+}
+
+// scrape mengambil output /metrics dalam format teks Prometheus
+func scrape(t *testing.T, m *PrometheusMetrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("scrape status = %d", rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func assertContains(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(body, line) {
+			t.Errorf("metrics output has no %q", line)
+		}
+	}
+}
+
+func TestGinMiddlewareUsesRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := NewPrometheusMetrics(&fakeProjectRepo{})
+	r := gin.New()
+	r.Use(m.GinMiddleware())
+	r.GET("/api/v1/projects/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, path := range []string{"/api/v1/projects/" + uuid.NewString(), "/api/v1/projects/" + uuid.NewString(), "/nope"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assertContains(t, scrape(t, m),
+		`mth_http_request_duration_seconds_count{method="GET",route="/api/v1/projects/:id",status="204"} 2`,
+		`mth_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+	)
+}
+
+func TestRecorderAndCollectors(t *testing.T) {
+	project := domain.Project{ID: uuid.New(), Status: "running", Deployments: []domain.Deployment{{ContainerID: "web"}}}
+	repo := &fakeProjectRepo{
+		counts:  map[string]int64{"running": 3, "stopped": 1},
+		running: []domain.Project{project},
+	}
+	stats := services.NewStatsCollector(repo, fakeRuntime{}, time.Hour, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stats.Run(ctx) // satu kali sampling lalu berhenti karena ctx sudah dibatalkan
+
+	m := NewPrometheusMetrics(repo)
+	m.RegisterContainerStats(stats)
+	m.ObserveDeploy("success", 2*time.Second)
+	m.ObserveDeploy("failure", time.Second)
+	m.ObserveDeploy("success", time.Second)
+	m.ObserveImagePull(time.Second)
+	m.IncDockerError("container_create")
+
+	id := project.ID.String()
+	assertContains(t, scrape(t, m),
+		`mth_deploys_total{outcome="success"} 2`,
+		`mth_deploys_total{outcome="failure"} 1`,
+		`mth_image_pull_duration_seconds_count 1`,
+		`mth_docker_api_errors_total{operation="container_create"} 1`,
+		`mth_projects{status="running"} 3`,
+		`mth_projects{status="stopped"} 1`,
+		`mth_container_cpu_percent{project_id="`+id+`"} 12.5`,
+		`mth_container_memory_bytes{project_id="`+id+`"} 2048`,
+		`mth_container_network_bytes{direction="rx",project_id="`+id+`"} 10`,
+		`mth_container_network_bytes{direction="tx",project_id="`+id+`"} 20`,
+	)
+}
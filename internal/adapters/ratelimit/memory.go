@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval seberapa sering entry kadaluarsa dibersihkan dari map
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	count       int
+	resetAt     time.Time
+	lockedUntil time.Time
+}
+
+// MemoryStore RateLimitStore di memory proses (default, cukup untuk satu replica)
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry), now: time.Now}
+}
+
+func (s *MemoryStore) Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &memoryEntry{}
+		s.entries[key] = e
+	}
+	if !now.Before(e.resetAt) {
+		e.count = 0
+		e.resetAt = now.Add(window)
+	}
+	e.count++
+	return e.count, e.resetAt, nil
+}
+
+func (s *MemoryStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &memoryEntry{}
+		s.entries[key] = e
+	}
+	e.lockedUntil = until
+	return nil
+}
+
+func (s *MemoryStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || !s.now().Before(e.lockedUntil) {
+		return time.Time{}, nil
+	}
+	return e.lockedUntil, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// sweep membuang entry yang window dan lockout-nya sudah lewat (dipanggil dengan mu terkunci)
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.resetAt) && !now.Before(e.lockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock jam manual supaya window dan lockout bisa diuji tanpa sleep
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = clock.now
+	return s, clock
+}
+
+func TestMemoryStoreIncrementWindow(t *testing.T) {
+	ctx := context.Background()
+	s, clock := newTestStore()
+	start := clock.t
+
+	tests := []struct {
+		name      string
+		advance   time.Duration
+		key       string
+		wantCount int
+		wantReset time.Time
+	}{
+		{name: "first hit opens window", key: "a", wantCount: 1, wantReset: start.Add(time.Minute)},
+		{name: "same window counts up", advance: 30 * time.Second, key: "a", wantCount: 2, wantReset: start.Add(time.Minute)},
+		{name: "keys are independent", key: "b", wantCount: 1, wantReset: start.Add(90 * time.Second)},
+		{name: "window expiry resets count", advance: 30 * time.Second, key: "a", wantCount: 1, wantReset: start.Add(2 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.advance(tt.advance)
+			count, resetAt, err := s.Increment(ctx, tt.key, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.wantCount || !resetAt.Equal(tt.wantReset) {
+				t.Fatalf("Increment = (%d, %v), want (%d, %v)", count, resetAt, tt.wantCount, tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreLockAndReset(t *testing.T) {
+	ctx := context.Background()
+	s, clock := newTestStore()
+
+	until := clock.t.Add(time.Minute)
+	if err := s.Lock(ctx, "user", until); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.LockedUntil(ctx, "user"); !got.Equal(until) {
+		t.Fatalf("LockedUntil = %v, want %v", got, until)
+	}
+	if got, _ := s.LockedUntil(ctx, "other"); !got.IsZero() {
+		t.Fatalf("LockedUntil(other) = %v, want zero", got)
+	}
+
+	clock.advance(time.Minute)
+	if got, _ := s.LockedUntil(ctx, "user"); !got.IsZero() {
+		t.Fatalf("LockedUntil after expiry = %v, want zero", got)
+	}
+
+	s.Lock(ctx, "user", clock.t.Add(time.Hour))
+	s.Increment(ctx, "user", time.Hour)
+	if err := s.Reset(ctx, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.LockedUntil(ctx, "user"); !got.IsZero() {
+		t.Fatalf("LockedUntil after Reset = %v, want zero", got)
+	}
+	if count, _, _ := s.Increment(ctx, "user", time.Hour); count != 1 {
+		t.Fatalf("count after Reset = %d, want 1", count)
+	}
+}
+
+func TestMemoryStoreSweepsExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	s, clock := newTestStore()
+
+	s.Increment(ctx, "expired", time.Second)
+	s.Lock(ctx, "locked", clock.t.Add(time.Hour))
+	clock.advance(sweepInterval)
+	s.Increment(ctx, "fresh", time.Minute)
+
+	if _, ok := s.entries["expired"]; ok {
+		t.Fatal("expired entry was not swept")
+	}
+	for _, key := range []string{"locked", "fresh"} {
+		if _, ok := s.entries[key]; !ok {
+			t.Fatalf("entry %q was swept while still active", key)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// rateLimitEntry satu baris per key; counter dan lockout disimpan di tabel yang sama
+type rateLimitEntry struct {
+	Key         string    `gorm:"type:varchar(255);primaryKey"`
+	Count       int       `gorm:"not null;default:0"`
+	ResetAt     time.Time `gorm:"not null;index"`
+	LockedUntil *time.Time
+}
+
+func (rateLimitEntry) TableName() string {
+	return "rate_limit_entries"
+}
+
+// PostgresStore RateLimitStore yang dibagi semua replica lewat database
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// AutoMigrate membuat tabel rate_limit_entries
+func (s *PostgresStore) AutoMigrate(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(&rateLimitEntry{})
+}
+
+// Increment atomic lewat upsert, jadi aman dipanggil paralel dari banyak replica
+func (s *PostgresStore) Increment(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	var row struct {
+		Count   int
+		ResetAt time.Time
+	}
+	err := s.db.WithContext(ctx).Raw(`
+		INSERT INTO rate_limit_entries (key, count, reset_at) VALUES (@key, 1, @reset)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_limit_entries.reset_at <= @now THEN 1 ELSE rate_limit_entries.count + 1 END,
+			reset_at = CASE WHEN rate_limit_entries.reset_at <= @now THEN @reset ELSE rate_limit_entries.reset_at END
+		RETURNING count, reset_at`,
+		map[string]interface{}{"key": key, "now": now, "reset": now.Add(window)},
+	).Scan(&row).Error
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return row.Count, row.ResetAt, nil
+}
+
+func (s *PostgresStore) Lock(ctx context.Context, key string, until time.Time) error {
+	return s.db.WithContext(ctx).Exec(`
+		INSERT INTO rate_limit_entries (key, count, reset_at, locked_until) VALUES (?, 0, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET locked_until = EXCLUDED.locked_until`,
+		key, time.Now(), until,
+	).Error
+}
+
+func (s *PostgresStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	var entry rateLimitEntry
+	err := s.db.WithContext(ctx).Where("key = ? AND locked_until > ?", key, time.Now()).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return *entry.LockedUntil, nil
+}
+
+func (s *PostgresStore) Reset(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Where("key IN ?", keys).Delete(&rateLimitEntry{}).Error
+}
+
+// Cleanup menghapus entry yang sudah tidak berlaku; dipanggil berkala dari main
+func (s *PostgresStore) Cleanup(ctx context.Context) error {
+	now := time.Now()
+	return s.db.WithContext(ctx).
+		Where("reset_at <= ? AND (locked_until IS NULL OR locked_until <= ?)", now, now).
+		Delete(&rateLimitEntry{}).Error
+}
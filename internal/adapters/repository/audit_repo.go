@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+type GormAuditRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAuditRepository(db *gorm.DB) *GormAuditRepository {
+	return &GormAuditRepository{db: db}
+}
+
+// Protect memasang trigger yang menolak UPDATE/DELETE pada audit_events (dipanggil setelah AutoMigrate)
+func (r *GormAuditRepository) Protect(ctx context.Context) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stmts := []string{
+			`CREATE OR REPLACE FUNCTION audit_events_immutable() RETURNS trigger AS $$
+			BEGIN
+				RAISE EXCEPTION 'audit_events is append-only';
+			END;
+			$$ LANGUAGE plpgsql`,
+			`DROP TRIGGER IF EXISTS audit_events_immutable ON audit_events`,
+			`CREATE TRIGGER audit_events_immutable BEFORE UPDATE OR DELETE ON audit_events
+			FOR EACH ROW EXECUTE FUNCTION audit_events_immutable()`,
+		}
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *GormAuditRepository) Append(ctx context.Context, event *domain.AuditEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "GormAuditRepository.Append", attribute.String("audit.action", event.Action), attribute.String("db.system", "postgresql"))
+	defer func() { tracing.End(span, err) }()
+
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *GormAuditRepository) List(ctx context.Context, filter ports.AuditFilter) (_ []domain.AuditEvent, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "GormAuditRepository.List", attribute.String("db.system", "postgresql"))
+	defer func() { tracing.End(span, err) }()
+
+	q := r.db.WithContext(ctx).Model(&domain.AuditEvent{})
+	if filter.OrganizationID != nil {
+		q = q.Where("organization_id = ?", *filter.OrganizationID)
+	}
+	if filter.ActorID != nil {
+		q = q.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		if strings.HasSuffix(filter.Action, ".") {
+			q = q.Where("action LIKE ?", filter.Action+"%")
+		} else {
+			q = q.Where("action = ?", filter.Action)
+		}
+	}
+	if filter.TargetType != "" {
+		q = q.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		q = q.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Outcome != "" {
+		q = q.Where("outcome = ?", filter.Outcome)
+	}
+	if !filter.Since.IsZero() {
+		q = q.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		q = q.Where("created_at < ?", filter.Until)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var events []domain.AuditEvent
+	if err := q.Order("created_at DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 
 	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
@@ -16,19 +19,35 @@ func NewGormProjectRepository(db *gorm.DB) *GormProjectRepository {
 	return &GormProjectRepository{db: db}
 }
 
-func (r *GormProjectRepository) Create(ctx context.Context, project *domain.Project) error {
+// startSpan membuat span untuk satu operasi repository
+func startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "GormProjectRepository."+operation, append(attrs, attribute.String("db.system", "postgresql"))...)
+}
+
+func (r *GormProjectRepository) Create(ctx context.Context, project *domain.Project) (err error) {
+	ctx, span := startSpan(ctx, "Create")
+	defer func() { tracing.End(span, err) }()
+
 	return r.db.WithContext(ctx).Create(project).Error
 }
 
-func (r *GormProjectRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Project, error) {
+func (r *GormProjectRepository) GetByID(ctx context.Context, id uuid.UUID) (_ *domain.Project, err error) {
+	ctx, span := startSpan(ctx, "GetByID", attribute.String("project.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
 	var p domain.Project
-	if err := r.db.WithContext(ctx).Preload("EnvVars").Preload("Deployments").First(&p, "id = ?", id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("EnvVars").Preload("Deployments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("deployed_at ASC")
+	}).First(&p, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &p, nil
 }
 
-func (r *GormProjectRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]domain.Project, error) {
+func (r *GormProjectRepository) ListByUserID(ctx context.Context, userID uuid.UUID) (_ []domain.Project, err error) {
+	ctx, span := startSpan(ctx, "ListByUserID", attribute.String("user.id", userID.String()))
+	defer func() { tracing.End(span, err) }()
+
 	var projects []domain.Project
 	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&projects).Error; err != nil {
 		return nil, err
@@ -36,10 +55,108 @@ func (r *GormProjectRepository) ListByUserID(ctx context.Context, userID uuid.UU
 	return projects, nil
 }
 
-func (r *GormProjectRepository) Update(ctx context.Context, project *domain.Project) error {
+func (r *GormProjectRepository) ListByOrganizationIDs(ctx context.Context, orgIDs []uuid.UUID) (_ []domain.Project, err error) {
+	ctx, span := startSpan(ctx, "ListByOrganizationIDs", attribute.Int("organization.count", len(orgIDs)))
+	defer func() { tracing.End(span, err) }()
+
+	var projects []domain.Project
+	if len(orgIDs) == 0 {
+		return projects, nil
+	}
+	if err := r.db.WithContext(ctx).Where("organization_id IN ?", orgIDs).Order("created_at ASC").Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (r *GormProjectRepository) Update(ctx context.Context, project *domain.Project) (err error) {
+	ctx, span := startSpan(ctx, "Update", attribute.String("project.id", project.ID.String()))
+	defer func() { tracing.End(span, err) }()
+
 	return r.db.WithContext(ctx).Save(project).Error
 }
 
-func (r *GormProjectRepository) Delete(ctx context.Context, id uuid.UUID) error {
+func (r *GormProjectRepository) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := startSpan(ctx, "Delete", attribute.String("project.id", id.String()))
+	defer func() { tracing.End(span, err) }()
+
 	return r.db.WithContext(ctx).Delete(&domain.Project{}, "id = ?", id).Error
 }
+
+func (r *GormProjectRepository) CreateDeployment(ctx context.Context, deployment *domain.Deployment) (err error) {
+	ctx, span := startSpan(ctx, "CreateDeployment", attribute.String("project.id", deployment.ProjectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	return r.db.WithContext(ctx).Create(deployment).Error
+}
+
+func (r *GormProjectRepository) UpdateDeploymentStatus(ctx context.Context, deploymentID uuid.UUID, status string) (err error) {
+	ctx, span := startSpan(ctx, "UpdateDeploymentStatus", attribute.String("deployment.id", deploymentID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	return r.db.WithContext(ctx).Model(&domain.Deployment{}).Where("id = ?", deploymentID).Update("status", status).Error
+}
+
+func (r *GormProjectRepository) ListByStatus(ctx context.Context, status string) (_ []domain.Project, err error) {
+	ctx, span := startSpan(ctx, "ListByStatus", attribute.String("project.status", status))
+	defer func() { tracing.End(span, err) }()
+
+	var projects []domain.Project
+	if err := r.db.WithContext(ctx).Preload("Deployments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("deployed_at ASC")
+	}).Where("status = ?", status).Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (r *GormProjectRepository) CountByStatus(ctx context.Context) (_ map[string]int64, err error) {
+	ctx, span := startSpan(ctx, "CountByStatus")
+	defer func() { tracing.End(span, err) }()
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := r.db.WithContext(ctx).Model(&domain.Project{}).Select("status, count(*) as count").Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *GormProjectRepository) ReplaceEnvVars(ctx context.Context, projectID uuid.UUID, envVars []domain.EnvVar) (err error) {
+	ctx, span := startSpan(ctx, "ReplaceEnvVars", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_id = ?", projectID).Delete(&domain.EnvVar{}).Error; err != nil {
+			return err
+		}
+		if len(envVars) == 0 {
+			return nil
+		}
+		for i := range envVars {
+			envVars[i].ProjectID = projectID
+		}
+		return tx.Create(&envVars).Error
+	})
+}
+
+func (r *GormProjectRepository) CreateBuild(ctx context.Context, build *domain.Build) (err error) {
+	ctx, span := startSpan(ctx, "CreateBuild", attribute.String("project.id", build.ProjectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	return r.db.WithContext(ctx).Create(build).Error
+}
+
+func (r *GormProjectRepository) UpdateBuild(ctx context.Context, build *domain.Build) (err error) {
+	ctx, span := startSpan(ctx, "UpdateBuild", attribute.String("build.id", build.ID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	return r.db.WithContext(ctx).Save(build).Error
+}
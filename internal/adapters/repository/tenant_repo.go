@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+type GormTenantRepository struct {
+	db *gorm.DB
+}
+
+func NewGormTenantRepository(db *gorm.DB) *GormTenantRepository {
+	return &GormTenantRepository{db: db}
+}
+
+// owner pemegang akun tenant: owner organization yang paling awal bergabung.
+// Organization tanpa owner mengembalikan nil tanpa error.
+func (r *GormTenantRepository) owner(ctx context.Context, orgID uuid.UUID) (*domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).
+		Joins("JOIN memberships ON memberships.user_id = users.id").
+		Where("memberships.organization_id = ? AND memberships.role = ?", orgID, domain.RoleOwner).
+		Order("memberships.created_at ASC, memberships.id ASC").
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormTenantRepository) IsSuspended(ctx context.Context, orgID uuid.UUID) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "GormTenantRepository.IsSuspended", attribute.String("organization.id", orgID.String()), attribute.String("db.system", "postgresql"))
+	defer func() { tracing.End(span, err) }()
+
+	owner, err := r.owner(ctx, orgID)
+	if err != nil || owner == nil {
+		return false, err
+	}
+	return owner.Suspended(), nil
+}
+
+func (r *GormTenantRepository) MaxProjects(ctx context.Context, orgID uuid.UUID) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "GormTenantRepository.MaxProjects", attribute.String("organization.id", orgID.String()), attribute.String("db.system", "postgresql"))
+	defer func() { tracing.End(span, err) }()
+
+	owner, err := r.owner(ctx, orgID)
+	if err != nil || owner == nil {
+		return 0, err
+	}
+	return owner.MaxProjects, nil
+}
+
+func (r *GormTenantRepository) Plan(ctx context.Context, orgID uuid.UUID) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "GormTenantRepository.Plan", attribute.String("organization.id", orgID.String()), attribute.String("db.system", "postgresql"))
+	defer func() { tracing.End(span, err) }()
+
+	owner, err := r.owner(ctx, orgID)
+	if err != nil {
+		return "", err
+	}
+	if owner == nil || owner.Plan == "" {
+		return domain.DefaultPlan, nil
+	}
+	return owner.Plan, nil
+}
+
+func (r *GormTenantRepository) CountProjects(ctx context.Context, orgID uuid.UUID) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "GormTenantRepository.CountProjects", attribute.String("organization.id", orgID.String()), attribute.String("db.system", "postgresql"))
+	defer func() { tracing.End(span, err) }()
+
+	orgIDs := []uuid.UUID{orgID}
+	owner, err := r.owner(ctx, orgID)
+	if err != nil {
+		return 0, err
+	}
+	if owner != nil {
+		if orgIDs, err = r.OwnedOrganizationIDs(ctx, owner.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	var count int64
+	err = r.db.WithContext(ctx).Model(&domain.Project{}).Where("organization_id IN ?", orgIDs).Count(&count).Error
+	return count, err
+}
+
+func (r *GormTenantRepository) OwnedOrganizationIDs(ctx context.Context, userID uuid.UUID) (_ []uuid.UUID, err error) {
+	ctx, span := tracing.Start(ctx, "GormTenantRepository.OwnedOrganizationIDs", attribute.String("user.id", userID.String()), attribute.String("db.system", "postgresql"))
+	defer func() { tracing.End(span, err) }()
+
+	var memberships []domain.Membership
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND role = ?", userID, domain.RoleOwner).Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+	// Co-owner yang bergabung belakangan bukan pemegang akun tenant
+	var ids []uuid.UUID
+	for _, m := range memberships {
+		owner, err := r.owner(ctx, m.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		if owner != nil && owner.ID == userID {
+			ids = append(ids, m.OrganizationID)
+		}
+	}
+	return ids, nil
+}
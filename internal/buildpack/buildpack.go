@@ -0,0 +1,171 @@
+// Package buildpack mendeteksi stack dari isi source (go.mod, package.json, requirements.txt, index.html)
+// dan membuat Dockerfile dari template untuk project yang tidak menyertakan Dockerfile sendiri.
+package buildpack
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Nama stack yang dikenali
+const (
+	StackGo     = "go"
+	StackNode   = "node"
+	StackPython = "python"
+	StackStatic = "static"
+)
+
+// DockerfileName nama Dockerfile hasil generate di dalam build context
+const DockerfileName = ".mth.Dockerfile"
+
+const defaultGoVersion = "1.25"
+
+var ErrNotDetected = errors.New("no Dockerfile found and the stack could not be detected (expected go.mod, package.json, requirements.txt or index.html)")
+
+//go:embed templates/*.Dockerfile.tmpl
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.Dockerfile.tmpl"))
+
+var goDirective = regexp.MustCompile(`(?m)^go\s+(\d+\.\d+)`)
+
+// Stack hasil deteksi beserta parameter template-nya
+type Stack struct {
+	Name        string
+	DefaultPort int
+
+	// Go
+	GoVersion string
+	// Go: package main yang di-build; Python: file yang dijalankan
+	Main string
+	// Node
+	Yarn     bool
+	Lockfile bool
+	// Python: target gunicorn (module:app) jika gunicorn ada di requirements.txt
+	WSGI string
+}
+
+// Detect memeriksa file di root dir secara berurutan: go.mod, package.json, requirements.txt, index.html
+func Detect(dir string) (*Stack, error) {
+	switch {
+	case exists(dir, "go.mod"):
+		return detectGo(dir)
+	case exists(dir, "package.json"):
+		return &Stack{
+			Name:        StackNode,
+			DefaultPort: 3000,
+			Yarn:        exists(dir, "yarn.lock"),
+			Lockfile:    exists(dir, "package-lock.json"),
+		}, nil
+	case exists(dir, "requirements.txt"):
+		return detectPython(dir)
+	case exists(dir, "index.html"):
+		return &Stack{Name: StackStatic, DefaultPort: 8080}, nil
+	}
+	return nil, ErrNotDetected
+}
+
+// Dockerfile merender template stack dengan port yang didengarkan aplikasi
+func (s *Stack) Dockerfile(port int) ([]byte, error) {
+	data := struct {
+		*Stack
+		Port int
+	}{s, port}
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, s.Name+".Dockerfile.tmpl", data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// IgnorePatterns pola .dockerignore bawaan stack, ditambahkan ke .dockerignore source
+func (s *Stack) IgnorePatterns() []string {
+	patterns := []string{DockerfileName, ".dockerignore"}
+	switch s.Name {
+	case StackNode:
+		patterns = append(patterns, "node_modules")
+	case StackPython:
+		patterns = append(patterns, "**/__pycache__", ".venv", "venv")
+	}
+	return patterns
+}
+
+func detectGo(dir string) (*Stack, error) {
+	stack := &Stack{Name: StackGo, DefaultPort: 8080, GoVersion: defaultGoVersion}
+	if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		if m := goDirective.FindSubmatch(data); m != nil {
+			stack.GoVersion = string(m[1])
+		}
+	}
+
+	// package main di root, atau satu-satunya folder di cmd/
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.go")); len(matches) > 0 {
+		stack.Main = "."
+		return stack, nil
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "cmd"))
+	var cmds []string
+	for _, e := range entries {
+		if e.IsDir() {
+			cmds = append(cmds, e.Name())
+		}
+	}
+	if len(cmds) != 1 {
+		return nil, fmt.Errorf("go project must have a main package at the root or exactly one directory under cmd/ (found %d)", len(cmds))
+	}
+	stack.Main = "./cmd/" + cmds[0]
+	return stack, nil
+}
+
+func detectPython(dir string) (*Stack, error) {
+	stack := &Stack{Name: StackPython, DefaultPort: 8000}
+	for _, name := range []string{"app.py", "main.py", "wsgi.py"} {
+		if exists(dir, name) {
+			stack.Main = name
+			break
+		}
+	}
+	if stack.Main == "" {
+		return nil, errors.New("python project must have app.py, main.py or wsgi.py")
+	}
+	if requiresPackage(filepath.Join(dir, "requirements.txt"), "gunicorn") {
+		stack.WSGI = strings.TrimSuffix(stack.Main, ".py") + ":app"
+	}
+	return stack, nil
+}
+
+// requiresPackage cek nama package di requirements.txt (mengabaikan versi, extras, dan komentar)
+func requiresPackage(path, pkg string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if i := strings.IndexAny(line, "#;=<>~![ "); i >= 0 {
+			line = line[:i]
+		}
+		if strings.EqualFold(line, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// exists hanya file biasa; symlink diabaikan supaya deteksi tidak membaca file di luar source
+func exists(dir, name string) bool {
+	info, err := os.Lstat(filepath.Join(dir, name))
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,155 @@
+package buildpack
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles membuat file di dir; isi "->target" berarti symlink ke target
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if target, ok := strings.CutPrefix(content, "->"); ok {
+			if err := os.Symlink(target, path); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "go.mod")
+	if err := os.WriteFile(outside, []byte("module x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    Stack
+		wantErr bool
+	}{
+		{
+			name:  "go with main at root",
+			files: map[string]string{"go.mod": "module x\n\ngo 1.22.3\n", "main.go": "package main"},
+			want:  Stack{Name: StackGo, DefaultPort: 8080, GoVersion: "1.22", Main: "."},
+		},
+		{
+			name:  "go with single cmd and no go directive",
+			files: map[string]string{"go.mod": "module x\n", "cmd/server/main.go": "package main"},
+			want:  Stack{Name: StackGo, DefaultPort: 8080, GoVersion: defaultGoVersion, Main: "./cmd/server"},
+		},
+		{
+			name:    "go with several cmds",
+			files:   map[string]string{"go.mod": "module x\n", "cmd/a/main.go": "", "cmd/b/main.go": ""},
+			wantErr: true,
+		},
+		{
+			name:  "go wins over package.json",
+			files: map[string]string{"go.mod": "module x\n", "main.go": "", "package.json": "{}"},
+			want:  Stack{Name: StackGo, DefaultPort: 8080, GoVersion: defaultGoVersion, Main: "."},
+		},
+		{
+			name:  "node with yarn",
+			files: map[string]string{"package.json": "{}", "yarn.lock": ""},
+			want:  Stack{Name: StackNode, DefaultPort: 3000, Yarn: true},
+		},
+		{
+			name:  "node with npm lockfile",
+			files: map[string]string{"package.json": "{}", "package-lock.json": "{}"},
+			want:  Stack{Name: StackNode, DefaultPort: 3000, Lockfile: true},
+		},
+		{
+			name:  "python with gunicorn",
+			files: map[string]string{"requirements.txt": "flask==3.0\nGunicorn>=21 # server\n", "wsgi.py": ""},
+			want:  Stack{Name: StackPython, DefaultPort: 8000, Main: "wsgi.py", WSGI: "wsgi:app"},
+		},
+		{
+			name:  "python without gunicorn",
+			files: map[string]string{"requirements.txt": "flask\n# gunicorn\n", "app.py": "", "main.py": ""},
+			want:  Stack{Name: StackPython, DefaultPort: 8000, Main: "app.py"},
+		},
+		{
+			name:    "python without entrypoint",
+			files:   map[string]string{"requirements.txt": "flask\n"},
+			wantErr: true,
+		},
+		{
+			name:  "static site",
+			files: map[string]string{"index.html": "<h1>hi</h1>"},
+			want:  Stack{Name: StackStatic, DefaultPort: 8080},
+		},
+		{
+			name:    "symlinked marker is ignored",
+			files:   map[string]string{"go.mod": "->" + outside, "main.go": ""},
+			wantErr: true,
+		},
+		{
+			name:    "nothing recognised",
+			files:   map[string]string{"README.md": "hello"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			got, err := Detect(dir)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Detect = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Fatalf("Detect = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Detect(t.TempDir()); !errors.Is(err, ErrNotDetected) {
+		t.Fatalf("empty dir: err = %v, want ErrNotDetected", err)
+	}
+}
+
+func TestStackDockerfile(t *testing.T) {
+	tests := []struct {
+		stack Stack
+		want  []string
+	}{
+		{stack: Stack{Name: StackGo, GoVersion: "1.22", Main: "./cmd/server"}, want: []string{"FROM golang:1.22-alpine", "-o /out/app ./cmd/server", "EXPOSE 9000"}},
+		{stack: Stack{Name: StackNode, Yarn: true}, want: []string{"yarn install --frozen-lockfile", "EXPOSE 9000"}},
+		{stack: Stack{Name: StackNode, Lockfile: true}, want: []string{"npm ci", "EXPOSE 9000"}},
+		{stack: Stack{Name: StackPython, Main: "app.py", WSGI: "app:app"}, want: []string{"gunicorn --bind 0.0.0.0:$PORT app:app", "EXPOSE 9000"}},
+		{stack: Stack{Name: StackPython, Main: "main.py"}, want: []string{`CMD ["python", "main.py"]`}},
+		{stack: Stack{Name: StackStatic}, want: []string{"listen 9000;", "EXPOSE 9000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stack.Name, func(t *testing.T) {
+			out, err := tt.stack.Dockerfile(9000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(out), want) {
+					t.Fatalf("Dockerfile lacks %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+// Package config konfigurasi server yang dibaca sekali saat startup.
+// Urutan prioritas: nilai default < file JSON (-config / CONFIG_FILE) < environment variable < flag.
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Config struct {
+	LogLevel       string         `json:"log_level"`
+	BaseDomain     string         `json:"base_domain"` // project di-route ke <subdomain>.<base_domain>
+	AppURL         string         `json:"app_url"`     // URL frontend, dipakai di link email
+	RateLimitStore string         `json:"rate_limit_store"`
+	SeccompProfile string         `json:"seccomp_profile"` // path file JSON profile seccomp container tenant
+	Server         ServerConfig   `json:"server"`
+	Database       DatabaseConfig `json:"database"`
+	Docker         DockerConfig   `json:"docker"`
+	Auth           AuthConfig     `json:"auth"`
+	OIDC           OIDCConfig     `json:"oidc"`
+	Mail           MailConfig     `json:"mail"`
+	Build          BuildConfig    `json:"build"`
+}
+
+type ServerConfig struct {
+	Addr        string `json:"addr"`
+	MetricsAddr string `json:"metrics_addr"` // listener terpisah untuk /metrics; jangan diekspos ke publik
+}
+
+type DatabaseConfig struct {
+	DSN string `json:"dsn"`
+}
+
+type DockerConfig struct {
+	ProxyContainer string `json:"proxy_container"` // container Traefik yang disambungkan ke network tenant
+	NetworkPrefix  string `json:"network_prefix"`  // nama network tenant = prefix + ID organization
+}
+
+type AuthConfig struct {
+	JWTKeysDir     string   `json:"jwt_keys_dir"`
+	JWTActiveKID   string   `json:"jwt_active_kid"`
+	Secret         string   `json:"secret"`          // base64, minimal 32 byte
+	EncryptionKey  string   `json:"encryption_key"`  // base64, tepat 32 byte
+	PlatformAdmins []string `json:"platform_admins"` // user ID atau email yang sudah diverifikasi
+}
+
+type OIDCConfig struct {
+	Issuer               string   `json:"issuer"`
+	ClientID             string   `json:"client_id"`
+	ClientSecret         string   `json:"client_secret"`
+	RedirectURL          string   `json:"redirect_url"`
+	AllowedDomains       []string `json:"allowed_domains"`
+	PostLoginRedirect    string   `json:"post_login_redirect"`
+	DisablePasswordLogin bool     `json:"disable_password_login"`
+}
+
+type MailConfig struct {
+	From         string `json:"from"`
+	Dir          string `json:"dir"` // dipakai jika SMTP host kosong
+	SMTPHost     string `json:"smtp_host"`
+	SMTPPort     int    `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword string `json:"smtp_password"`
+}
+
+type BuildConfig struct {
+	MaxUploadMB   int64 `json:"max_upload_mb"`
+	MaxContextMB  int64 `json:"max_context_mb"`
+	GitAllowLocal bool  `json:"git_allow_local"` // izinkan clone dari path lokal (development saja)
+}
+
+// Default nilai yang dipakai jika tidak di-set di file, env, maupun flag
+func Default() *Config {
+	return &Config{
+		LogLevel:       "info",
+		BaseDomain:     "localhost",
+		AppURL:         "http://localhost:5173",
+		RateLimitStore: "memory",
+		Server:         ServerConfig{Addr: ":8080", MetricsAddr: "127.0.0.1:9090"},
+		Database: DatabaseConfig{
+			DSN: "host=localhost user=postgres password=postgres dbname=multitenant port=5432 sslmode=disable TimeZone=Asia/Jakarta",
+		},
+		Docker: DockerConfig{ProxyContainer: "traefik", NetworkPrefix: "mth-org-"},
+		Mail:   MailConfig{From: "no-reply@localhost", Dir: "mail-outbox", SMTPPort: 587},
+		Build:  BuildConfig{MaxUploadMB: 100, MaxContextMB: 500},
+	}
+}
+
+// Load membaca konfigurasi dari file, env dan argumen command line lalu memvalidasinya
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", getenv("CONFIG_FILE"), "path file konfigurasi JSON")
+	// Flag diterapkan setelah env supaya selalu menang
+	var pending []func() error
+	for _, f := range cfg.flags() {
+		fs.Func(f.name, f.usage, func(v string) error {
+			pending = append(pending, func() error {
+				if err := f.set(v); err != nil {
+					return fmt.Errorf("-%s: %w", f.name, err)
+				}
+				return nil
+			})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path != "" {
+		if err := cfg.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range cfg.envBindings() {
+		v := getenv(b.name)
+		if v == "" {
+			continue
+		}
+		if err := b.set(v); err != nil {
+			return nil, fmt.Errorf("%s: %w", b.name, err)
+		}
+	}
+	for _, apply := range pending {
+		if err := apply(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+type binding struct {
+	name  string
+	usage string
+	set   func(string) error
+}
+
+func (c *Config) envBindings() []binding {
+	return []binding{
+		{name: "LOG_LEVEL", set: stringVar(&c.LogLevel)},
+		{name: "BASE_DOMAIN", set: stringVar(&c.BaseDomain)},
+		{name: "APP_URL", set: stringVar(&c.AppURL)},
+		{name: "RATE_LIMIT_STORE", set: stringVar(&c.RateLimitStore)},
+		{name: "SECCOMP_PROFILE", set: stringVar(&c.SeccompProfile)},
+		{name: "HTTP_ADDR", set: stringVar(&c.Server.Addr)},
+		{name: "METRICS_ADDR", set: stringVar(&c.Server.MetricsAddr)},
+		{name: "DB_DSN", set: stringVar(&c.Database.DSN)},
+		{name: "TRAEFIK_CONTAINER", set: stringVar(&c.Docker.ProxyContainer)},
+		{name: "TENANT_NETWORK_PREFIX", set: stringVar(&c.Docker.NetworkPrefix)},
+		{name: "JWT_KEYS_DIR", set: stringVar(&c.Auth.JWTKeysDir)},
+		{name: "JWT_ACTIVE_KID", set: stringVar(&c.Auth.JWTActiveKID)},
+		{name: "AUTH_SECRET", set: stringVar(&c.Auth.Secret)},
+		{name: "ENCRYPTION_KEY", set: stringVar(&c.Auth.EncryptionKey)},
+		{name: "PLATFORM_ADMINS", set: listVar(&c.Auth.PlatformAdmins)},
+		{name: "OIDC_ISSUER", set: stringVar(&c.OIDC.Issuer)},
+		{name: "OIDC_CLIENT_ID", set: stringVar(&c.OIDC.ClientID)},
+		{name: "OIDC_CLIENT_SECRET", set: stringVar(&c.OIDC.ClientSecret)},
+		{name: "OIDC_REDIRECT_URL", set: stringVar(&c.OIDC.RedirectURL)},
+		{name: "OIDC_ALLOWED_DOMAINS", set: listVar(&c.OIDC.AllowedDomains)},
+		{name: "OIDC_POST_LOGIN_REDIRECT", set: stringVar(&c.OIDC.PostLoginRedirect)},
+		{name: "OIDC_DISABLE_PASSWORD_LOGIN", set: boolVar(&c.OIDC.DisablePasswordLogin)},
+		{name: "MAIL_FROM", set: stringVar(&c.Mail.From)},
+		{name: "MAIL_DIR", set: stringVar(&c.Mail.Dir)},
+		{name: "SMTP_HOST", set: stringVar(&c.Mail.SMTPHost)},
+		{name: "SMTP_PORT", set: intVar(&c.Mail.SMTPPort)},
+		{name: "SMTP_USERNAME", set: stringVar(&c.Mail.SMTPUsername)},
+		{name: "SMTP_PASSWORD", set: stringVar(&c.Mail.SMTPPassword)},
+		{name: "BUILD_MAX_UPLOAD_MB", set: int64Var(&c.Build.MaxUploadMB)},
+		{name: "BUILD_MAX_CONTEXT_MB", set: int64Var(&c.Build.MaxContextMB)},
+		{name: "GIT_ALLOW_LOCAL", set: boolVar(&c.Build.GitAllowLocal)},
+	}
+}
+
+// flags hanya untuk nilai non-secret; secret tidak boleh terlihat di daftar proses
+func (c *Config) flags() []binding {
+	return []binding{
+		{name: "addr", usage: "alamat listen HTTP (HTTP_ADDR)", set: stringVar(&c.Server.Addr)},
+		{name: "metrics-addr", usage: "alamat listen internal untuk /metrics (METRICS_ADDR)", set: stringVar(&c.Server.MetricsAddr)},
+		{name: "base-domain", usage: "domain dasar subdomain project (BASE_DOMAIN)", set: stringVar(&c.BaseDomain)},
+		{name: "log-level", usage: "debug, info, warn atau error (LOG_LEVEL)", set: stringVar(&c.LogLevel)},
+		{name: "traefik-container", usage: "container Traefik yang disambungkan ke network tenant (TRAEFIK_CONTAINER)", set: stringVar(&c.Docker.ProxyContainer)},
+		{name: "network-prefix", usage: "prefix nama network Docker tenant (TENANT_NETWORK_PREFIX)", set: stringVar(&c.Docker.NetworkPrefix)},
+	}
+}
+
+func stringVar(p *string) func(string) error {
+	return func(v string) error {
+		*p = v
+		return nil
+	}
+}
+
+// listVar nilai "a,b,c" menjadi slice (mengabaikan elemen kosong)
+func listVar(p *[]string) func(string) error {
+	return func(v string) error {
+		*p = nil
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				*p = append(*p, part)
+			}
+		}
+		return nil
+	}
+}
+
+func boolVar(p *bool) func(string) error {
+	return func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*p = b
+		return nil
+	}
+}
+
+func intVar(p *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		*p = n
+		return nil
+	}
+}
+
+func int64Var(p *int64) func(string) error {
+	return func(v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		*p = n
+		return nil
+	}
+}
+
+var (
+	hostnamePattern      = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	networkPrefixPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,31}$`)
+)
+
+// Validate mengumpulkan semua kesalahan konfigurasi sekaligus supaya bisa diperbaiki dalam satu kali restart
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log level %q must be debug, info, warn or error", c.LogLevel))
+	}
+	check(hostnamePattern.MatchString(c.BaseDomain), "base domain %q must be a lowercase hostname without scheme or port", c.BaseDomain)
+	if u, err := url.Parse(c.AppURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("app url %q must be an absolute http(s) URL", c.AppURL))
+	}
+	check(c.RateLimitStore == "memory" || c.RateLimitStore == "postgres", "rate limit store %q must be memory or postgres", c.RateLimitStore)
+
+	_, port, err := net.SplitHostPort(c.Server.Addr)
+	if err != nil || port == "" {
+		errs = append(errs, fmt.Errorf("server addr %q must be host:port (e.g. :8080)", c.Server.Addr))
+	}
+	if _, metricsPort, err := net.SplitHostPort(c.Server.MetricsAddr); err != nil || metricsPort == "" {
+		errs = append(errs, fmt.Errorf("metrics addr %q must be host:port (e.g. 127.0.0.1:9090)", c.Server.MetricsAddr))
+	} else {
+		check(metricsPort != port, "metrics addr %q must not share the API port", c.Server.MetricsAddr)
+	}
+	check(c.Database.DSN != "", "database dsn is required")
+	check(networkPrefixPattern.MatchString(c.Docker.NetworkPrefix), "docker network prefix %q must start with a letter or digit and contain only letters, digits, '_', '.' or '-' (max 32)", c.Docker.NetworkPrefix)
+
+	if _, err := c.Auth.SecretBytes(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.Auth.EncryptionKeyBytes(); err != nil {
+		errs = append(errs, err)
+	}
+	check((c.OIDC.Issuer == "") == (c.OIDC.ClientID == ""), "oidc issuer and client id must be set together")
+
+	check(c.Mail.From != "", "mail from is required")
+	check(c.Mail.SMTPPort > 0 && c.Mail.SMTPPort <= 65535, "smtp port %d out of range", c.Mail.SMTPPort)
+	check(c.Build.MaxUploadMB > 0, "build max upload must be positive (MB)")
+	check(c.Build.MaxContextMB > 0, "build max context must be positive (MB)")
+
+	return errors.Join(errs...)
+}
+
+// SecretBytes secret HMAC yang sudah di-decode; nil jika tidak di-set
+func (a AuthConfig) SecretBytes() ([]byte, error) {
+	if a.Secret == "" {
+		return nil, nil
+	}
+	secret, err := base64.StdEncoding.DecodeString(a.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("auth secret must be base64: %w", err)
+	}
+	if len(secret) < 32 {
+		return nil, errors.New("auth secret must decode to at least 32 bytes")
+	}
+	return secret, nil
+}
+
+// EncryptionKeyBytes key AES-256 yang sudah di-decode; nil jika tidak di-set
+func (a AuthConfig) EncryptionKeyBytes() ([]byte, error) {
+	if a.EncryptionKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(a.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key must be base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, errors.New("encryption key must decode to exactly 32 bytes")
+	}
+	return key, nil
+}
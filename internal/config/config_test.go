@@ -0,0 +1,139 @@
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// env getenv dari map untuk Load
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestLoadDefaultsAreValid(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatalf("Load with defaults: %v", err)
+	}
+	if cfg.Server.Addr != ":8080" || cfg.Server.MetricsAddr != "127.0.0.1:9090" || cfg.Docker.NetworkPrefix != "mth-org-" {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := `{"base_domain": "file.example", "log_level": "debug", "server": {"addr": ":7000"}, "docker": {"network_prefix": "file-"}}`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(
+		[]string{"-config", path, "-addr", ":9000"},
+		env(map[string]string{"BASE_DOMAIN": "env.example", "HTTP_ADDR": ":8000", "PLATFORM_ADMINS": " a@example.com, ,b@example.com "}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		field, got, want string
+	}{
+		{"log level (file over default)", cfg.LogLevel, "debug"},
+		{"network prefix (file over default)", cfg.Docker.NetworkPrefix, "file-"},
+		{"base domain (env over file)", cfg.BaseDomain, "env.example"},
+		{"addr (flag over env)", cfg.Server.Addr, ":9000"},
+		{"platform admins (list)", strings.Join(cfg.Auth.PlatformAdmins, "|"), "a@example.com|b@example.com"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"base_domain": "file.example"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(nil, env(map[string]string{"CONFIG_FILE": path}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BaseDomain != "file.example" {
+		t.Fatalf("base domain = %q, want value from CONFIG_FILE", cfg.BaseDomain)
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	short := base64.StdEncoding.EncodeToString(make([]byte, 16))
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		file    string
+		wantErr []string // kosong = valid
+	}{
+		{name: "valid secrets", env: map[string]string{"AUTH_SECRET": secret, "ENCRYPTION_KEY": secret}},
+		{name: "valid oidc", env: map[string]string{"OIDC_ISSUER": "https://idp.example", "OIDC_CLIENT_ID": "mth"}},
+		{name: "unknown log level", env: map[string]string{"LOG_LEVEL": "verbose"}, wantErr: []string{`log level "verbose"`}},
+		{name: "base domain with scheme", args: []string{"-base-domain", "https://example.com"}, wantErr: []string{"base domain"}},
+		{name: "base domain with port", env: map[string]string{"BASE_DOMAIN": "example.com:443"}, wantErr: []string{"base domain"}},
+		{name: "relative app url", env: map[string]string{"APP_URL": "/app"}, wantErr: []string{"app url"}},
+		{name: "unknown rate limit store", env: map[string]string{"RATE_LIMIT_STORE": "redis"}, wantErr: []string{"rate limit store"}},
+		{name: "addr without port", args: []string{"-addr", "localhost"}, wantErr: []string{"server addr"}},
+		{name: "metrics addr without port", env: map[string]string{"METRICS_ADDR": "localhost"}, wantErr: []string{"metrics addr"}},
+		{name: "metrics on the API port", args: []string{"-metrics-addr", "127.0.0.1:8080"}, wantErr: []string{"must not share the API port"}},
+		{name: "invalid network prefix", args: []string{"-network-prefix", "-bad"}, wantErr: []string{"docker network prefix"}},
+		{name: "secret not base64", env: map[string]string{"AUTH_SECRET": "not base64!"}, wantErr: []string{"auth secret must be base64"}},
+		{name: "secret too short", env: map[string]string{"AUTH_SECRET": short}, wantErr: []string{"at least 32 bytes"}},
+		{name: "encryption key wrong length", env: map[string]string{"ENCRYPTION_KEY": short}, wantErr: []string{"exactly 32 bytes"}},
+		{name: "oidc issuer without client id", env: map[string]string{"OIDC_ISSUER": "https://idp.example"}, wantErr: []string{"oidc issuer and client id"}},
+		{name: "smtp port out of range", env: map[string]string{"SMTP_PORT": "70000"}, wantErr: []string{"smtp port 70000"}},
+		{name: "non-positive upload limit", env: map[string]string{"BUILD_MAX_UPLOAD_MB": "0"}, wantErr: []string{"build max upload"}},
+		{name: "env value not a number", env: map[string]string{"SMTP_PORT": "abc"}, wantErr: []string{"SMTP_PORT", "invalid number"}},
+		{name: "env value not a boolean", env: map[string]string{"GIT_ALLOW_LOCAL": "maybe"}, wantErr: []string{"GIT_ALLOW_LOCAL", "invalid boolean"}},
+		{name: "unknown flag", args: []string{"-db-dsn", "x"}, wantErr: []string{"flag provided but not defined"}},
+		{name: "unknown field in file", file: `{"server": {"adr": ":1"}}`, wantErr: []string{"unknown field"}},
+		{
+			name:    "all errors reported at once",
+			env:     map[string]string{"LOG_LEVEL": "verbose", "RATE_LIMIT_STORE": "redis"},
+			args:    []string{"-addr", "bad"},
+			wantErr: []string{"log level", "rate limit store", "server addr"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "config.json")
+				if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				args = append([]string{"-config", path}, args...)
+			}
+
+			_, err := Load(args, env(tt.env))
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Load: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Load succeeded")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
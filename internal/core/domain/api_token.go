@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Aksi yang bisa diberikan ke API token. Format scope: "<aksi>:<resource>",
+// resource berupa ID/subdomain project atau "*" untuk semua project.
+const (
+	ScopeRead   = "read"
+	ScopeWrite  = "write"
+	ScopeDeploy = "deploy"
+)
+
+// APIToken personal access token untuk CI / automation. Token hanya disimpan dalam bentuk hash.
+type APIToken struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"-"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(16);not null" json:"prefix"` // beberapa karakter awal untuk identifikasi di UI
+	TokenHash  string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Scopes     []string   `gorm:"serializer:json;type:text;not null" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Active true jika token belum dicabut dan belum kedaluwarsa
+func (t *APIToken) Active(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
+
+// ValidScope memastikan format scope "<aksi>:<resource>" dengan aksi yang dikenal
+func ValidScope(scope string) bool {
+	action, resource, ok := strings.Cut(scope, ":")
+	if !ok || resource == "" {
+		return false
+	}
+	switch action {
+	case ScopeRead, ScopeWrite, ScopeDeploy, "*":
+		return true
+	}
+	return false
+}
+
+// ScopeAllows cek apakah salah satu scope mengizinkan aksi pada salah satu resource.
+// Tanpa resource (endpoint koleksi) hanya scope dengan resource "*" yang lolos.
+func ScopeAllows(scopes []string, action string, resources ...string) bool {
+	for _, scope := range scopes {
+		a, r, ok := strings.Cut(scope, ":")
+		if !ok || (a != action && a != "*") {
+			continue
+		}
+		if r == "*" {
+			return true
+		}
+		for _, res := range resources {
+			if res != "" && r == res {
+				return true
+			}
+		}
+	}
+	return false
+}
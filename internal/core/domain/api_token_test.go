@@ -0,0 +1,59 @@
+package domain
+
+import "testing"
+
+func TestScopeAllows(t *testing.T) {
+	const projectID = "0b9f6b1e-3f1a-4a47-9d6f-3c1b2a7e8d90"
+
+	tests := []struct {
+		name      string
+		scopes    []string
+		action    string
+		resources []string
+		want      bool
+	}{
+		{name: "exact action and project id", scopes: []string{"read:" + projectID}, action: ScopeRead, resources: []string{projectID, "blog"}, want: true},
+		{name: "exact action and subdomain", scopes: []string{"deploy:blog"}, action: ScopeDeploy, resources: []string{projectID, "blog"}, want: true},
+		{name: "wildcard resource", scopes: []string{"write:*"}, action: ScopeWrite, resources: []string{projectID}, want: true},
+		{name: "wildcard action", scopes: []string{"*:blog"}, action: ScopeDeploy, resources: []string{projectID, "blog"}, want: true},
+		{name: "wildcard resource on collection endpoint", scopes: []string{"read:*"}, action: ScopeRead, want: true},
+		{name: "any matching scope wins", scopes: []string{"read:other", "write:blog"}, action: ScopeWrite, resources: []string{"blog"}, want: true},
+
+		{name: "different action", scopes: []string{"read:blog"}, action: ScopeWrite, resources: []string{"blog"}, want: false},
+		{name: "read does not imply deploy", scopes: []string{"read:*"}, action: ScopeDeploy, resources: []string{"blog"}, want: false},
+		{name: "different project", scopes: []string{"write:other"}, action: ScopeWrite, resources: []string{projectID, "blog"}, want: false},
+		{name: "specific resource on collection endpoint", scopes: []string{"read:blog"}, action: ScopeRead, want: false},
+		{name: "empty resource never matches", scopes: []string{"read:blog"}, action: ScopeRead, resources: []string{""}, want: false},
+		{name: "scope without resource", scopes: []string{"read"}, action: ScopeRead, resources: []string{"read"}, want: false},
+		{name: "prefix of subdomain", scopes: []string{"write:blo"}, action: ScopeWrite, resources: []string{"blog"}, want: false},
+		{name: "no scopes", action: ScopeRead, resources: []string{"blog"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScopeAllows(tt.scopes, tt.action, tt.resources...); got != tt.want {
+				t.Fatalf("ScopeAllows(%v, %q, %v) = %v, want %v", tt.scopes, tt.action, tt.resources, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidScope(t *testing.T) {
+	tests := []struct {
+		scope string
+		want  bool
+	}{
+		{"read:*", true},
+		{"deploy:blog", true},
+		{"*:blog", true},
+		{"admin:*", false},
+		{"read:", false},
+		{"read", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidScope(tt.scope); got != tt.want {
+			t.Errorf("ValidScope(%q) = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Hasil aksi yang diaudit
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent catatan aksi yang mengubah state. Append-only: tabelnya dilindungi trigger
+// sehingga UPDATE/DELETE ditolak database.
+type AuditEvent struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID *uuid.UUID `gorm:"type:uuid;index"`          // kosong untuk aksi level akun (login, token, ...)
+	ActorID        uuid.UUID  `gorm:"type:uuid;not null;index"` // uuid.Nil jika actor tidak dikenal (mis. login username salah)
+	ImpersonatorID *uuid.UUID `gorm:"type:uuid;index"`          // admin yang bertindak atas nama ActorID
+	Action         string     `gorm:"type:varchar(64);not null;index"`
+	TargetType     string     `gorm:"type:varchar(32);not null"` // user, project, organization, ...
+	TargetID       string     `gorm:"type:varchar(64);index"`
+	Before         string     `gorm:"type:text"` // JSON snapshot sebelum aksi
+	After          string     `gorm:"type:text"` // JSON snapshot sesudah aksi
+	Metadata       string     `gorm:"type:text"` // JSON
+	Outcome        string     `gorm:"type:varchar(16);not null;default:'success';index"`
+	Error          string     `gorm:"type:text"`
+	IPAddress      string     `gorm:"type:varchar(45)"`
+	UserAgent      string     `gorm:"type:varchar(255)"`
+	CreatedAt      time.Time  `gorm:"index"`
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status build image
+const (
+	BuildStatusBuilding  = "building"
+	BuildStatusSucceeded = "succeeded"
+	BuildStatusFailed    = "failed"
+)
+
+// Build riwayat build image dari source project
+type Build struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ProjectID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	Source     string    `gorm:"type:varchar(10)"` // git / upload
+	CommitSHA  string    `gorm:"type:varchar(64)"`
+	Stack      string    `gorm:"type:varchar(20)"`  // stack terdeteksi jika Dockerfile dibuat otomatis
+	Image      string    `gorm:"type:varchar(255)"` // tag image hasil build
+	Status     string    `gorm:"type:varchar(20);not null"`
+	Error      string    `gorm:"type:text"`
+	StartedAt  time.Time `gorm:"autoCreateTime"`
+	FinishedAt *time.Time
+}
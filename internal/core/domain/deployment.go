@@ -11,6 +11,8 @@ type Deployment struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	ProjectID   uuid.UUID `gorm:"type:uuid;not null;index"`
 	ContainerID string    `gorm:"type:varchar(64);index"` // Docker Container ID
+	Image       string    `gorm:"type:varchar(255)"`      // image sesuai konfigurasi project, mis. "nginx:alpine"
+	ImageDigest string    `gorm:"type:varchar(255)"`      // referensi yang benar-benar dijalankan: repo@sha256:... atau ID image hasil build lokal
 	Status      string    `gorm:"type:varchar(20)"`       // running, exited, failed
 	DeployedAt  time.Time `gorm:"autoCreateTime"`
 }
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity menghubungkan user lokal dengan akun di identity provider eksternal (OIDC)
+type UserIdentity struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Issuer    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_issuer_subject"`
+	Subject   string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_issuer_subject"`
+	Email     string    `gorm:"type:varchar(100)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPlan plan tenant baru
+const DefaultPlan = "free"
+
+// ImagePolicy aturan image yang boleh di-deploy. Policy platform (Plan kosong) berlaku untuk semua tenant,
+// policy plan berlaku tambahan untuk tenant dengan plan tersebut; image harus lolos keduanya.
+type ImagePolicy struct {
+	ID                  uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Plan                string    `gorm:"type:varchar(30);uniqueIndex" json:"plan"`              // kosong = seluruh platform
+	AllowedRegistries   []string  `gorm:"serializer:json;type:text" json:"allowed_registries"`   // kosong = semua registry
+	BlockedRepositories []string  `gorm:"serializer:json;type:text" json:"blocked_repositories"` // nama repository, boleh pakai wildcard (docker.io/xmrig/*)
+	RequireDigest       bool      `gorm:"not null;default:false" json:"require_digest"`          // image harus ditulis repo@sha256:...
+	MaxImageSizeMB      int64     `gorm:"not null;default:0" json:"max_image_size_mb"`           // 0 = tanpa batas
+	UpdatedBy           uuid.UUID `gorm:"type:uuid" json:"updated_by"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
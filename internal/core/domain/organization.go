@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Role peran user di dalam organization
+type Role string
+
+const (
+	RoleOwner     Role = "owner"     // semua akses + kelola owner & hapus organization
+	RoleAdmin     Role = "admin"     // kelola member, hapus project
+	RoleDeveloper Role = "developer" // buat, ubah, deploy, start/stop project
+	RoleViewer    Role = "viewer"    // hanya baca
+)
+
+var roleLevels = map[Role]int{
+	RoleViewer:    1,
+	RoleDeveloper: 2,
+	RoleAdmin:     3,
+	RoleOwner:     4,
+}
+
+// Valid true jika role dikenal
+func (r Role) Valid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// AtLeast true jika role ini setara atau lebih tinggi dari min
+func (r Role) AtLeast(min Role) bool {
+	return roleLevels[r] >= roleLevels[min]
+}
+
+// Organization pemilik project. Setiap user punya satu personal organization.
+type Organization struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name       string    `gorm:"type:varchar(100);not null"`
+	Slug       string    `gorm:"type:varchar(63);uniqueIndex;not null"`
+	Personal   bool      `gorm:"not null;default:false"`
+	Require2FA bool      `gorm:"column:require_2fa;not null;default:false"` // member wajib mengaktifkan TOTP
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	// Relations
+	Memberships []Membership `gorm:"foreignKey:OrganizationID"`
+	Projects    []Project    `gorm:"foreignKey:OrganizationID"`
+}
+
+// Membership relasi user <-> organization beserta role-nya
+type Membership struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_membership_org_user"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_membership_org_user;index"`
+	Role           Role      `gorm:"type:varchar(20);not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Invitation undangan bergabung ke organization, diterima lewat token (disimpan hash)
+type Invitation struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;index"`
+	Email          string    `gorm:"type:varchar(100);not null;index"`
+	Role           Role      `gorm:"type:varchar(20);not null"`
+	TokenHash      string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	InvitedBy      uuid.UUID `gorm:"type:uuid;not null"`
+	ExpiresAt      time.Time `gorm:"not null"`
+	AcceptedAt     *time.Time
+	CreatedAt      time.Time
+}
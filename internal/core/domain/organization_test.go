@@ -0,0 +1,25 @@
+package domain
+
+import "testing"
+
+func TestRoleAtLeast(t *testing.T) {
+	// Urutan dari terendah ke tertinggi
+	ordered := []Role{RoleViewer, RoleDeveloper, RoleAdmin, RoleOwner}
+
+	for i, role := range ordered {
+		for j, min := range ordered {
+			if got, want := role.AtLeast(min), i >= j; got != want {
+				t.Errorf("%s.AtLeast(%s) = %v, want %v", role, min, got, want)
+			}
+		}
+	}
+
+	for _, unknown := range []Role{"", "superuser", "Owner"} {
+		if unknown.Valid() {
+			t.Errorf("%q.Valid() = true", unknown)
+		}
+		if unknown.AtLeast(RoleViewer) {
+			t.Errorf("unknown role %q passes AtLeast(viewer)", unknown)
+		}
+	}
+}
@@ -6,23 +6,59 @@ import (
 	"github.com/google/uuid"
 )
 
-// Project merepresentasikan aplikasi web yang dimiliki user
+// Project merepresentasikan aplikasi web yang dimiliki organization
 type Project struct {
-	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	UserID        uuid.UUID `gorm:"type:uuid;not null;index"`
-	Name          string    `gorm:"type:varchar(100);not null"`
-	Subdomain     string    `gorm:"type:varchar(63);uniqueIndex;not null"` // e.g., "blog" -> blog.domain.com
-	ImageName     string    `gorm:"type:varchar(255);not null"`            // e.g., "nginx:alpine"
-	ContainerPort int       `gorm:"not null"`                              // e.g., 80
-	Status        string    `gorm:"type:varchar(20);default:'stopped'"`    // active, stopped
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;index"` // user yang membuat project
+	OrganizationID uuid.UUID `gorm:"type:uuid;index"`          // pemilik project
+	Name           string    `gorm:"type:varchar(100);not null"`
+	Subdomain      string    `gorm:"type:varchar(63);uniqueIndex;not null"` // e.g., "blog" -> blog.domain.com
+	ImageName      string    `gorm:"type:varchar(255);not null"`            // e.g., "nginx:alpine"
+	ContainerPort  int       `gorm:"not null"`                              // e.g., 80
+	Status         string    `gorm:"type:varchar(20);default:'stopped'"`    // active, stopped
+	SourceType     string    `gorm:"type:varchar(10);not null;default:'image'"`
+	PullPolicy     string    `gorm:"type:varchar(20);not null;default:'if-not-present'"` // hanya untuk SourceType "image"
+	GitSource      `gorm:"embedded"`
+	Security       SecurityRelaxations `gorm:"embedded;embeddedPrefix:security_"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 
 	// Relations
 	Deployments []Deployment `gorm:"foreignKey:ProjectID"`
 	EnvVars     []EnvVar     `gorm:"foreignKey:ProjectID"`
 }
 
+// Sumber image project
+const (
+	SourceTypeImage  = "image"  // image jadi dari registry (ImageName)
+	SourceTypeGit    = "git"    // dibangun dari repository Git, ImageName diisi tag hasil build
+	SourceTypeUpload = "upload" // dibangun dari build context tar.gz yang di-upload
+)
+
+// Kapan image di-pull dari registry saat deploy
+const (
+	PullAlways       = "always"         // selalu pull, tag di-resolve ulang ke digest terbaru
+	PullIfNotPresent = "if-not-present" // pakai image lokal jika ada
+	PullNever        = "never"          // hanya image lokal, deploy gagal jika tidak ada
+)
+
+// ValidPullPolicy true jika policy dikenal
+func ValidPullPolicy(policy string) bool {
+	switch policy {
+	case PullAlways, PullIfNotPresent, PullNever:
+		return true
+	}
+	return false
+}
+
+// GitSource lokasi kode untuk project dengan SourceType "git"
+type GitSource struct {
+	GitURL         string `gorm:"type:varchar(255)"`
+	GitBranch      string `gorm:"type:varchar(100)"` // kosong = default branch repository
+	GitSubdir      string `gorm:"type:varchar(255)"` // build context relatif terhadap root repository
+	DockerfilePath string `gorm:"type:varchar(255)"` // relatif terhadap build context, default "Dockerfile"
+}
+
 // EnvVar menyimpan konfigurasi environment variable untuk container
 type EnvVar struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegistryCredential login private registry milik organization. Token hanya disimpan terenkripsi
+// dan tidak pernah dikembalikan lewat API.
+type RegistryCredential struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrganizationID  uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_registry_credential_org_host" json:"organization_id"`
+	Registry        string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_registry_credential_org_host" json:"registry"` // host registry, mis. ghcr.io atau docker.io
+	Username        string     `gorm:"type:varchar(255);not null" json:"username"`
+	TokenCiphertext []byte     `gorm:"not null" json:"-"`
+	CreatedBy       uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SecurityRelaxations pelonggaran hardening container yang diminta tenant untuk satu project.
+// Hanya berlaku jika SecurityPolicy plan tenant mengizinkannya.
+type SecurityRelaxations struct {
+	WritableRootfs  bool     `gorm:"not null;default:false" json:"writable_rootfs"`     // rootfs tidak read-only
+	RunAsImageUser  bool     `gorm:"not null;default:false" json:"run_as_image_user"`   // pakai USER dari image (bisa root)
+	AddCapabilities []string `gorm:"serializer:json;type:text" json:"add_capabilities"` // capability yang dikembalikan setelah drop ALL
+}
+
+// SecurityPolicy pelonggaran hardening yang boleh dipilih tenant dengan plan tertentu.
+// Plan tanpa SecurityPolicy memakai profil paling ketat tanpa pelonggaran.
+type SecurityPolicy struct {
+	ID                  uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Plan                string    `gorm:"type:varchar(30);uniqueIndex;not null" json:"plan"`
+	AllowWritableRootfs bool      `gorm:"not null;default:false" json:"allow_writable_rootfs"`
+	AllowImageUser      bool      `gorm:"not null;default:false" json:"allow_image_user"`
+	AllowedCapabilities []string  `gorm:"serializer:json;type:text" json:"allowed_capabilities"`
+	PidsLimit           int64     `gorm:"not null;default:0" json:"pids_limit"` // 0 = default platform
+	UpdatedBy           uuid.UUID `gorm:"type:uuid" json:"updated_by"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
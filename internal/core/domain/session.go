@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session merepresentasikan satu login (device) user. Refresh token disimpan dalam bentuk hash.
+type Session struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID            uuid.UUID  `gorm:"type:uuid;not null;index"`
+	RefreshTokenHash  string     `gorm:"type:varchar(64);uniqueIndex;not null"` // sha256 hex
+	PreviousTokenHash string     `gorm:"type:varchar(64);index"`                // token sebelum rotasi, untuk deteksi reuse
+	UserAgent         string     `gorm:"type:varchar(255)"`
+	IPAddress         string     `gorm:"type:varchar(45)"`
+	ExpiresAt         time.Time  `gorm:"not null"`
+	RevokedAt         *time.Time `gorm:"index"`
+	LastUsedAt        time.Time
+	CreatedAt         time.Time
+
+	// ImpersonatorID terisi jika session dibuka platform admin atas nama user ini
+	ImpersonatorID *uuid.UUID `gorm:"type:uuid;index"`
+}
+
+// Active true jika session belum dicabut dan belum kedaluwarsa
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
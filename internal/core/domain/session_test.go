@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionActive(t *testing.T) {
+	now := time.Now()
+	revoked := now.Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		session Session
+		want    bool
+	}{
+		{name: "active", session: Session{ExpiresAt: now.Add(time.Hour)}, want: true},
+		{name: "expired", session: Session{ExpiresAt: now.Add(-time.Second)}},
+		{name: "expires exactly now", session: Session{ExpiresAt: now}},
+		{name: "revoked", session: Session{ExpiresAt: now.Add(time.Hour), RevokedAt: &revoked}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.session.Active(now); got != tt.want {
+				t.Fatalf("Active = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -11,10 +11,58 @@ type User struct {
 	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	Username     string    `gorm:"type:varchar(50);uniqueIndex;not null"`
 	Email        string    `gorm:"type:varchar(100);uniqueIndex;not null"`
-	PasswordHash string    `gorm:"type:text;not null"`
+	PasswordHash string    `gorm:"type:text;not null" json:"-"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
-	
+
+	// EmailVerified true setelah user membuka link verifikasi (atau email berasal dari SSO yang sudah terverifikasi)
+	EmailVerified   bool `gorm:"not null;default:false"`
+	EmailVerifiedAt *time.Time
+
+	// Platform admin dan status tenant (dikelola lewat /api/v1/admin)
+	IsPlatformAdmin  bool       `gorm:"not null;default:false"`
+	SuspendedAt      *time.Time `gorm:"index"`
+	SuspensionReason string     `gorm:"type:varchar(255)"`
+	MaxProjects      int        `gorm:"not null;default:10"`                      // kuota project yang boleh dibuat user, 0 = tanpa batas
+	Plan             string     `gorm:"type:varchar(30);not null;default:'free'"` // menentukan policy image & hardening yang berlaku
+
+	// Two-factor (TOTP). TOTPSecret terisi saat enrollment, TOTPEnabled setelah kode pertama diverifikasi.
+	TOTPSecret       string `gorm:"type:varchar(64)" json:"-"`
+	TOTPEnabled      bool   `gorm:"not null;default:false"`
+	TOTPLastUsedStep int64  `json:"-"` // mencegah kode yang sama dipakai dua kali
+
 	// Relations
 	Projects []Project `gorm:"foreignKey:UserID"`
 }
+
+// Suspended true jika tenant sedang di-suspend oleh platform admin
+func (u *User) Suspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// RecoveryCode kode cadangan 2FA sekali pakai (disimpan hash)
+type RecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	CodeHash  string    `gorm:"type:varchar(64);not null;index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// Tujuan EmailToken
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// EmailToken token sekali pakai yang dikirim lewat email (verifikasi email, reset password).
+// Nilai token tidak disimpan; yang dikirim ke user adalah ID + tanda tangan HMAC.
+type EmailToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Purpose   string    `gorm:"type:varchar(32);not null"`
+	Email     string    `gorm:"type:varchar(100);not null"` // email saat token dibuat, token hangus jika email berubah
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -2,23 +2,53 @@ package ports
 
 import (
 	"context"
+	"errors"
+	"io"
+	"time"
 
 	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
 	"github.com/google/uuid"
 )
 
+// ErrImageNotPresent image tidak ada di host dan pull policy melarang pull
+var ErrImageNotPresent = errors.New("image is not present locally and pull policy is never")
+
+// ErrRegistryAccessDenied registry menolak kredensial tenant (atau akses anonim) untuk image yang sudah ada di cache host
+var ErrRegistryAccessDenied = errors.New("registry denied access to the image")
+
 // ProjectRepository mendefinisikan operasi database untuk Project
 type ProjectRepository interface {
 	Create(ctx context.Context, project *domain.Project) error
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Project, error)
 	ListByUserID(ctx context.Context, userID uuid.UUID) ([]domain.Project, error)
+	ListByOrganizationIDs(ctx context.Context, orgIDs []uuid.UUID) ([]domain.Project, error)
 	Update(ctx context.Context, project *domain.Project) error
 	Delete(ctx context.Context, id uuid.UUID) error
+
+	// CreateDeployment menyimpan riwayat deployment project
+	CreateDeployment(ctx context.Context, deployment *domain.Deployment) error
+	UpdateDeploymentStatus(ctx context.Context, deploymentID uuid.UUID, status string) error
+
+	// ListByStatus mengambil semua project dengan status tertentu (lintas user)
+	ListByStatus(ctx context.Context, status string) ([]domain.Project, error)
+
+	// CountByStatus menghitung jumlah project per status
+	CountByStatus(ctx context.Context) (map[string]int64, error)
+
+	// ReplaceEnvVars mengganti seluruh env var project
+	ReplaceEnvVars(ctx context.Context, projectID uuid.UUID, envVars []domain.EnvVar) error
+
+	// CreateBuild / UpdateBuild menyimpan riwayat build image project
+	CreateBuild(ctx context.Context, build *domain.Build) error
+	UpdateBuild(ctx context.Context, build *domain.Build) error
 }
 
 // ContainerRuntime mendefinisikan interaksi dengan Docker Engine
 // Ini adalah "Port" yang akan diimplementasikan oleh adapter Docker
 type ContainerRuntime interface {
+	// PrepareImage memastikan image tersedia sesuai pull policy lalu me-resolve tag ke digest
+	PrepareImage(ctx context.Context, image, pullPolicy string, auth *RegistryAuth) (*ImageRef, error)
+
 	// CreateContainer membuat container baru tanpa menjalankannya
 	// Mengembalikan containerID jika sukses
 	CreateContainer(ctx context.Context, config ContainerConfig) (string, error)
@@ -31,18 +61,160 @@ type ContainerRuntime interface {
 
 	// RemoveContainer menghapus container
 	RemoveContainer(ctx context.Context, containerID string) error
-	
+
 	// InspectContainer mendapatkan status terkini
 	InspectContainer(ctx context.Context, containerID string) (*ContainerStatus, error)
+
+	// Stats mengambil snapshot pemakaian resource container (CPU, memory, network, block IO)
+	Stats(ctx context.Context, containerID string) (*ContainerStats, error)
+
+	// RemoveNetworkIfUnused menghapus network tenant jika tidak ada lagi container yang memakainya
+	RemoveNetworkIfUnused(ctx context.Context, name string) error
+}
+
+// ImageBuilder membangun image dari build context di filesystem lokal
+type ImageBuilder interface {
+	// BuildImage menulis output build (baris teks) ke logs selama proses berjalan
+	BuildImage(ctx context.Context, config BuildConfig, logs io.Writer) error
+}
+
+// GitClient mengambil source code dari repository Git
+type GitClient interface {
+	// Clone checkout branch (kosong = default branch) ke dir dan mengembalikan commit SHA-nya
+	Clone(ctx context.Context, url, branch, dir string) (commit string, err error)
+}
+
+// MetricsRecorder mencatat metrik operasional control plane (diimplementasi adapter Prometheus)
+type MetricsRecorder interface {
+	// ObserveDeploy mencatat durasi dan hasil deploy ("success" / "failure")
+	ObserveDeploy(outcome string, duration time.Duration)
+
+	// ObserveImagePull mencatat lama pull image
+	ObserveImagePull(duration time.Duration)
+
+	// IncDockerError menghitung error dari Docker API per operasi
+	IncDockerError(operation string)
+}
+
+// TenantRepository status dan kuota tenant. Tenant = organization pemilik project; suspend, plan dan
+// kuota mengikuti owner organization (owner paling awal jika ada lebih dari satu).
+type TenantRepository interface {
+	IsSuspended(ctx context.Context, orgID uuid.UUID) (bool, error)
+	// MaxProjects 0 berarti tanpa batas
+	MaxProjects(ctx context.Context, orgID uuid.UUID) (int, error)
+	// Plan nama plan tenant (mis. "free")
+	Plan(ctx context.Context, orgID uuid.UUID) (string, error)
+	// CountProjects jumlah project di semua organization milik owner tenant (untuk kuota)
+	CountProjects(ctx context.Context, orgID uuid.UUID) (int64, error)
+	// OwnedOrganizationIDs organization yang owner tenant-nya userID
+	OwnedOrganizationIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// AuditRepository penyimpanan audit log. Sengaja tidak ada Update/Delete.
+type AuditRepository interface {
+	Append(ctx context.Context, event *domain.AuditEvent) error
+	List(ctx context.Context, filter AuditFilter) ([]domain.AuditEvent, int64, error)
+}
+
+// AuditFilter field kosong tidak difilter. Action diakhiri "." dicocokkan sebagai prefix (mis. "project.").
+type AuditFilter struct {
+	OrganizationID *uuid.UUID
+	ActorID        *uuid.UUID
+	Action         string
+	TargetType     string
+	TargetID       string
+	Outcome        string
+	Since          time.Time
+	Until          time.Time
+	Limit          int
+	Offset         int
+}
+
+// Mailer mengirim email transaksional (verifikasi email, reset password, dsb)
+type Mailer interface {
+	Send(ctx context.Context, msg MailMessage) error
+}
+
+// RateLimitStore menyimpan counter fixed-window dan status lockout.
+// In-memory untuk single instance, Postgres supaya limit berlaku sama di semua replica.
+type RateLimitStore interface {
+	// Increment menambah counter key; window baru dimulai jika window sebelumnya sudah lewat
+	Increment(ctx context.Context, key string, window time.Duration) (count int, resetAt time.Time, err error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	// LockedUntil mengembalikan zero time jika key tidak sedang terkunci
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	Reset(ctx context.Context, keys ...string) error
+}
+
+// MailMessage email plain text sederhana
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
 }
 
 // ContainerConfig structDTO untuk parameter pembuatan container
 type ContainerConfig struct {
-	Name      string
-	Image     string
-	Env       []string
-	Labels    map[string]string
-	Port      int
+	Name     string
+	Image    string
+	Env      []string
+	Labels   map[string]string
+	Port     int
+	Security SecurityProfile
+
+	// Network tenant tempat container dijalankan, dibuat otomatis jika belum ada
+	Network        string
+	NetworkAliases []string // nama DNS container di dalam network tenant
+}
+
+// SecurityProfile hardening yang diterapkan ke container tenant
+type SecurityProfile struct {
+	NoNewPrivileges bool
+	CapDrop         []string
+	CapAdd          []string
+	ReadOnlyRootfs  bool
+	Tmpfs           map[string]string // mount point -> opsi mount
+	User            string            // "uid:gid"; kosong = USER dari image
+	PidsLimit       int64             // 0 = tanpa batas
+	SeccompProfile  string            // isi JSON profile seccomp; kosong = profile default Docker
+}
+
+// ImageRef hasil PrepareImage
+type ImageRef struct {
+	ID     string            // ID image lokal (sha256:...)
+	Digest string            // repo@sha256:... dari registry, kosong untuk image hasil build lokal
+	Size   int64             // ukuran image (byte)
+	Labels map[string]string // label image, mis. mth.project_id untuk image hasil build
+}
+
+// Pinned referensi yang tidak berubah walau tag di registry dipindah
+func (r *ImageRef) Pinned() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.ID
+}
+
+// RegistryAuth kredensial login registry untuk pull image
+type RegistryAuth struct {
+	ServerAddress string // host registry, "docker.io" untuk Docker Hub
+	Username      string
+	Password      string
+}
+
+// RegistryAuthProvider memilih kredensial registry milik organization untuk sebuah image
+type RegistryAuthProvider interface {
+	// AuthFor mengembalikan nil jika organization tidak punya kredensial untuk registry image tersebut
+	AuthFor(ctx context.Context, orgID uuid.UUID, image string) (*RegistryAuth, error)
+}
+
+// BuildConfig parameter build image
+type BuildConfig struct {
+	ContextDir string
+	Dockerfile string // relatif terhadap ContextDir
+	Tags       []string
+	Labels     map[string]string
+	BuildArgs  map[string]*string
 }
 
 type ContainerStatus struct {
@@ -50,3 +222,17 @@ type ContainerStatus struct {
 	State  string
 	Status string
 }
+
+// ContainerStats snapshot pemakaian resource sebuah container
+type ContainerStats struct {
+	Timestamp     time.Time `json:"timestamp"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	MemoryUsage   uint64    `json:"memory_usage_bytes"`
+	MemoryLimit   uint64    `json:"memory_limit_bytes"`
+	MemoryPercent float64   `json:"memory_percent"`
+	NetworkRx     uint64    `json:"network_rx_bytes"`
+	NetworkTx     uint64    `json:"network_tx_bytes"`
+	BlockRead     uint64    `json:"block_read_bytes"`
+	BlockWrite    uint64    `json:"block_write_bytes"`
+	PIDs          uint64    `json:"pids"`
+}
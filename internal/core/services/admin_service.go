@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotPlatformAdmin  = errors.New("platform admin access required")
+	ErrCannotTargetAdmin = errors.New("this action cannot be performed on a platform admin")
+)
+
+// ListFilter pagination sederhana untuk endpoint admin
+type ListFilter struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// AdminService operasi lintas tenant untuk platform admin
+type AdminService struct {
+	db         *gorm.DB
+	authSvc    *AuthService
+	projectSvc *ProjectService
+	audit      *AuditService
+}
+
+func NewAdminService(db *gorm.DB, authSvc *AuthService, projectSvc *ProjectService, audit *AuditService) *AdminService {
+	return &AdminService{db: db, authSvc: authSvc, projectSvc: projectSvc, audit: audit}
+}
+
+// IsAdmin true jika user adalah platform admin
+func (s *AdminService) IsAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&domain.User{}).Where("id = ? AND is_platform_admin", userID).Count(&count).Error
+	return count > 0, err
+}
+
+// PromoteAdmins menjadikan user sebagai platform admin, dipakai untuk bootstrap dari config.
+// Identifier berupa user ID atau email yang sudah diverifikasi; username tidak dipakai karena
+// siapa pun bisa mendaftar lebih dulu dengan username (atau email belum terverifikasi) yang ada di config.
+func (s *AdminService) PromoteAdmins(ctx context.Context, identifiers []string) error {
+	for _, ident := range identifiers {
+		ident = strings.ToLower(strings.TrimSpace(ident))
+		q := s.db.WithContext(ctx).Model(&domain.User{})
+		if id, err := uuid.Parse(ident); err == nil {
+			q = q.Where("id = ?", id)
+		} else {
+			q = q.Where("LOWER(email) = ? AND email_verified = ?", ident, true)
+		}
+		res := q.Update("is_platform_admin", true)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			slog.WarnContext(ctx, "platform admin not found (expected a user ID or a verified email)", slog.String("user", ident))
+		}
+	}
+	return nil
+}
+
+func (s *AdminService) ListUsers(ctx context.Context, filter ListFilter) ([]domain.User, int64, error) {
+	q := s.db.WithContext(ctx).Model(&domain.User{})
+	if filter.Query != "" {
+		like := "%" + strings.ToLower(filter.Query) + "%"
+		q = q.Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ?", like, like)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []domain.User
+	if err := q.Order("created_at ASC").Limit(filter.Limit).Offset(filter.Offset).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (s *AdminService) ListProjects(ctx context.Context, filter ListFilter) ([]domain.Project, int64, error) {
+	q := s.db.WithContext(ctx).Model(&domain.Project{})
+	if filter.Query != "" {
+		like := "%" + strings.ToLower(filter.Query) + "%"
+		q = q.Where("LOWER(name) LIKE ? OR LOWER(subdomain) LIKE ?", like, like)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var projects []domain.Project
+	if err := q.Order("created_at ASC").Limit(filter.Limit).Offset(filter.Offset).Find(&projects).Error; err != nil {
+		return nil, 0, err
+	}
+	return projects, total, nil
+}
+
+// SuspendUser memblokir login user, mencabut semua session, lalu menghentikan semua container miliknya
+func (s *AdminService) SuspendUser(ctx context.Context, userID uuid.UUID, reason string) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditAdminSuspendUser, TargetType: "user", TargetID: userID.String(), Metadata: map[string]any{"reason": reason}, Err: err})
+	}()
+
+	now := time.Now()
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		if user.IsPlatformAdmin {
+			return ErrCannotTargetAdmin
+		}
+
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"suspended_at":      now,
+			"suspension_reason": reason,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Container dihentikan setelah commit: suspend tetap berlaku walau sebagian container gagal di-stop
+	return s.projectSvc.StopUserProjects(ctx, userID)
+}
+
+// UnsuspendUser mengizinkan user login lagi. Container tidak dijalankan ulang otomatis.
+func (s *AdminService) UnsuspendUser(ctx context.Context, userID uuid.UUID) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditAdminUnsuspendUser, TargetType: "user", TargetID: userID.String(), Err: err})
+	}()
+
+	res := s.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"suspended_at":      nil,
+		"suspension_reason": "",
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetQuota mengubah kuota project user (0 = tanpa batas)
+func (s *AdminService) SetQuota(ctx context.Context, userID uuid.UUID, maxProjects int) (err error) {
+	var user domain.User
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{
+			Action:     AuditAdminSetQuota,
+			TargetType: "user",
+			TargetID:   userID.String(),
+			Before:     map[string]any{"max_projects": user.MaxProjects},
+			After:      map[string]any{"max_projects": maxProjects},
+			Err:        err,
+		})
+	}()
+
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", userID).Update("max_projects", maxProjects).Error
+}
+
+// SetPlan mengubah plan tenant; policy image plan berlaku mulai deploy berikutnya
+func (s *AdminService) SetPlan(ctx context.Context, userID uuid.UUID, plan string) (err error) {
+	var user domain.User
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{
+			Action:     AuditAdminSetPlan,
+			TargetType: "user",
+			TargetID:   userID.String(),
+			Before:     map[string]any{"plan": user.Plan},
+			After:      map[string]any{"plan": plan},
+			Err:        err,
+		})
+	}()
+
+	if !planPattern.MatchString(plan) {
+		return ErrInvalidPlan
+	}
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", userID).Update("plan", plan).Error
+}
+
+// Impersonate membuka session atas nama user; setiap impersonation tercatat di audit trail
+func (s *AdminService) Impersonate(ctx context.Context, adminID, userID uuid.UUID, client ClientInfo) (_ *TokenPair, err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditAdminImpersonate, TargetType: "user", TargetID: userID.String(), Err: err})
+	}()
+
+	var user domain.User
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	if user.IsPlatformAdmin {
+		return nil, ErrCannotTargetAdmin
+	}
+	return s.authSvc.Impersonate(ctx, adminID, userID, client)
+}
+
+// ForceStopProject menghentikan semua container project tanpa peduli role di organization
+func (s *AdminService) ForceStopProject(ctx context.Context, projectID uuid.UUID) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditAdminStopProject, TargetType: "project", TargetID: projectID.String(), Err: err})
+	}()
+
+	return s.projectSvc.StopAllDeployments(ctx, projectID)
+}
+
+func (s *AdminService) RedeployProject(ctx context.Context, projectID uuid.UUID) (_ *domain.Deployment, err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditAdminRedeploy, TargetType: "project", TargetID: projectID.String(), Err: err})
+	}()
+
+	return s.projectSvc.RedeployProject(ctx, projectID)
+}
+
+// ListAudit seluruh audit log lintas organization
+func (s *AdminService) ListAudit(ctx context.Context, filter ports.AuditFilter) ([]domain.AuditEvent, int64, error) {
+	return s.audit.List(ctx, filter)
+}
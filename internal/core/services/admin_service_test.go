@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/mailer"
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+)
+
+func newTestAdminService(t *testing.T) (*AdminService, *AuthService, *memoryAuditRepo) {
+	t.Helper()
+	db := testDB(t)
+	auditRepo := &memoryAuditRepo{}
+	audit := NewAuditService(auditRepo)
+	authSvc := newTestAuthService(t, db, mailer.NewMemoryMailer())
+	projectSvc := NewProjectService(&tenantProjectRepo{}, nil, nil, &fakeTenantRepo{}, nil, nil, nil, audit, ProjectConfig{})
+	return NewAdminService(db, authSvc, projectSvc, audit), authSvc, auditRepo
+}
+
+// findAudit event pertama dengan action tersebut
+func findAudit(repo *memoryAuditRepo, action string) (domain.AuditEvent, bool) {
+	events, _, _ := repo.List(context.Background(), ports.AuditFilter{})
+	for _, e := range events {
+		if e.Action == action {
+			return e, true
+		}
+	}
+	return domain.AuditEvent{}, false
+}
+
+func TestPromoteAdmins(t *testing.T) {
+	admin, _, _ := newTestAdminService(t)
+	db := admin.db
+	byID := createTestUser(t, db, "byid", "byid@example.com", false)
+	verified := createTestUser(t, db, "verified", "verified@example.com", true)
+	unverified := createTestUser(t, db, "unverified", "unverified@example.com", false)
+	byName := createTestUser(t, db, "byname", "byname@example.com", true)
+
+	// Username dan email yang belum diverifikasi bisa diklaim siapa saja, jadi tidak dipromosikan
+	idents := []string{byID.ID.String(), " Verified@Example.com ", "unverified@example.com", "byname", "nobody@example.com"}
+	if err := admin.PromoteAdmins(context.Background(), idents); err != nil {
+		t.Fatalf("PromoteAdmins: %v", err)
+	}
+	for _, tt := range []struct {
+		user *domain.User
+		want bool
+	}{{byID, true}, {verified, true}, {unverified, false}, {byName, false}} {
+		got, err := admin.IsAdmin(context.Background(), tt.user.ID)
+		if err != nil || got != tt.want {
+			t.Errorf("IsAdmin(%s) = %v, %v; want %v", tt.user.Username, got, err, tt.want)
+		}
+	}
+}
+
+func TestSuspendUser(t *testing.T) {
+	admin, authSvc, auditRepo := newTestAdminService(t)
+	db := admin.db
+	root := createTestUser(t, db, "root", "root@example.com", true)
+	alice := createTestUser(t, db, "alice", "alice@example.com", true)
+	ctx := WithActor(context.Background(), Actor{UserID: root.ID, IPAddress: "10.0.0.1"})
+	if err := admin.PromoteAdmins(ctx, []string{" Root@Example.com "}); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := authSvc.createSession(ctx, alice.ID, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.SuspendUser(ctx, alice.ID, "abuse"); err != nil {
+		t.Fatalf("suspend: %v", err)
+	}
+	if _, err := authSvc.ValidateToken(ctx, session.AccessToken); err == nil {
+		t.Fatal("session of suspended user still valid")
+	}
+	if _, err := authSvc.Login(ctx, "alice", testPassword, ClientInfo{}); !errors.Is(err, ErrAccountSuspended) {
+		t.Fatalf("login while suspended: err = %v", err)
+	}
+	if _, err := admin.Impersonate(ctx, root.ID, root.ID, ClientInfo{}); !errors.Is(err, ErrCannotTargetAdmin) {
+		t.Fatalf("impersonate admin: err = %v", err)
+	}
+	if err := admin.SuspendUser(ctx, root.ID, "oops"); !errors.Is(err, ErrCannotTargetAdmin) {
+		t.Fatalf("suspend admin: err = %v", err)
+	}
+
+	event, ok := findAudit(auditRepo, AuditAdminSuspendUser)
+	if !ok {
+		t.Fatal("no suspend audit event")
+	}
+	if event.ActorID != root.ID || event.TargetID != alice.ID.String() || event.IPAddress != "10.0.0.1" || event.Outcome != domain.AuditOutcomeSuccess {
+		t.Fatalf("audit event = %+v", event)
+	}
+
+	if err := admin.UnsuspendUser(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authSvc.Login(ctx, "alice", testPassword, ClientInfo{}); err != nil {
+		t.Fatalf("login after unsuspend: %v", err)
+	}
+}
+
+func TestImpersonateSessionCarriesAdmin(t *testing.T) {
+	admin, authSvc, auditRepo := newTestAdminService(t)
+	db := admin.db
+	root := createTestUser(t, db, "root", "root@example.com", true)
+	alice := createTestUser(t, db, "alice", "alice@example.com", true)
+	ctx := WithActor(context.Background(), Actor{UserID: root.ID})
+
+	pair, err := admin.Impersonate(ctx, root.ID, alice.ID, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := authSvc.ValidateToken(ctx, pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != alice.ID || claims.ImpersonatorID == nil || *claims.ImpersonatorID != root.ID {
+		t.Fatalf("claims = %+v, want alice impersonated by root", claims)
+	}
+
+	// Refresh mempertahankan impersonator
+	refreshed, err := authSvc.Refresh(ctx, pair.RefreshToken, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := authSvc.ValidateToken(ctx, refreshed.AccessToken); err != nil || claims.ImpersonatorID == nil {
+		t.Fatalf("refreshed claims = %+v, %v; want impersonator kept", claims, err)
+	}
+
+	if event, ok := findAudit(auditRepo, AuditAdminImpersonate); !ok || event.ActorID != root.ID || event.TargetID != alice.ID.String() {
+		t.Fatalf("impersonation audit event = %+v (found %v)", event, ok)
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrInvalidArchive       = errors.New("build context must be a valid tar.gz archive")
+	ErrBuildContextTooLarge = errors.New("build context exceeds the size limit")
+)
+
+// archiveStats ringkasan hasil ekstrak build context
+type archiveStats struct {
+	Files   int
+	Bytes   int64
+	Skipped []string
+}
+
+// extractTarGz mengekstrak tar.gz ke dir. Hanya file biasa dan folder yang ditulis;
+// symlink/hardlink dilewati supaya entry berikutnya tidak bisa menulis ke luar dir.
+func extractTarGz(r io.Reader, dir string, limits BuildLimits) (*archiveStats, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, archiveError(err)
+	}
+	defer gz.Close()
+
+	stats := &archiveStats{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, archiveError(err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if name == "." {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, fmt.Errorf("%w: entry %q is outside the archive root", ErrInvalidArchive, hdr.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			stats.Files++
+			if limits.MaxFiles > 0 && stats.Files > limits.MaxFiles {
+				return nil, fmt.Errorf("%w: more than %d files", ErrBuildContextTooLarge, limits.MaxFiles)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return nil, err
+			}
+			n, err := writeArchiveFile(target, tr, hdr.FileInfo().Mode().Perm(), limits.MaxContextBytes-stats.Bytes)
+			stats.Bytes += n
+			if err != nil {
+				return nil, err
+			}
+		default:
+			stats.Skipped = append(stats.Skipped, name)
+		}
+	}
+	return stats, nil
+}
+
+// writeArchiveFile menyalin isi entry ke file baru, gagal jika melebihi sisa kuota ukuran
+func writeArchiveFile(target string, r io.Reader, perm os.FileMode, remaining int64) (int64, error) {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, io.LimitReader(r, remaining+1))
+	if err != nil {
+		return n, archiveError(err)
+	}
+	if n > remaining {
+		return n, ErrBuildContextTooLarge
+	}
+	return n, nil
+}
+
+// archiveError error asli tetap di-wrap supaya handler bisa mengenali body yang terpotong batas upload
+func archiveError(err error) error {
+	return fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+}
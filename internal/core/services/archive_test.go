@@ -0,0 +1,159 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+// tarGz membuat archive tar.gz di memory dari entries
+func tarGz(t *testing.T, entries ...tarEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0o644}
+		if e.typeflag == 0 {
+			hdr.Typeflag = tar.TypeReg
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(e.body))
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			hdr.Mode = 0o755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, e.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractTarGz(t *testing.T) {
+	limits := BuildLimits{MaxContextBytes: 10, MaxFiles: 3}
+
+	tests := []struct {
+		name        string
+		archive     []tarEntry
+		raw         string // dipakai jika archive nil
+		wantErr     error
+		wantFiles   map[string]string
+		wantSkipped []string
+	}{
+		{
+			name: "regular files and directories",
+			archive: []tarEntry{
+				{name: "./", typeflag: tar.TypeDir},
+				{name: "./Dockerfile", body: "FROM x"},
+				{name: "src/", typeflag: tar.TypeDir},
+				{name: "src/a.go", body: "abc"},
+			},
+			wantFiles: map[string]string{"Dockerfile": "FROM x", "src/a.go": "abc"},
+		},
+		{name: "parent traversal", archive: []tarEntry{{name: "../evil", body: "x"}}, wantErr: ErrInvalidArchive},
+		{name: "nested parent traversal", archive: []tarEntry{{name: "src/../../evil", body: "x"}}, wantErr: ErrInvalidArchive},
+		{name: "absolute path", archive: []tarEntry{{name: "/etc/cron.d/evil", body: "x"}}, wantErr: ErrInvalidArchive},
+		{
+			name: "symlink is skipped and not followed",
+			archive: []tarEntry{
+				{name: "escape", typeflag: tar.TypeSymlink, linkname: "/tmp"},
+				{name: "escape/evil", body: "x"},
+			},
+			wantFiles:   map[string]string{"escape/evil": "x"},
+			wantSkipped: []string{"escape"},
+		},
+		{
+			name: "hardlink is skipped",
+			archive: []tarEntry{
+				{name: "Dockerfile", body: "FROM x"},
+				{name: "passwd", typeflag: tar.TypeLink, linkname: "/etc/passwd"},
+			},
+			wantFiles:   map[string]string{"Dockerfile": "FROM x"},
+			wantSkipped: []string{"passwd"},
+		},
+		{name: "single file over size limit", archive: []tarEntry{{name: "big", body: strings.Repeat("x", 11)}}, wantErr: ErrBuildContextTooLarge},
+		{
+			name:    "total size over limit",
+			archive: []tarEntry{{name: "a", body: "123456"}, {name: "b", body: "123456"}},
+			wantErr: ErrBuildContextTooLarge,
+		},
+		{
+			name:    "too many files",
+			archive: []tarEntry{{name: "a"}, {name: "b"}, {name: "c"}, {name: "d"}},
+			wantErr: ErrBuildContextTooLarge,
+		},
+		{name: "not gzip", raw: "plain text", wantErr: ErrInvalidArchive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "ctx")
+			if err := os.Mkdir(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			var r io.Reader = strings.NewReader(tt.raw)
+			if tt.archive != nil {
+				r = tarGz(t, tt.archive...)
+			}
+
+			stats, err := extractTarGz(r, dir, limits)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			// Tidak ada yang boleh tertulis di luar dir
+			outside, _ := os.ReadDir(root)
+			if len(outside) != 1 {
+				t.Fatalf("entries written outside the context dir: %v", outside)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			for name, want := range tt.wantFiles {
+				info, err := os.Lstat(filepath.Join(dir, name))
+				if err != nil || !info.Mode().IsRegular() {
+					t.Fatalf("%s is not a regular file: %v", name, err)
+				}
+				got, _ := os.ReadFile(filepath.Join(dir, name))
+				if string(got) != want {
+					t.Fatalf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if stats.Files != len(tt.wantFiles) {
+				t.Fatalf("stats.Files = %d, want %d", stats.Files, len(tt.wantFiles))
+			}
+			if !reflect.DeepEqual(stats.Skipped, tt.wantSkipped) {
+				t.Fatalf("skipped = %v, want %v", stats.Skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/google/uuid"
+)
+
+// Nama aksi audit
+const (
+	AuditLogin           = "auth.login"
+	AuditLoginMFA        = "auth.login.mfa"
+	AuditRegister        = "auth.register"
+	AuditLogout          = "auth.logout"
+	AuditLogoutAll       = "auth.logout_all"
+	AuditPasswordChange  = "auth.password.change"
+	AuditPasswordReset   = "auth.password.reset"
+	AuditProfileUpdate   = "auth.profile.update"
+	AuditTwoFactorEnable = "auth.2fa.enable"
+	AuditTwoFactorOff    = "auth.2fa.disable"
+
+	AuditTokenCreate = "token.create"
+	AuditTokenRevoke = "token.revoke"
+
+	AuditProjectCreate   = "project.create"
+	AuditProjectUpdate   = "project.update"
+	AuditProjectDelete   = "project.delete"
+	AuditProjectDeploy   = "project.deploy"
+	AuditProjectRedeploy = "project.redeploy"
+	AuditProjectStart    = "project.start"
+	AuditProjectStop     = "project.stop"
+	AuditProjectStopAll  = "project.stop_all"
+	AuditProjectEnv      = "project.env.update"
+	AuditProjectBuild    = "project.build"
+	AuditProjectSecurity = "project.security.update"
+
+	AuditOrgCreate           = "org.create"
+	AuditOrgUpdate           = "org.update"
+	AuditOrgMemberRole       = "org.member.role"
+	AuditOrgMemberRemove     = "org.member.remove"
+	AuditOrgInvite           = "org.invitation.create"
+	AuditOrgInviteRevoke     = "org.invitation.revoke"
+	AuditOrgInvitationAccept = "org.invitation.accept"
+
+	AuditRegistryCredentialCreate = "org.registry_credential.create"
+	AuditRegistryCredentialDelete = "org.registry_credential.delete"
+
+	AuditAdminSuspendUser          = "admin.user.suspend"
+	AuditAdminUnsuspendUser        = "admin.user.unsuspend"
+	AuditAdminSetQuota             = "admin.user.quota"
+	AuditAdminImpersonate          = "admin.user.impersonate"
+	AuditAdminStopProject          = "admin.project.stop"
+	AuditAdminRedeploy             = "admin.project.redeploy"
+	AuditAdminSetPlan              = "admin.user.plan"
+	AuditAdminImagePolicy          = "admin.image_policy.update"
+	AuditAdminImagePolicyDelete    = "admin.image_policy.delete"
+	AuditAdminSecurityPolicy       = "admin.security_policy.update"
+	AuditAdminSecurityPolicyDelete = "admin.security_policy.delete"
+)
+
+// Actor siapa yang melakukan request; diisi middleware HTTP ke context lalu dibaca AuditService
+type Actor struct {
+	UserID         uuid.UUID
+	ImpersonatorID *uuid.UUID
+	IPAddress      string
+	UserAgent      string
+}
+
+type actorKey struct{}
+
+// WithActor menyimpan actor di context
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext actor request saat ini (zero value jika tidak ada)
+func ActorFromContext(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// AuditEntry input untuk AuditService.Record
+type AuditEntry struct {
+	Action         string
+	TargetType     string
+	TargetID       string
+	OrganizationID *uuid.UUID
+	Before         any
+	After          any
+	Metadata       map[string]any
+	Err            error // nil = success
+
+	// UserID override actor dari context, untuk aksi sebelum user terautentikasi (login, register, reset)
+	UserID *uuid.UUID
+}
+
+// AuditService menulis audit log append-only. Dipanggil dari service (bukan handler) supaya tidak ada aksi yang terlewat.
+type AuditService struct {
+	repo ports.AuditRepository
+}
+
+func NewAuditService(repo ports.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record tidak pernah menggagalkan aksi yang diaudit; kegagalan menulis audit hanya di-log
+func (s *AuditService) Record(ctx context.Context, entry AuditEntry) {
+	actor := ActorFromContext(ctx)
+	if entry.UserID != nil {
+		actor.UserID = *entry.UserID
+	}
+
+	event := domain.AuditEvent{
+		OrganizationID: entry.OrganizationID,
+		ActorID:        actor.UserID,
+		ImpersonatorID: actor.ImpersonatorID,
+		Action:         entry.Action,
+		TargetType:     entry.TargetType,
+		TargetID:       entry.TargetID,
+		Before:         marshalAudit(entry.Before),
+		After:          marshalAudit(entry.After),
+		Outcome:        domain.AuditOutcomeSuccess,
+		IPAddress:      actor.IPAddress,
+		UserAgent:      truncate(actor.UserAgent, 255),
+	}
+	if len(entry.Metadata) > 0 {
+		event.Metadata = marshalAudit(entry.Metadata)
+	}
+	if entry.Err != nil {
+		event.Outcome = domain.AuditOutcomeFailure
+		event.Error = entry.Err.Error()
+	}
+
+	if err := s.repo.Append(context.WithoutCancel(ctx), &event); err != nil {
+		slog.ErrorContext(ctx, "failed to write audit event", slog.String("action", event.Action), slog.Any("error", err))
+	}
+}
+
+// List audit event sesuai filter (terbaru dulu)
+func (s *AuditService) List(ctx context.Context, filter ports.AuditFilter) ([]domain.AuditEvent, int64, error) {
+	return s.repo.List(ctx, filter)
+}
+
+func marshalAudit(v any) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/google/uuid"
+)
+
+// failingAuditRepo selalu gagal menulis
+type failingAuditRepo struct{ ports.AuditRepository }
+
+func (failingAuditRepo) Append(ctx context.Context, event *domain.AuditEvent) error {
+	return errors.New("database is down")
+}
+
+func TestAuditServiceRecord(t *testing.T) {
+	actorID, adminID, loginID, orgID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	ctx := WithActor(context.Background(), Actor{
+		UserID:         actorID,
+		ImpersonatorID: &adminID,
+		IPAddress:      "10.0.0.1",
+		UserAgent:      strings.Repeat("a", 300),
+	})
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		entry       AuditEntry
+		wantActor   uuid.UUID
+		wantOutcome string
+		check       func(t *testing.T, e domain.AuditEvent)
+	}{
+		{
+			name:        "actor from context",
+			ctx:         ctx,
+			entry:       AuditEntry{Action: AuditProjectCreate, TargetType: "project", TargetID: "p1", OrganizationID: &orgID, After: map[string]string{"name": "web"}},
+			wantActor:   actorID,
+			wantOutcome: domain.AuditOutcomeSuccess,
+			check: func(t *testing.T, e domain.AuditEvent) {
+				if e.ImpersonatorID == nil || *e.ImpersonatorID != adminID || e.IPAddress != "10.0.0.1" || len(e.UserAgent) != 255 {
+					t.Fatalf("request metadata not recorded: %+v", e)
+				}
+				if e.OrganizationID == nil || *e.OrganizationID != orgID || e.After != `{"name":"web"}` || e.Before != "" {
+					t.Fatalf("org/snapshot = %v %q %q", e.OrganizationID, e.Before, e.After)
+				}
+			},
+		},
+		{
+			name:        "explicit user overrides context",
+			ctx:         ctx,
+			entry:       AuditEntry{Action: AuditLogin, TargetType: "user", UserID: &loginID},
+			wantActor:   loginID,
+			wantOutcome: domain.AuditOutcomeSuccess,
+		},
+		{
+			name:        "failure without actor",
+			ctx:         context.Background(),
+			entry:       AuditEntry{Action: AuditLogin, TargetType: "user", Metadata: map[string]any{"username": "alice"}, Err: ErrInvalidCredentials},
+			wantActor:   uuid.Nil,
+			wantOutcome: domain.AuditOutcomeFailure,
+			check: func(t *testing.T, e domain.AuditEvent) {
+				if e.Error != ErrInvalidCredentials.Error() || e.Metadata != `{"username":"alice"}` {
+					t.Fatalf("error/metadata = %q %q", e.Error, e.Metadata)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryAuditRepo{}
+			NewAuditService(repo).Record(tt.ctx, tt.entry)
+
+			if len(repo.events) != 1 {
+				t.Fatalf("recorded %d events, want 1", len(repo.events))
+			}
+			e := repo.events[0]
+			if e.Action != tt.entry.Action || e.ActorID != tt.wantActor || e.Outcome != tt.wantOutcome {
+				t.Fatalf("event = %s by %s (%s), want %s by %s (%s)", e.Action, e.ActorID, e.Outcome, tt.entry.Action, tt.wantActor, tt.wantOutcome)
+			}
+			if tt.check != nil {
+				tt.check(t, e)
+			}
+		})
+	}
+}
+
+func TestAuditServiceRecordDoesNotFailOnStoreError(t *testing.T) {
+	// Record tidak mengembalikan error dan tidak panic walau penyimpanan gagal
+	NewAuditService(failingAuditRepo{}).Record(context.Background(), AuditEntry{Action: AuditLogin, TargetType: "user"})
+}
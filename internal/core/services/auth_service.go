@@ -2,89 +2,393 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+const (
+	accessTokenTTL   = 15 * time.Minute
+	refreshTokenTTL  = 30 * 24 * time.Hour
+	impersonationTTL = time.Hour
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrSessionRevoked     = errors.New("session revoked")
+	ErrAccountSuspended   = errors.New("account is suspended")
+)
+
 type AuthService struct {
-	db        *gorm.DB
+	db     *gorm.DB
+	keys   *KeySet // tanda tangan JWT (RS256/EdDSA)
+	mailer ports.Mailer
+	appURL string // base URL frontend untuk link di email
+	audit  *AuditService
+
+	// secretKey HMAC untuk data yang hanya dibaca service ini sendiri (state cookie OIDC, token email)
 	secretKey []byte
 }
 
-func NewAuthService(db *gorm.DB, secret string) *AuthService {
+func NewAuthService(db *gorm.DB, keys *KeySet, secret []byte, mailer ports.Mailer, appURL string, audit *AuditService) *AuthService {
 	return &AuthService{
 		db:        db,
-		secretKey: []byte(secret),
+		keys:      keys,
+		mailer:    mailer,
+		appURL:    appURL,
+		audit:     audit,
+		secretKey: secret,
 	}
 }
 
+// RegisterInput format email & panjang dicek lewat binding; charset username dan policy password dicek di Register
 type RegisterInput struct {
-	Username string
-	Email    string
-	Password string
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Email    string `json:"email" binding:"required,email,max=100"`
+	Password string `json:"password" binding:"required"`
+}
+
+// TokenPair access token (JWT berumur pendek) + refresh token (opaque, dirotasi setiap dipakai)
+type TokenPair struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// TokenClaims hasil validasi access token
+type TokenClaims struct {
+	UserID         uuid.UUID
+	SessionID      uuid.UUID
+	ImpersonatorID *uuid.UUID // admin yang sedang impersonate user ini
+}
+
+// ClientInfo metadata device yang disimpan di session
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
 }
 
-func (s *AuthService) Register(ctx context.Context, input RegisterInput) error {
+func (s *AuthService) Register(ctx context.Context, input RegisterInput) (err error) {
+	input.Username = strings.TrimSpace(input.Username)
+	input.Email = strings.ToLower(strings.TrimSpace(input.Email))
+
+	var user domain.User
+	defer func() {
+		entry := AuditEntry{Action: AuditRegister, TargetType: "user", Metadata: map[string]any{"username": input.Username, "email": input.Email}, Err: err}
+		if err == nil {
+			entry.UserID = &user.ID
+			entry.TargetID = user.ID.String()
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	if err := validateUsername(input.Username); err != nil {
+		return err
+	}
+	if err := validatePassword(input.Password); err != nil {
+		return err
+	}
+
 	// Hash password
 	hashed, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
-	user := domain.User{
+	user = domain.User{
 		Username:     input.Username,
 		Email:        input.Email,
 		PasswordHash: string(hashed),
 	}
 
-	if err := s.db.WithContext(ctx).Create(&user).Error; err != nil {
+	// User baru langsung mendapat personal organization sebagai owner
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := ensureUnique(tx, "LOWER(username) = LOWER(?)", user.Username, uuid.Nil, ErrUsernameTaken); err != nil {
+			return err
+		}
+		if err := ensureUnique(tx, "LOWER(email) = ?", user.Email, uuid.Nil, ErrEmailTaken); err != nil {
+			return err
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		_, err := createPersonalOrganization(tx, &user)
+		return err
+	})
+	if err != nil {
 		return err
 	}
+
+	// Gagal kirim email tidak menggagalkan registrasi, user bisa minta kirim ulang
+	if err := s.SendVerificationEmail(ctx, user.ID); err != nil {
+		slog.WarnContext(ctx, "failed to send verification email", slog.String("user_id", user.ID.String()), slog.Any("error", err))
+	}
 	return nil
 }
 
-func (s *AuthService) Login(ctx context.Context, username, password string) (string, error) {
+// LoginResult berisi token jika login selesai, atau challenge jika user mengaktifkan 2FA
+type LoginResult struct {
+	Tokens         *TokenPair
+	MFARequired    bool
+	ChallengeToken string
+}
+
+func (s *AuthService) Login(ctx context.Context, username, password string, client ClientInfo) (result *LoginResult, err error) {
 	var user domain.User
-	if err := s.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
-		return "", errors.New("invalid credentials")
+	defer func() {
+		// user.ID tetap uuid.Nil jika username tidak dikenal
+		entry := AuditEntry{Action: AuditLogin, TargetType: "user", UserID: &user.ID, Metadata: map[string]any{"username": username, "method": "password"}, Err: err}
+		if user.ID != uuid.Nil {
+			entry.TargetID = user.ID.String()
+		}
+		if result != nil {
+			entry.Metadata["mfa_required"] = result.MFARequired
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	if err := s.db.WithContext(ctx).Where("LOWER(username) = LOWER(?)", strings.TrimSpace(username)).First(&user).Error; err != nil {
+		return nil, ErrInvalidCredentials
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return "", errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+	return s.finishLogin(ctx, &user, client)
+}
+
+// finishLogin dipakai setelah faktor pertama valid (password atau SSO): 2FA aktif berarti
+// kembalikan challenge, token baru diberikan setelah kode TOTP valid
+func (s *AuthService) finishLogin(ctx context.Context, user *domain.User, client ClientInfo) (*LoginResult, error) {
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+	if user.TOTPEnabled {
+		challenge, err := s.signChallengeToken(user)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{MFARequired: true, ChallengeToken: challenge}, nil
+	}
+
+	tokens, err := s.createSession(ctx, user.ID, client)
+	if err != nil {
+		return nil, err
 	}
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+// Refresh menukar refresh token dengan pasangan token baru (rotasi).
+// Refresh token lama yang dipakai ulang dianggap bocor dan session langsung dicabut.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (*TokenPair, error) {
+	hash := hashToken(refreshToken)
+	now := time.Now()
+
+	var pair *TokenPair
+	var reused bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var session domain.Session
+		if err := tx.Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			// Reuse detection: token lama yang sudah dirotasi dipakai lagi.
+			// Tidak return error di sini supaya pencabutan session tetap di-commit.
+			reused = true
+			return tx.Model(&domain.Session{}).
+				Where("previous_token_hash = ? AND revoked_at IS NULL", hash).
+				Update("revoked_at", now).Error
+		}
+
+		if !session.Active(now) {
+			return ErrSessionRevoked
+		}
+
+		newRefresh, err := generateOpaqueToken()
+		if err != nil {
+			return err
+		}
 
-	// Generate JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": user.ID.String(),
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		session.PreviousTokenHash = session.RefreshTokenHash
+		session.RefreshTokenHash = hashToken(newRefresh)
+		session.LastUsedAt = now
+		session.UserAgent = client.UserAgent
+		session.IPAddress = client.IPAddress
+		if err := tx.Save(&session).Error; err != nil {
+			return err
+		}
+
+		access, expiresAt, err := s.signAccessToken(session.UserID, session.ID, session.ImpersonatorID)
+		if err != nil {
+			return err
+		}
+		pair = &TokenPair{AccessToken: access, RefreshToken: newRefresh, ExpiresAt: expiresAt}
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrInvalidToken
+	}
+	return pair, nil
+}
 
-	tokenString, err := token.SignedString(s.secretKey)
+// Logout mencabut satu session (device saat ini)
+func (s *AuthService) Logout(ctx context.Context, sessionID uuid.UUID) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditLogout, TargetType: "session", TargetID: sessionID.String(), Err: err})
+	}()
+
+	return s.db.WithContext(ctx).Model(&domain.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// LogoutAll mencabut semua session milik user (log out all devices)
+func (s *AuthService) LogoutAll(ctx context.Context, userID uuid.UUID) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditLogoutAll, TargetType: "user", TargetID: userID.String(), Err: err})
+	}()
+
+	return s.db.WithContext(ctx).Model(&domain.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// ValidateToken memverifikasi JWT lalu memastikan session-nya belum dicabut
+func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*TokenClaims, error) {
+	token, err := s.keys.Parse(tokenString, jwt.WithLeeway(5*time.Second))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	sub, _ := claims.GetSubject()
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	sid, _ := claims["sid"].(string)
+	sessionID, err := uuid.Parse(sid)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var session domain.Session
+	err = s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, time.Now()).
+		First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionRevoked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenClaims{UserID: userID, SessionID: sessionID, ImpersonatorID: session.ImpersonatorID}, nil
+}
+
+// JWKS public key untuk verifikasi access token oleh service lain
+func (s *AuthService) JWKS() JWKS {
+	return s.keys.JWKS()
+}
+
+func (s *AuthService) createSession(ctx context.Context, userID uuid.UUID, client ClientInfo) (*TokenPair, error) {
+	return s.newSession(ctx, userID, nil, refreshTokenTTL, client)
+}
+
+// Impersonate membuka session berumur pendek atas nama user lain. Hanya dipanggil oleh AdminService.
+func (s *AuthService) Impersonate(ctx context.Context, adminID, userID uuid.UUID, client ClientInfo) (*TokenPair, error) {
+	return s.newSession(ctx, userID, &adminID, impersonationTTL, client)
+}
+
+// newSession satu-satunya jalur pembuatan session, sehingga user yang di-suspend tidak bisa login lewat cara apa pun
+func (s *AuthService) newSession(ctx context.Context, userID uuid.UUID, impersonatorID *uuid.UUID, ttl time.Duration, client ClientInfo) (*TokenPair, error) {
+	var user domain.User
+	if err := s.db.WithContext(ctx).Select("id", "suspended_at").First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+
+	refresh, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
 	}
-	return tokenString, nil
+
+	now := time.Now()
+	session := domain.Session{
+		ID:               uuid.New(),
+		UserID:           userID,
+		RefreshTokenHash: hashToken(refresh),
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		ExpiresAt:        now.Add(ttl),
+		LastUsedAt:       now,
+		ImpersonatorID:   impersonatorID,
+	}
+	if err := s.db.WithContext(ctx).Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	access, expiresAt, err := s.signAccessToken(userID, session.ID, impersonatorID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresAt: expiresAt}, nil
 }
 
-func (s *AuthService) ValidateToken(tokenString string) (uuid.UUID, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return s.secretKey, nil
-	}, jwt.WithLeeway(5*time.Second))
+func (s *AuthService) signAccessToken(userID, sessionID uuid.UUID, impersonatorID *uuid.UUID) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(accessTokenTTL)
 
+	claims := jwt.MapClaims{
+		"sub": userID.String(),
+		"sid": sessionID.String(),
+		"iat": now.Unix(),
+		"exp": expiresAt.Unix(),
+	}
+	// Claim "act" (RFC 8693) supaya service lain tahu token ini dipakai admin atas nama user
+	if impersonatorID != nil {
+		claims["act"] = map[string]string{"sub": impersonatorID.String()}
+	}
+	tokenString, err := s.keys.Sign(claims)
 	if err != nil {
-		return uuid.Nil, err
+		return "", time.Time{}, err
 	}
+	return tokenString, expiresAt, nil
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		sub, _ := claims.GetSubject()
-		return uuid.Parse(sub)
+// generateOpaqueToken membuat token acak 256-bit (base64url), dipakai refresh token dan API token
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	return uuid.Nil, errors.New("invalid token")
+// hashToken sha256 cukup untuk token acak berentropi tinggi (tidak perlu bcrypt)
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/mailer"
+)
+
+func TestRefreshRotatesToken(t *testing.T) {
+	db := testDB(t)
+	svc := newTestAuthService(t, db, mailer.NewMemoryMailer())
+	ctx := context.Background()
+	createTestUser(t, db, "alice", "alice@example.com", true)
+
+	login, err := svc.Login(ctx, "alice", testPassword, ClientInfo{UserAgent: "cli"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	first := login.Tokens
+	second, err := svc.Refresh(ctx, first.RefreshToken, ClientInfo{UserAgent: "cli"})
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+	if _, err := svc.ValidateToken(ctx, second.AccessToken); err != nil {
+		t.Fatalf("new access token: %v", err)
+	}
+
+	// Token lama dipakai ulang: dianggap bocor, seluruh session dicabut
+	if _, err := svc.Refresh(ctx, first.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("reused refresh token: err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.Refresh(ctx, second.RefreshToken, ClientInfo{}); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("refresh after reuse: err = %v, want ErrSessionRevoked", err)
+	}
+	if _, err := svc.ValidateToken(ctx, second.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("access token of a revoked session: err = %v, want ErrSessionRevoked", err)
+	}
+}
+
+func TestLogoutRevokesSessions(t *testing.T) {
+	db := testDB(t)
+	svc := newTestAuthService(t, db, mailer.NewMemoryMailer())
+	ctx := context.Background()
+	createTestUser(t, db, "alice", "alice@example.com", true)
+
+	login := func() (*TokenPair, *TokenClaims) {
+		result, err := svc.Login(ctx, "alice", testPassword, ClientInfo{})
+		if err != nil {
+			t.Fatalf("login: %v", err)
+		}
+		pair := result.Tokens
+		claims, err := svc.ValidateToken(ctx, pair.AccessToken)
+		if err != nil {
+			t.Fatalf("validate: %v", err)
+		}
+		return pair, claims
+	}
+
+	laptop, laptopClaims := login()
+	phone, _ := login()
+	if err := svc.Logout(ctx, laptopClaims.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ValidateToken(ctx, laptop.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("logged out session: err = %v, want ErrSessionRevoked", err)
+	}
+	if _, err := svc.ValidateToken(ctx, phone.AccessToken); err != nil {
+		t.Fatalf("other device was logged out: %v", err)
+	}
+
+	tablet, _ := login()
+	if err := svc.LogoutAll(ctx, laptopClaims.UserID); err != nil {
+		t.Fatal(err)
+	}
+	for name, pair := range map[string]*TokenPair{"phone": phone, "tablet": tablet} {
+		if _, err := svc.Refresh(ctx, pair.RefreshToken, ClientInfo{}); !errors.Is(err, ErrSessionRevoked) {
+			t.Fatalf("%s after logout all: err = %v, want ErrSessionRevoked", name, err)
+		}
+	}
+}
+
+func TestLoginRejectsWrongPassword(t *testing.T) {
+	db := testDB(t)
+	svc := newTestAuthService(t, db, mailer.NewMemoryMailer())
+	createTestUser(t, db, "alice", "alice@example.com", true)
+
+	for _, tt := range []struct{ username, password string }{
+		{"alice", "wrong-password-1"},
+		{"bob", testPassword},
+	} {
+		if _, err := svc.Login(context.Background(), tt.username, tt.password, ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("login %s: err = %v, want ErrInvalidCredentials", tt.username, err)
+		}
+	}
+}
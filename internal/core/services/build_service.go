@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/buildpack"
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/damantine/multi-tenant-hosting/internal/logging"
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	maxBuildArgs      = 50
+	defaultDockerfile = "Dockerfile"
+)
+
+var (
+	ErrNotGitProject    = errors.New("project source is not a git repository")
+	ErrInvalidBuildPath = errors.New("subdirectory and Dockerfile path must be relative paths inside the repository")
+	ErrInvalidBuildArg  = errors.New("invalid build argument")
+)
+
+// BuildLimits batas ukuran build context yang di-upload
+type BuildLimits struct {
+	MaxUploadBytes  int64 // ukuran tar.gz yang diterima
+	MaxContextBytes int64 // total ukuran file setelah diekstrak
+	MaxFiles        int
+}
+
+func DefaultBuildLimits() BuildLimits {
+	return BuildLimits{
+		MaxUploadBytes:  100 << 20,
+		MaxContextBytes: 500 << 20,
+		MaxFiles:        20000,
+	}
+}
+
+// BuildOptions opsi build dari source yang di-upload
+type BuildOptions struct {
+	Dockerfile string // relatif terhadap root archive, default Dockerfile project atau "Dockerfile"
+	BuildArgs  map[string]string
+}
+
+// BuildService membangun image dari source project lalu menjalankan deploy biasa
+type BuildService struct {
+	repo       ports.ProjectRepository
+	git        ports.GitClient
+	builder    ports.ImageBuilder
+	projectSvc *ProjectService
+	audit      *AuditService
+	limits     BuildLimits
+}
+
+func NewBuildService(repo ports.ProjectRepository, git ports.GitClient, builder ports.ImageBuilder, projectSvc *ProjectService, audit *AuditService, limits BuildLimits) *BuildService {
+	return &BuildService{repo: repo, git: git, builder: builder, projectSvc: projectSvc, audit: audit, limits: limits}
+}
+
+// Limits batas upload yang berlaku (dipakai handler untuk membatasi body request)
+func (s *BuildService) Limits() BuildLimits {
+	return s.limits
+}
+
+// BuildFromGit clone repository project, build image dengan tag per commit, lalu deploy.
+// Progress clone/build ditulis ke logs.
+func (s *BuildService) BuildFromGit(ctx context.Context, projectID uuid.UUID, logs io.Writer) (_ *domain.Build, _ *domain.Deployment, err error) {
+	ctx = logging.With(ctx, slog.String(logging.KeyProjectID, projectID.String()))
+	ctx, span := tracing.Start(ctx, "BuildService.BuildFromGit", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var project *domain.Project
+	var build *domain.Build
+	defer func() { s.audit.Record(ctx, buildAudit(projectID, project, build, err)) }()
+
+	project, err = s.loadProject(ctx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if project.SourceType != domain.SourceTypeGit {
+		return nil, nil, ErrNotGitProject
+	}
+	contextSubdir, dockerfile, err := buildPaths(project.GitSubdir, project.DockerfilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	workDir, err := os.MkdirTemp("", "mth-build-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(workDir)
+	repoDir := filepath.Join(workDir, "src")
+
+	build, err = s.startBuild(ctx, project, domain.SourceTypeGit)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx = logging.With(ctx, slog.String("build_id", build.ID.String()))
+
+	branch := project.GitBranch
+	if branch == "" {
+		branch = "(default branch)"
+	}
+	fmt.Fprintf(logs, "Cloning %s %s\n", project.GitURL, branch)
+	commit, err := s.git.Clone(ctx, project.GitURL, project.GitBranch, repoDir)
+	if err != nil {
+		s.finishBuild(ctx, build, err)
+		return build, nil, err
+	}
+	build.CommitSHA = commit
+	build.Image = localImagePrefix(project.ID) + shortSHA(commit)
+	fmt.Fprintf(logs, "Checked out commit %s\n", commit)
+
+	deployment, err := s.buildAndDeploy(ctx, project, build, repoDir, contextSubdir, dockerfile, nil, logs)
+	return build, deployment, err
+}
+
+// BuildFromArchive build image dari build context tar.gz yang di-upload, lalu deploy.
+// Archive dibaca habis sebelum ada output ke logs, supaya handler masih bisa membalas error dengan status yang tepat.
+func (s *BuildService) BuildFromArchive(ctx context.Context, projectID uuid.UUID, archive io.Reader, opts BuildOptions, logs io.Writer) (_ *domain.Build, _ *domain.Deployment, err error) {
+	ctx = logging.With(ctx, slog.String(logging.KeyProjectID, projectID.String()))
+	ctx, span := tracing.Start(ctx, "BuildService.BuildFromArchive", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var project *domain.Project
+	var build *domain.Build
+	defer func() { s.audit.Record(ctx, buildAudit(projectID, project, build, err)) }()
+
+	project, err = s.loadProject(ctx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Dockerfile == "" {
+		opts.Dockerfile = project.DockerfilePath
+	}
+	_, dockerfile, err := buildPaths("", opts.Dockerfile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validateBuildArgs(opts.BuildArgs); err != nil {
+		return nil, nil, err
+	}
+
+	workDir, err := os.MkdirTemp("", "mth-upload-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	stats, err := extractTarGz(archive, workDir, s.limits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	build, err = s.startBuild(ctx, project, domain.SourceTypeUpload)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx = logging.With(ctx, slog.String("build_id", build.ID.String()))
+	build.Image = localImagePrefix(project.ID) + "build-" + strings.ReplaceAll(build.ID.String(), "-", "")[:12]
+
+	fmt.Fprintf(logs, "Received build context: %d files, %d bytes\n", stats.Files, stats.Bytes)
+	for _, skipped := range stats.Skipped {
+		fmt.Fprintf(logs, "Skipped %s (links and special files are not supported)\n", skipped)
+	}
+
+	// Project image biasa berubah menjadi project upload; project git tetap git
+	if project.SourceType == domain.SourceTypeImage {
+		project.SourceType = domain.SourceTypeUpload
+	}
+	deployment, err := s.buildAndDeploy(ctx, project, build, workDir, ".", dockerfile, opts.BuildArgs, logs)
+	return build, deployment, err
+}
+
+// localImageRepo namespace image hasil build; tag selalu mth/<project id>:<versi>
+const localImageRepo = "mth"
+
+// projectIDLabel label image hasil build berisi ID project pemiliknya
+const projectIDLabel = "mth.project_id"
+
+func localImagePrefix(projectID uuid.UUID) string {
+	return localImageRepo + "/" + projectID.String() + ":"
+}
+
+func (s *BuildService) loadProject(ctx context.Context, projectID uuid.UUID) (*domain.Project, error) {
+	project, err := s.repo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("project not found: %w", err)
+	}
+	if err := s.projectSvc.ensureTenantActive(ctx, project.OrganizationID); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (s *BuildService) startBuild(ctx context.Context, project *domain.Project, source string) (*domain.Build, error) {
+	build := &domain.Build{ID: uuid.New(), ProjectID: project.ID, Source: source, Status: domain.BuildStatusBuilding}
+	if err := s.repo.CreateBuild(ctx, build); err != nil {
+		return nil, fmt.Errorf("failed to record build: %w", err)
+	}
+	return build, nil
+}
+
+// buildAndDeploy build image dari rootDir/contextSubdir, set sebagai image project, lalu deploy
+func (s *BuildService) buildAndDeploy(ctx context.Context, project *domain.Project, build *domain.Build, rootDir, contextSubdir, dockerfile string, buildArgs map[string]string, logs io.Writer) (*domain.Deployment, error) {
+	if err := s.runBuild(ctx, project, build, rootDir, contextSubdir, dockerfile, buildArgs, logs); err != nil {
+		s.finishBuild(ctx, build, err)
+		return nil, err
+	}
+	s.finishBuild(ctx, build, nil)
+
+	// Deploy berikutnya (termasuk start/redeploy) memakai image hasil build ini
+	project.ImageName = build.Image
+	if err := s.repo.Update(ctx, project); err != nil {
+		return nil, fmt.Errorf("failed to update project image: %w", err)
+	}
+
+	fmt.Fprintf(logs, "Deploying %s\n", build.Image)
+	return s.projectSvc.DeployProject(ctx, project.ID)
+}
+
+func (s *BuildService) runBuild(ctx context.Context, project *domain.Project, build *domain.Build, rootDir, contextSubdir, dockerfile string, buildArgs map[string]string, logs io.Writer) error {
+	// Symlink di source tidak boleh membawa build context keluar dari direktori kerja
+	rootDir, err := filepath.EvalSymlinks(rootDir)
+	if err != nil {
+		return err
+	}
+	contextDir, err := filepath.EvalSymlinks(filepath.Join(rootDir, contextSubdir))
+	if err != nil {
+		return fmt.Errorf("build context %q not found in source", contextSubdir)
+	}
+	if rel, err := filepath.Rel(rootDir, contextDir); err != nil || !filepath.IsLocal(rel) {
+		return ErrInvalidBuildPath
+	}
+	if info, err := os.Stat(contextDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("build context %q not found in source", contextSubdir)
+	}
+	if _, err := os.Stat(filepath.Join(contextDir, dockerfile)); err != nil {
+		// Dockerfile hanya dibuat otomatis jika project tidak menentukan path Dockerfile sendiri
+		if dockerfile != defaultDockerfile {
+			return fmt.Errorf("dockerfile %q not found in build context", dockerfile)
+		}
+		if dockerfile, err = generateDockerfile(project, build, contextDir, logs); err != nil {
+			return err
+		}
+	}
+
+	args := make(map[string]*string, len(buildArgs))
+	for k, v := range buildArgs {
+		args[k] = &v
+	}
+
+	labels := map[string]string{
+		projectIDLabel: project.ID.String(),
+		"mth.build_id": build.ID.String(),
+	}
+	if build.CommitSHA != "" {
+		labels["mth.commit"] = build.CommitSHA
+	}
+
+	fmt.Fprintf(logs, "Building %s\n", build.Image)
+	return s.builder.BuildImage(ctx, ports.BuildConfig{
+		ContextDir: contextDir,
+		Dockerfile: dockerfile,
+		Tags:       []string{build.Image},
+		Labels:     labels,
+		BuildArgs:  args,
+	}, logs)
+}
+
+// generateDockerfile mendeteksi stack dari isi build context lalu menulis Dockerfile dari template.
+// Port project yang belum diisi memakai port default stack.
+func generateDockerfile(project *domain.Project, build *domain.Build, contextDir string, logs io.Writer) (string, error) {
+	stack, err := buildpack.Detect(contextDir)
+	if err != nil {
+		return "", err
+	}
+	if project.ContainerPort <= 0 {
+		project.ContainerPort = stack.DefaultPort
+	}
+	content, err := stack.Dockerfile(project.ContainerPort)
+	if err != nil {
+		return "", err
+	}
+	// File lama (bisa berupa symlink dari repository) dihapus supaya write tidak mengikuti link ke luar source
+	for _, name := range []string{buildpack.DockerfileName, ".dockerignore"} {
+		if info, err := os.Lstat(filepath.Join(contextDir, name)); err == nil && !info.Mode().IsRegular() {
+			if err := os.RemoveAll(filepath.Join(contextDir, name)); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(contextDir, buildpack.DockerfileName), content, 0o644); err != nil {
+		return "", err
+	}
+
+	// Dockerfile hasil generate dan dependency lokal tidak ikut ter-copy ke image
+	f, err := os.OpenFile(filepath.Join(contextDir, ".dockerignore"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "\n%s\n", strings.Join(stack.IgnorePatterns(), "\n")); err != nil {
+		return "", err
+	}
+
+	build.Stack = stack.Name
+	fmt.Fprintf(logs, "No Dockerfile found, detected %s stack; using generated Dockerfile (port %d)\n", stack.Name, project.ContainerPort)
+	return buildpack.DockerfileName, nil
+}
+
+// finishBuild menyimpan hasil akhir build; gagal menyimpan hanya di-log supaya error build asli tidak tertutup
+func (s *BuildService) finishBuild(ctx context.Context, build *domain.Build, buildErr error) {
+	now := time.Now()
+	build.FinishedAt = &now
+	build.Status = domain.BuildStatusSucceeded
+	if buildErr != nil {
+		build.Status = domain.BuildStatusFailed
+		build.Error = buildErr.Error()
+		slog.ErrorContext(ctx, "build failed", slog.Any("error", buildErr))
+	} else {
+		slog.InfoContext(ctx, "build succeeded", slog.String("image", build.Image))
+	}
+	if err := s.repo.UpdateBuild(context.WithoutCancel(ctx), build); err != nil {
+		slog.WarnContext(ctx, "failed to update build status", slog.Any("error", err))
+	}
+}
+
+func buildAudit(projectID uuid.UUID, project *domain.Project, build *domain.Build, err error) AuditEntry {
+	entry := projectAudit(AuditProjectBuild, projectID, project, err)
+	if build != nil {
+		entry.Metadata = map[string]any{"build_id": build.ID.String(), "source": build.Source, "image": build.Image}
+		if build.CommitSHA != "" {
+			entry.Metadata["commit"] = build.CommitSHA
+		}
+		if build.Stack != "" {
+			entry.Metadata["stack"] = build.Stack
+		}
+	}
+	return entry
+}
+
+// buildPaths memvalidasi subdirectory dan Dockerfile supaya tidak keluar dari source
+func buildPaths(subdir, dockerfile string) (string, string, error) {
+	subdir = filepath.Clean(filepath.FromSlash(subdir))
+	if dockerfile == "" {
+		dockerfile = defaultDockerfile
+	}
+	dockerfile = filepath.Clean(filepath.FromSlash(dockerfile))
+	if !filepath.IsLocal(subdir) || !filepath.IsLocal(dockerfile) {
+		return "", "", ErrInvalidBuildPath
+	}
+	return subdir, dockerfile, nil
+}
+
+// validateBuildArgs nama build arg mengikuti aturan nama env var
+func validateBuildArgs(args map[string]string) error {
+	if len(args) > maxBuildArgs {
+		return fmt.Errorf("%w: at most %d build arguments are allowed", ErrInvalidBuildArg, maxBuildArgs)
+	}
+	for key := range args {
+		if !envKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w: %q", ErrInvalidBuildArg, key)
+		}
+	}
+	return nil
+}
+
+func shortSHA(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
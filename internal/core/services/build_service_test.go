@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/buildpack"
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/google/uuid"
+)
+
+// buildRepo menyimpan satu project dan build yang dicatat
+type buildRepo struct {
+	ports.ProjectRepository
+	project *domain.Project
+	builds  []*domain.Build
+}
+
+func (r *buildRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Project, error) {
+	return r.project, nil
+}
+
+func (r *buildRepo) CreateBuild(ctx context.Context, build *domain.Build) error {
+	r.builds = append(r.builds, build)
+	return nil
+}
+
+func (r *buildRepo) UpdateBuild(ctx context.Context, build *domain.Build) error { return nil }
+
+// recordingBuilder mencatat config build lalu gagal supaya test berhenti sebelum deploy
+type recordingBuilder struct {
+	config     ports.BuildConfig
+	dockerfile string
+}
+
+var errBuildStopped = errors.New("build stopped by test")
+
+func (b *recordingBuilder) BuildImage(ctx context.Context, config ports.BuildConfig, logs io.Writer) error {
+	b.config = config
+	data, _ := os.ReadFile(filepath.Join(config.ContextDir, config.Dockerfile))
+	b.dockerfile = string(data)
+	return errBuildStopped
+}
+
+func TestBuildFromArchiveTagsImagePerBuild(t *testing.T) {
+	project := &domain.Project{ID: uuid.New(), UserID: uuid.New(), SourceType: domain.SourceTypeImage}
+	repo := &buildRepo{project: project}
+	builder := &recordingBuilder{}
+	audit := NewAuditService(&memoryAuditRepo{})
+	projectSvc := NewProjectService(repo, nil, nil, &fakeTenantRepo{}, nil, nil, nil, audit, ProjectConfig{})
+	svc := NewBuildService(repo, nil, builder, projectSvc, audit, DefaultBuildLimits())
+
+	archive := tarGz(t, tarEntry{name: "Dockerfile", body: "FROM scratch"})
+	build, _, err := svc.BuildFromArchive(context.Background(), project.ID, archive, BuildOptions{}, io.Discard)
+	if !errors.Is(err, errBuildStopped) {
+		t.Fatalf("err = %v, want builder error", err)
+	}
+
+	wantTag := "mth/" + project.ID.String() + ":build-" + strings.ReplaceAll(build.ID.String(), "-", "")[:12]
+	if build.Image != wantTag || !reflect.DeepEqual(builder.config.Tags, []string{wantTag}) {
+		t.Fatalf("image = %q, tags = %v, want %q", build.Image, builder.config.Tags, wantTag)
+	}
+	if builder.config.Labels["mth.project_id"] != project.ID.String() || builder.config.Labels["mth.build_id"] != build.ID.String() {
+		t.Fatalf("labels = %v", builder.config.Labels)
+	}
+	if builder.dockerfile != "FROM scratch" {
+		t.Fatalf("Dockerfile in build context = %q", builder.dockerfile)
+	}
+	if build.Status != domain.BuildStatusFailed || build.Source != domain.SourceTypeUpload {
+		t.Fatalf("build = %s/%s, want failed upload build", build.Status, build.Source)
+	}
+}
+
+func TestBuildGeneratesDockerfileOnlyForDefaultPath(t *testing.T) {
+	tests := []struct {
+		name           string
+		dockerfilePath string // DockerfilePath project
+		files          []tarEntry
+		wantDockerfile string // Dockerfile yang dipakai builder; kosong = builder tidak dipanggil
+		wantStack      string
+		wantPort       int
+		wantErr        error // nil = cukup error apa pun
+	}{
+		{
+			name:           "source Dockerfile is used as is",
+			files:          []tarEntry{{name: "Dockerfile", body: "FROM scratch"}, {name: "index.html", body: "hi"}},
+			wantDockerfile: "Dockerfile",
+		},
+		{
+			name:           "missing default Dockerfile is generated",
+			files:          []tarEntry{{name: "index.html", body: "hi"}},
+			wantDockerfile: ".mth.Dockerfile",
+			wantStack:      "static",
+			wantPort:       8080,
+		},
+		{
+			name:           "explicit default path still generates",
+			dockerfilePath: "Dockerfile",
+			files:          []tarEntry{{name: "index.html", body: "hi"}},
+			wantDockerfile: ".mth.Dockerfile",
+			wantStack:      "static",
+			wantPort:       8080,
+		},
+		{
+			name:           "missing custom Dockerfile is an error",
+			dockerfilePath: "docker/prod.Dockerfile",
+			files:          []tarEntry{{name: "index.html", body: "hi"}},
+		},
+		{
+			name:    "undetectable stack",
+			files:   []tarEntry{{name: "README.md", body: "hi"}},
+			wantErr: buildpack.ErrNotDetected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := &domain.Project{ID: uuid.New(), UserID: uuid.New(), SourceType: domain.SourceTypeImage}
+			project.DockerfilePath = tt.dockerfilePath
+			repo := &buildRepo{project: project}
+			builder := &recordingBuilder{}
+			audit := NewAuditService(&memoryAuditRepo{})
+			svc := NewBuildService(repo, nil, builder, NewProjectService(repo, nil, nil, &fakeTenantRepo{}, nil, nil, nil, audit, ProjectConfig{}), audit, DefaultBuildLimits())
+
+			build, _, err := svc.BuildFromArchive(context.Background(), project.ID, tarGz(t, tt.files...), BuildOptions{}, io.Discard)
+			if tt.wantDockerfile == "" {
+				if err == nil || errors.Is(err, errBuildStopped) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("err = %v, want build to fail before the builder (%v)", err, tt.wantErr)
+				}
+				return
+			}
+			if !errors.Is(err, errBuildStopped) {
+				t.Fatalf("err = %v, want builder to run", err)
+			}
+			if builder.config.Dockerfile != tt.wantDockerfile {
+				t.Fatalf("Dockerfile = %q, want %q", builder.config.Dockerfile, tt.wantDockerfile)
+			}
+			if build.Stack != tt.wantStack || project.ContainerPort != tt.wantPort {
+				t.Fatalf("stack = %q port = %d, want %q port %d", build.Stack, project.ContainerPort, tt.wantStack, tt.wantPort)
+			}
+			if tt.wantStack != "" && !strings.Contains(builder.dockerfile, "EXPOSE 8080") {
+				t.Fatalf("generated Dockerfile:\n%s", builder.dockerfile)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	verifyEmailTTL   = 24 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
+var (
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	ErrEmailNotVerified     = errors.New("email address has not been verified")
+)
+
+// SendVerificationEmail mengirim (ulang) link verifikasi ke email user
+func (s *AuthService) SendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	var user domain.User
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := s.issueEmailToken(ctx, &user, domain.TokenPurposeVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, ports.MailMessage{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+			user.Username, s.appLink("/verify-email", token), verifyEmailTTL),
+	})
+}
+
+// VerifyEmail menandai email user terverifikasi dengan token dari link verifikasi
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record, user, err := s.consumeEmailToken(tx, token, domain.TokenPurposeVerifyEmail)
+		if err != nil {
+			return err
+		}
+		if user.EmailVerified {
+			return nil
+		}
+		return tx.Model(user).Updates(map[string]interface{}{
+			"email_verified":    true,
+			"email_verified_at": record.UsedAt,
+		}).Error
+	})
+}
+
+// RequestPasswordReset mengirim link reset password jika email terdaftar.
+// Selalu return nil untuk email yang tidak dikenal supaya tidak bisa dipakai untuk enumerasi akun.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	var user domain.User
+	err := s.db.WithContext(ctx).Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := s.issueEmailToken(ctx, &user, domain.TokenPurposeResetPassword, resetPasswordTTL)
+	if err != nil {
+		return err
+	}
+
+	msg := ports.MailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone requested a password reset for your account. If it was you, open the link below:\n\n%s\n\nThe link expires in %s. If you did not request this, you can ignore this email.\n",
+			user.Username, s.appLink("/reset-password", token), resetPasswordTTL),
+	}
+
+	// Kirim di background: waktu respons tidak boleh membedakan email terdaftar vs tidak
+	go func() {
+		sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := s.mailer.Send(sendCtx, msg); err != nil {
+			slog.ErrorContext(sendCtx, "failed to send password reset email", slog.Any("error", err))
+		}
+	}()
+	return nil
+}
+
+// ResetPassword mengganti password dengan token reset, lalu mencabut semua session user
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) (err error) {
+	var userID uuid.UUID
+	defer func() {
+		entry := AuditEntry{Action: AuditPasswordReset, TargetType: "user", UserID: &userID, Err: err}
+		if userID != uuid.Nil {
+			entry.TargetID = userID.String()
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record, user, err := s.consumeEmailToken(tx, token, domain.TokenPurposeResetPassword)
+		if err != nil {
+			return err
+		}
+		userID = user.ID
+
+		// Link reset yang sampai ke inbox sekaligus membuktikan kepemilikan email
+		updates := map[string]interface{}{"password_hash": string(hashed)}
+		if !user.EmailVerified {
+			updates["email_verified"] = true
+			updates["email_verified_at"] = record.UsedAt
+		}
+		if err := tx.Model(user).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// Token reset lain yang masih berlaku ikut dihanguskan
+		if err := tx.Model(&domain.EmailToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, domain.TokenPurposeResetPassword).
+			Update("used_at", record.UsedAt).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&domain.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", record.UsedAt).Error
+	})
+}
+
+// issueEmailToken menyimpan record token lalu mengembalikan "<id>.<hmac>" untuk dikirim ke user
+func (s *AuthService) issueEmailToken(ctx context.Context, user *domain.User, purpose string, ttl time.Duration) (string, error) {
+	record := domain.EmailToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     strings.ToLower(user.Email),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.db.WithContext(ctx).Create(&record).Error; err != nil {
+		return "", err
+	}
+	return record.ID.String() + "." + s.signEmailToken(&record), nil
+}
+
+// consumeEmailToken memvalidasi tanda tangan, tujuan, masa berlaku dan status sekali pakai, lalu menandai token terpakai
+func (s *AuthService) consumeEmailToken(tx *gorm.DB, token, purpose string) (*domain.EmailToken, *domain.User, error) {
+	rawID, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, nil, ErrInvalidToken
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	var record domain.EmailToken
+	if err := tx.First(&record, "id = ? AND purpose = ?", id, purpose).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signEmailToken(&record))) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if record.UsedAt != nil || now.After(record.ExpiresAt) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	var user domain.User
+	if err := tx.First(&user, "id = ?", record.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+	// Email sudah diganti sejak token dibuat
+	if !strings.EqualFold(user.Email, record.Email) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	// Update bersyarat supaya dua request paralel tidak bisa memakai token yang sama
+	res := tx.Model(&domain.EmailToken{}).Where("id = ? AND used_at IS NULL", record.ID).Update("used_at", now)
+	if res.Error != nil {
+		return nil, nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil, ErrInvalidToken
+	}
+	record.UsedAt = &now
+	return &record, &user, nil
+}
+
+func (s *AuthService) signEmailToken(record *domain.EmailToken) string {
+	mac := hmac.New(sha256.New, s.secretKey)
+	fmt.Fprintf(mac, "%s|%s|%s|%s|%d", record.Purpose, record.ID, record.UserID, record.Email, record.ExpiresAt.Unix())
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// appLink URL frontend yang dibuka dari email
+func (s *AuthService) appLink(path, token string) string {
+	return strings.TrimRight(s.appURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/mailer"
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+)
+
+var mailTokenPattern = regexp.MustCompile(`[?&]token=([^\s&]+)`)
+
+// tokenFromMail mengambil token dari link di email terakhir untuk alamat to (menunggu pengiriman di background)
+func tokenFromMail(t *testing.T, m *mailer.MemoryMailer, to string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if msg, ok := m.Last(to); ok {
+			match := mailTokenPattern.FindStringSubmatch(msg.Body)
+			if match == nil {
+				t.Fatalf("no token link in email body:\n%s", msg.Body)
+			}
+			token, err := url.QueryUnescape(match[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no email sent to %s", to)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestVerifyEmailFlow(t *testing.T) {
+	db := testDB(t)
+	m := mailer.NewMemoryMailer()
+	svc := newTestAuthService(t, db, m)
+	ctx := context.Background()
+
+	if err := svc.Register(ctx, RegisterInput{Username: "alice", Email: "Alice@Example.com", Password: testPassword}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	token := tokenFromMail(t, m, "alice@example.com")
+
+	if err := svc.VerifyEmail(ctx, token); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	var user domain.User
+	db.First(&user, "username = ?", "alice")
+	if !user.EmailVerified || user.EmailVerifiedAt == nil {
+		t.Fatalf("user not verified: %+v", user)
+	}
+
+	if err := svc.VerifyEmail(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("second use: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestVerifyEmailRejectsInvalidTokens(t *testing.T) {
+	db := testDB(t)
+	svc := newTestAuthService(t, db, mailer.NewMemoryMailer())
+	ctx := context.Background()
+	user := createTestUser(t, db, "alice", "alice@example.com", false)
+
+	issue := func(purpose string, ttl time.Duration) string {
+		token, err := svc.issueEmailToken(ctx, user, purpose, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{name: "malformed", token: func() string { return "not-a-token" }},
+		{name: "tampered signature", token: func() string {
+			id, sig, _ := strings.Cut(issue(domain.TokenPurposeVerifyEmail, time.Hour), ".")
+			return id + "." + flipFirst(sig)
+		}},
+		{name: "signature of another token", token: func() string {
+			id, _, _ := strings.Cut(issue(domain.TokenPurposeVerifyEmail, time.Hour), ".")
+			_, sig, _ := strings.Cut(issue(domain.TokenPurposeVerifyEmail, time.Hour), ".")
+			return id + "." + sig
+		}},
+		{name: "reset token used for verification", token: func() string { return issue(domain.TokenPurposeResetPassword, time.Hour) }},
+		{name: "expired", token: func() string { return issue(domain.TokenPurposeVerifyEmail, -time.Minute) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := svc.VerifyEmail(ctx, tt.token()); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("err = %v, want ErrInvalidToken", err)
+			}
+			var fresh domain.User
+			db.First(&fresh, "id = ?", user.ID)
+			if fresh.EmailVerified {
+				t.Fatal("email verified with an invalid token")
+			}
+		})
+	}
+}
+
+func TestEmailChangeInvalidatesPendingToken(t *testing.T) {
+	db := testDB(t)
+	m := mailer.NewMemoryMailer()
+	svc := newTestAuthService(t, db, m)
+	ctx := context.Background()
+	user := createTestUser(t, db, "alice", "alice@example.com", false)
+
+	if err := svc.SendVerificationEmail(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	oldToken := tokenFromMail(t, m, "alice@example.com")
+
+	newEmail := "alice@new.example"
+	if _, err := svc.UpdateProfile(ctx, user.ID, UpdateProfileInput{Email: &newEmail}); err != nil {
+		t.Fatalf("update profile: %v", err)
+	}
+	if err := svc.VerifyEmail(ctx, oldToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token for old email: err = %v, want ErrInvalidToken", err)
+	}
+
+	if err := svc.VerifyEmail(ctx, tokenFromMail(t, m, newEmail)); err != nil {
+		t.Fatalf("token for new email: %v", err)
+	}
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	db := testDB(t)
+	m := mailer.NewMemoryMailer()
+	svc := newTestAuthService(t, db, m)
+	ctx := context.Background()
+	user := createTestUser(t, db, "alice", "alice@example.com", false)
+
+	session, err := svc.createSession(ctx, user.ID, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := svc.issueEmailToken(ctx, user, domain.TokenPurposeResetPassword, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Email tidak dikenal tidak mengirim apa pun dan tidak error
+	if err := svc.RequestPasswordReset(ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("unknown email: %v", err)
+	}
+	if err := svc.RequestPasswordReset(ctx, "ALICE@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	token := tokenFromMail(t, m, "alice@example.com")
+	if len(m.Messages()) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(m.Messages()))
+	}
+
+	const newPassword = "new-password-77"
+	if err := svc.ResetPassword(ctx, token, newPassword); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+
+	if _, err := svc.Refresh(ctx, session.RefreshToken, ClientInfo{}); err == nil {
+		t.Fatal("session created before the reset is still usable")
+	}
+	if _, err := svc.Login(ctx, "alice", testPassword, ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("login with old password: err = %v", err)
+	}
+	if _, err := svc.Login(ctx, "alice", newPassword, ClientInfo{}); err != nil {
+		t.Fatalf("login with new password: %v", err)
+	}
+
+	// Link reset membuktikan kepemilikan email; token sekali pakai dan token reset lain ikut hangus
+	var fresh domain.User
+	db.First(&fresh, "id = ?", user.ID)
+	if !fresh.EmailVerified {
+		t.Fatal("email not marked verified by password reset")
+	}
+	for name, tok := range map[string]string{"reused token": token, "other pending token": pending} {
+		if err := svc.ResetPassword(ctx, tok, "another-pass-88"); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("%s: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func flipFirst(s string) string {
+	if s == "" {
+		return "x"
+	}
+	b := []byte(s)
+	if b[0] == 'A' {
+		b[0] = 'B'
+	} else {
+		b[0] = 'A'
+	}
+	return string(b)
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// Nilai default profil hardening container tenant
+const (
+	defaultPidsLimit    = 256
+	nonRootUser         = "65534:65534" // nobody:nogroup
+	defaultTmpfsOptions = "rw,noexec,nosuid,nodev,size=64m"
+)
+
+var (
+	ErrRelaxationNotAllowed   = errors.New("security relaxation is not allowed by your plan")
+	ErrInvalidCapability      = errors.New("unknown Linux capability")
+	ErrSecurityPolicyNotFound = errors.New("security policy not found")
+	ErrInvalidSecurityPolicy  = errors.New("invalid security policy")
+)
+
+// knownCapabilities capability yang boleh dikembalikan lewat CapAdd (tanpa prefix CAP_)
+var knownCapabilities = []string{
+	"AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "MKNOD", "NET_BIND_SERVICE",
+	"NET_RAW", "SETFCAP", "SETGID", "SETPCAP", "SETUID", "SYS_CHROOT", "NET_ADMIN", "SYS_PTRACE",
+	"SYS_RESOURCE", "SYS_TIME", "IPC_LOCK", "SYS_NICE", "SYS_ADMIN", "SYS_MODULE", "SYS_RAWIO", "DAC_READ_SEARCH",
+}
+
+// HardeningService menyusun profil keamanan container dari profil ketat platform
+// dan pelonggaran project yang diizinkan SecurityPolicy plan tenant
+type HardeningService struct {
+	db      *gorm.DB
+	audit   *AuditService
+	seccomp string
+}
+
+// NewHardeningService seccomp berisi JSON profile seccomp (kosong = profile default Docker)
+func NewHardeningService(db *gorm.DB, audit *AuditService, seccomp string) *HardeningService {
+	return &HardeningService{db: db, audit: audit, seccomp: seccomp}
+}
+
+type SecurityPolicyInput struct {
+	AllowWritableRootfs bool
+	AllowImageUser      bool
+	AllowedCapabilities []string
+	PidsLimit           int64
+}
+
+func (s *HardeningService) ListPolicies(ctx context.Context) ([]domain.SecurityPolicy, error) {
+	var policies []domain.SecurityPolicy
+	err := s.db.WithContext(ctx).Order("plan").Find(&policies).Error
+	return policies, err
+}
+
+// PutPolicy membuat atau mengganti pelonggaran yang boleh dipakai tenant dengan plan tersebut
+func (s *HardeningService) PutPolicy(ctx context.Context, adminID uuid.UUID, plan string, input SecurityPolicyInput) (policy *domain.SecurityPolicy, err error) {
+	var before map[string]any
+	defer func() {
+		entry := AuditEntry{Action: AuditAdminSecurityPolicy, TargetType: "security_policy", TargetID: plan, Before: before, Err: err}
+		if policy != nil {
+			entry.After = securityPolicySnapshot(policy)
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	if !planPattern.MatchString(plan) {
+		return nil, ErrInvalidPlan
+	}
+	if input.PidsLimit < 0 {
+		return nil, fmt.Errorf("%w: pids limit must not be negative", ErrInvalidSecurityPolicy)
+	}
+	caps, err := normalizeCapabilities(input.AllowedCapabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing domain.SecurityPolicy
+	err = s.db.WithContext(ctx).Where("plan = ?", plan).First(&existing).Error
+	switch {
+	case err == nil:
+		before = securityPolicySnapshot(&existing)
+		policy = &existing
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		policy = &domain.SecurityPolicy{ID: uuid.New(), Plan: plan}
+	default:
+		return nil, err
+	}
+
+	policy.AllowWritableRootfs = input.AllowWritableRootfs
+	policy.AllowImageUser = input.AllowImageUser
+	policy.AllowedCapabilities = caps
+	policy.PidsLimit = input.PidsLimit
+	policy.UpdatedBy = adminID
+	if err := s.db.WithContext(ctx).Save(policy).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (s *HardeningService) DeletePolicy(ctx context.Context, plan string) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditAdminSecurityPolicyDelete, TargetType: "security_policy", TargetID: plan, Err: err})
+	}()
+
+	res := s.db.WithContext(ctx).Where("plan = ?", plan).Delete(&domain.SecurityPolicy{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSecurityPolicyNotFound
+	}
+	return nil
+}
+
+// Profile profil keamanan container project. Pelonggaran yang tidak diizinkan plan
+// (mis. plan diturunkan setelah pelonggaran dipilih) membuat deploy gagal, bukan diam-diam diabaikan.
+func (s *HardeningService) Profile(ctx context.Context, plan string, relax domain.SecurityRelaxations) (_ ports.SecurityProfile, err error) {
+	ctx, span := tracing.Start(ctx, "HardeningService.Profile", attribute.String("plan", plan))
+	defer func() { tracing.End(span, err) }()
+
+	policy, err := s.policyFor(ctx, plan)
+	if err != nil {
+		return ports.SecurityProfile{}, err
+	}
+	return s.mergeProfile(policy, relax)
+}
+
+// mergeProfile profil ketat platform ditambah pelonggaran project yang diizinkan policy
+func (s *HardeningService) mergeProfile(policy *domain.SecurityPolicy, relax domain.SecurityRelaxations) (ports.SecurityProfile, error) {
+	if err := checkRelaxations(policy, relax); err != nil {
+		return ports.SecurityProfile{}, err
+	}
+
+	profile := ports.SecurityProfile{
+		NoNewPrivileges: true,
+		CapDrop:         []string{"ALL"},
+		CapAdd:          relax.AddCapabilities,
+		ReadOnlyRootfs:  !relax.WritableRootfs,
+		Tmpfs:           map[string]string{"/tmp": defaultTmpfsOptions},
+		User:            nonRootUser,
+		PidsLimit:       defaultPidsLimit,
+		SeccompProfile:  s.seccomp,
+	}
+	if relax.RunAsImageUser {
+		profile.User = ""
+	}
+	if policy.PidsLimit > 0 {
+		profile.PidsLimit = policy.PidsLimit
+	}
+	return profile, nil
+}
+
+// CheckRelaxations validasi pelonggaran project terhadap plan tenant (dipakai saat tenant mengubahnya)
+func (s *HardeningService) CheckRelaxations(ctx context.Context, plan string, relax domain.SecurityRelaxations) error {
+	policy, err := s.policyFor(ctx, plan)
+	if err != nil {
+		return err
+	}
+	return checkRelaxations(policy, relax)
+}
+
+// policyFor plan tanpa SecurityPolicy tidak boleh memilih pelonggaran apa pun
+func (s *HardeningService) policyFor(ctx context.Context, plan string) (*domain.SecurityPolicy, error) {
+	var policy domain.SecurityPolicy
+	err := s.db.WithContext(ctx).Where("plan = ?", plan).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &domain.SecurityPolicy{Plan: plan}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func checkRelaxations(policy *domain.SecurityPolicy, relax domain.SecurityRelaxations) error {
+	if relax.WritableRootfs && !policy.AllowWritableRootfs {
+		return fmt.Errorf("%w: writable root filesystem (plan %q)", ErrRelaxationNotAllowed, policy.Plan)
+	}
+	if relax.RunAsImageUser && !policy.AllowImageUser {
+		return fmt.Errorf("%w: running as the image user (plan %q)", ErrRelaxationNotAllowed, policy.Plan)
+	}
+	for _, capability := range relax.AddCapabilities {
+		if !slices.Contains(policy.AllowedCapabilities, capability) {
+			return fmt.Errorf("%w: capability %s (plan %q)", ErrRelaxationNotAllowed, capability, policy.Plan)
+		}
+	}
+	return nil
+}
+
+// normalizeCapabilities huruf besar tanpa prefix CAP_, hanya capability yang dikenal
+func normalizeCapabilities(caps []string) ([]string, error) {
+	out := make([]string, 0, len(caps))
+	for _, c := range caps {
+		name := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(c)), "CAP_")
+		if !slices.Contains(knownCapabilities, name) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCapability, c)
+		}
+		if !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
+
+func securityPolicySnapshot(p *domain.SecurityPolicy) map[string]any {
+	return map[string]any{
+		"allow_writable_rootfs": p.AllowWritableRootfs,
+		"allow_image_user":      p.AllowImageUser,
+		"allowed_capabilities":  p.AllowedCapabilities,
+		"pids_limit":            p.PidsLimit,
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+)
+
+func TestHardeningMergeProfile(t *testing.T) {
+	const seccomp = `{"defaultAction":"SCMP_ACT_ERRNO"}`
+	strict := func() ports.SecurityProfile {
+		return ports.SecurityProfile{
+			NoNewPrivileges: true,
+			CapDrop:         []string{"ALL"},
+			ReadOnlyRootfs:  true,
+			Tmpfs:           map[string]string{"/tmp": defaultTmpfsOptions},
+			User:            nonRootUser,
+			PidsLimit:       defaultPidsLimit,
+			SeccompProfile:  seccomp,
+		}
+	}
+	permissive := &domain.SecurityPolicy{
+		Plan:                "pro",
+		AllowWritableRootfs: true,
+		AllowImageUser:      true,
+		AllowedCapabilities: []string{"NET_BIND_SERVICE", "CHOWN"},
+		PidsLimit:           1024,
+	}
+	none := &domain.SecurityPolicy{Plan: "free"}
+
+	tests := []struct {
+		name    string
+		policy  *domain.SecurityPolicy
+		relax   domain.SecurityRelaxations
+		want    func(p *ports.SecurityProfile)
+		wantErr bool
+	}{
+		{name: "no relaxations under the strictest plan", policy: none},
+		{name: "policy pids limit overrides the default", policy: permissive, want: func(p *ports.SecurityProfile) { p.PidsLimit = 1024 }},
+		{
+			name:   "allowed relaxations are applied",
+			policy: permissive,
+			relax:  domain.SecurityRelaxations{WritableRootfs: true, RunAsImageUser: true, AddCapabilities: []string{"NET_BIND_SERVICE"}},
+			want: func(p *ports.SecurityProfile) {
+				p.ReadOnlyRootfs = false
+				p.User = ""
+				p.CapAdd = []string{"NET_BIND_SERVICE"}
+				p.PidsLimit = 1024
+			},
+		},
+		{name: "writable rootfs not allowed", policy: none, relax: domain.SecurityRelaxations{WritableRootfs: true}, wantErr: true},
+		{name: "image user not allowed", policy: none, relax: domain.SecurityRelaxations{RunAsImageUser: true}, wantErr: true},
+		{name: "capability outside the allow list", policy: permissive, relax: domain.SecurityRelaxations{AddCapabilities: []string{"NET_BIND_SERVICE", "SYS_ADMIN"}}, wantErr: true},
+	}
+
+	svc := NewHardeningService(nil, nil, seccomp)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.mergeProfile(tt.policy, tt.relax)
+			if tt.wantErr {
+				if !errors.Is(err, ErrRelaxationNotAllowed) {
+					t.Fatalf("err = %v, want ErrRelaxationNotAllowed", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strict()
+			if tt.want != nil {
+				tt.want(&want)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("profile =\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCapabilities(t *testing.T) {
+	tests := []struct {
+		in      []string
+		want    []string
+		wantErr bool
+	}{
+		{in: nil, want: []string{}},
+		{in: []string{"cap_net_bind_service", " CHOWN ", "NET_BIND_SERVICE"}, want: []string{"NET_BIND_SERVICE", "CHOWN"}},
+		{in: []string{"CAP_SYS_ADMIN"}, want: []string{"SYS_ADMIN"}},
+		{in: []string{"ALL"}, wantErr: true},
+		{in: []string{"NET_BIND_SERVICE", "MADE_UP"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeCapabilities(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidCapability) {
+				t.Errorf("normalizeCapabilities(%v) err = %v, want ErrInvalidCapability", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalizeCapabilities(%v) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthCheck satu dependency yang dicek saat readiness (mis. postgres, docker)
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthService menyimpan status readiness server dan menjalankan cek dependency
+type HealthService struct {
+	checks   []HealthCheck
+	timeout  time.Duration
+	ready    atomic.Bool
+	migrated atomic.Bool // tidak di-reset saat shutdown supaya request yang sedang drain tetap dilayani
+}
+
+func NewHealthService(timeout time.Duration, checks ...HealthCheck) *HealthService {
+	return &HealthService{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// MarkReady dipanggil setelah migrasi selesai; sebelum itu readiness selalu gagal
+func (s *HealthService) MarkReady() {
+	s.migrated.Store(true)
+	s.ready.Store(true)
+}
+
+// Migrated true setelah MarkReady pertama; sebelum itu API selain probe belum boleh dilayani
+func (s *HealthService) Migrated() bool {
+	return s.migrated.Load()
+}
+
+// MarkNotReady dipanggil saat shutdown supaya load balancer berhenti mengirim traffic
+func (s *HealthService) MarkNotReady() {
+	s.ready.Store(false)
+}
+
+// Readiness menjalankan semua cek secara paralel dengan timeout per cek.
+// Mengembalikan hasil per dependency ("ok" atau pesan error).
+func (s *HealthService) Readiness(ctx context.Context) (bool, map[string]string) {
+	results := make(map[string]string, len(s.checks)+1)
+	ok := s.ready.Load()
+	if ok {
+		results["migrations"] = "ok"
+	} else {
+		results["migrations"] = "pending"
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range s.checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, s.timeout)
+			defer cancel()
+
+			status := "ok"
+			if err := check.Check(checkCtx); err != nil {
+				status = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[check.Name] = status
+			if status != "ok" {
+				ok = false
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return ok, results
+}
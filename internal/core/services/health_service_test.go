@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthServiceReadiness(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name      string
+		ready     bool
+		checks    []HealthCheck
+		wantReady bool
+		want      map[string]string
+	}{
+		{
+			name:      "migrations pending",
+			checks:    []HealthCheck{{Name: "postgres", Check: ok}},
+			wantReady: false,
+			want:      map[string]string{"migrations": "pending", "postgres": "ok"},
+		},
+		{
+			name:      "all dependencies up",
+			ready:     true,
+			checks:    []HealthCheck{{Name: "postgres", Check: ok}, {Name: "docker", Check: ok}},
+			wantReady: true,
+			want:      map[string]string{"migrations": "ok", "postgres": "ok", "docker": "ok"},
+		},
+		{
+			name:      "one dependency down",
+			ready:     true,
+			checks:    []HealthCheck{{Name: "postgres", Check: ok}, {Name: "docker", Check: down}},
+			wantReady: false,
+			want:      map[string]string{"migrations": "ok", "postgres": "ok", "docker": "connection refused"},
+		},
+		{
+			name:      "hanging check times out",
+			ready:     true,
+			checks:    []HealthCheck{{Name: "docker", Check: hang}},
+			wantReady: false,
+			want:      map[string]string{"migrations": "ok", "docker": context.DeadlineExceeded.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewHealthService(20*time.Millisecond, tt.checks...)
+			if tt.ready {
+				svc.MarkReady()
+			}
+
+			ready, results := svc.Readiness(context.Background())
+			if ready != tt.wantReady {
+				t.Fatalf("ready = %v, want %v (%v)", ready, tt.wantReady, results)
+			}
+			if len(results) != len(tt.want) {
+				t.Fatalf("results = %v, want %v", results, tt.want)
+			}
+			for name, status := range tt.want {
+				if results[name] != status {
+					t.Fatalf("%s = %q, want %q", name, results[name], status)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthServiceMarkNotReady(t *testing.T) {
+	svc := NewHealthService(time.Second)
+	if svc.Migrated() {
+		t.Fatal("migrated before MarkReady")
+	}
+	svc.MarkReady()
+	if ready, _ := svc.Readiness(context.Background()); !ready {
+		t.Fatal("not ready after MarkReady")
+	}
+	svc.MarkNotReady()
+	if ready, _ := svc.Readiness(context.Background()); ready {
+		t.Fatal("still ready after MarkNotReady")
+	}
+	// Request yang masih masuk selama shutdown tetap dilayani
+	if !svc.Migrated() {
+		t.Fatal("migrated reset by MarkNotReady")
+	}
+}
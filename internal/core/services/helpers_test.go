@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB database Postgres dari TEST_DATABASE_URL dengan schema baru per test (dihapus saat test selesai).
+// Test di-skip jika TEST_DATABASE_URL tidak di-set.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema)), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.AutoMigrate(&domain.User{}, &domain.Project{}, &domain.EnvVar{}, &domain.Deployment{}, &domain.Session{}, &domain.APIToken{},
+		&domain.Organization{}, &domain.Membership{}, &domain.Invitation{}, &domain.UserIdentity{},
+		&domain.RecoveryCode{}, &domain.EmailToken{}, &domain.RegistryCredential{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// withSearchPath menambahkan search_path ke DSN (format URL maupun key=value)
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
+
+// memoryAuditRepo ports.AuditRepository di memory supaya test tidak butuh tabel audit
+type memoryAuditRepo struct {
+	mu     sync.Mutex
+	events []domain.AuditEvent
+}
+
+func (r *memoryAuditRepo) Append(ctx context.Context, event *domain.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *memoryAuditRepo) List(ctx context.Context, filter ports.AuditFilter) ([]domain.AuditEvent, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]domain.AuditEvent, len(r.events))
+	copy(out, r.events)
+	return out, int64(len(out)), nil
+}
+
+// newTestAuthService AuthService dengan key ephemeral; db boleh nil untuk test yang tidak menyentuh database
+func newTestAuthService(t *testing.T, db *gorm.DB, mailer ports.Mailer) *AuthService {
+	t.Helper()
+	keys, err := GenerateKeySet()
+	if err != nil {
+		t.Fatalf("generate keys: %v", err)
+	}
+	return NewAuthService(db, keys, []byte("test-secret"), mailer, "https://app.test", NewAuditService(&memoryAuditRepo{}))
+}
+
+// testPassword password semua user dari createTestUser
+const testPassword = "correct-horse-42"
+
+// createTestUser user dengan password testPassword (bcrypt cost minimum supaya test cepat)
+func createTestUser(t *testing.T, db *gorm.DB, username, email string, verified bool) *domain.User {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user := &domain.User{
+		Username:      username,
+		Email:         email,
+		PasswordHash:  string(hash),
+		EmailVerified: verified,
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
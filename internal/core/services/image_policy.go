@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
+	"github.com/distribution/reference"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrImageRejected       = errors.New("image rejected by policy")
+	ErrInvalidPlan         = errors.New("invalid plan name (lowercase letters, digits and dashes, max 30 characters)")
+	ErrInvalidImagePolicy  = errors.New("invalid image policy")
+	ErrImagePolicyNotFound = errors.New("image policy not found")
+)
+
+var planPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,29}$`)
+
+// ImagePolicyService kelola policy image platform/plan (oleh platform admin) dan mengevaluasinya saat deploy
+type ImagePolicyService struct {
+	db    *gorm.DB
+	audit *AuditService
+}
+
+func NewImagePolicyService(db *gorm.DB, audit *AuditService) *ImagePolicyService {
+	return &ImagePolicyService{db: db, audit: audit}
+}
+
+type ImagePolicyInput struct {
+	AllowedRegistries   []string
+	BlockedRepositories []string
+	RequireDigest       bool
+	MaxImageSizeMB      int64
+}
+
+func (s *ImagePolicyService) ListPolicies(ctx context.Context) ([]domain.ImagePolicy, error) {
+	var policies []domain.ImagePolicy
+	err := s.db.WithContext(ctx).Order("plan").Find(&policies).Error
+	return policies, err
+}
+
+// PutPolicy membuat atau mengganti policy. plan kosong = policy seluruh platform.
+func (s *ImagePolicyService) PutPolicy(ctx context.Context, adminID uuid.UUID, plan string, input ImagePolicyInput) (policy *domain.ImagePolicy, err error) {
+	var before *domain.ImagePolicy
+	defer func() {
+		entry := AuditEntry{Action: AuditAdminImagePolicy, TargetType: "image_policy", TargetID: planTarget(plan), Err: err}
+		if before != nil {
+			entry.Before = imagePolicySnapshot(before)
+		}
+		if policy != nil {
+			entry.After = imagePolicySnapshot(policy)
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	if plan != "" && !planPattern.MatchString(plan) {
+		return nil, ErrInvalidPlan
+	}
+	if input.MaxImageSizeMB < 0 {
+		return nil, fmt.Errorf("%w: max image size must not be negative", ErrInvalidImagePolicy)
+	}
+	registries := make([]string, 0, len(input.AllowedRegistries))
+	for _, r := range input.AllowedRegistries {
+		host, err := normalizeRegistry(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: allowed registry %q: %w", ErrInvalidImagePolicy, r, err)
+		}
+		registries = append(registries, host)
+	}
+	blocked := make([]string, 0, len(input.BlockedRepositories))
+	for _, r := range input.BlockedRepositories {
+		pattern, err := normalizeRepositoryPattern(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: blocked repository %q: %w", ErrInvalidImagePolicy, r, err)
+		}
+		blocked = append(blocked, pattern)
+	}
+
+	var existing domain.ImagePolicy
+	err = s.db.WithContext(ctx).Where("plan = ?", plan).First(&existing).Error
+	switch {
+	case err == nil:
+		before = &domain.ImagePolicy{}
+		*before = existing
+		policy = &existing
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		policy = &domain.ImagePolicy{ID: uuid.New(), Plan: plan}
+	default:
+		return nil, err
+	}
+
+	policy.AllowedRegistries = registries
+	policy.BlockedRepositories = blocked
+	policy.RequireDigest = input.RequireDigest
+	policy.MaxImageSizeMB = input.MaxImageSizeMB
+	policy.UpdatedBy = adminID
+	if err := s.db.WithContext(ctx).Save(policy).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (s *ImagePolicyService) DeletePolicy(ctx context.Context, plan string) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditAdminImagePolicyDelete, TargetType: "image_policy", TargetID: planTarget(plan), Err: err})
+	}()
+
+	res := s.db.WithContext(ctx).Where("plan = ?", plan).Delete(&domain.ImagePolicy{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrImagePolicyNotFound
+	}
+	return nil
+}
+
+// ResolvedImagePolicy gabungan policy platform dan policy plan tenant
+type ResolvedImagePolicy struct {
+	policies []domain.ImagePolicy
+}
+
+// Resolve mengambil policy platform + policy plan. Tanpa policy sama sekali semua image lolos.
+func (s *ImagePolicyService) Resolve(ctx context.Context, plan string) (_ *ResolvedImagePolicy, err error) {
+	ctx, span := tracing.Start(ctx, "ImagePolicyService.Resolve", attribute.String("plan", plan))
+	defer func() { tracing.End(span, err) }()
+
+	var policies []domain.ImagePolicy
+	if err := s.db.WithContext(ctx).Where("plan = '' OR plan = ?", plan).Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return &ResolvedImagePolicy{policies: policies}, nil
+}
+
+// CheckReference dievaluasi sebelum image di-pull: registry, repository yang diblokir, dan digest
+func (p *ResolvedImagePolicy) CheckReference(image string) error {
+	if len(p.policies) == 0 {
+		return nil
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("%w: invalid image reference %q", ErrImageRejected, image)
+	}
+	registry := reference.Domain(named)
+	_, pinned := named.(reference.Canonical)
+
+	for _, policy := range p.policies {
+		if len(policy.AllowedRegistries) > 0 && !slices.Contains(policy.AllowedRegistries, registry) {
+			return fmt.Errorf("%w: registry %s is not allowed%s (allowed: %s)", ErrImageRejected, registry, policyScope(policy), strings.Join(policy.AllowedRegistries, ", "))
+		}
+		for _, pattern := range policy.BlockedRepositories {
+			if ok, _ := path.Match(pattern, named.Name()); ok {
+				return fmt.Errorf("%w: repository %s is blocked%s", ErrImageRejected, reference.FamiliarName(named), policyScope(policy))
+			}
+		}
+		if policy.RequireDigest && !pinned {
+			return fmt.Errorf("%w: image must be pinned to a digest%s (e.g. %s@sha256:...)", ErrImageRejected, policyScope(policy), reference.FamiliarName(named))
+		}
+	}
+	return nil
+}
+
+// CheckSize dievaluasi setelah image tersedia di host
+func (p *ResolvedImagePolicy) CheckSize(size int64) error {
+	for _, policy := range p.policies {
+		if policy.MaxImageSizeMB > 0 && size > policy.MaxImageSizeMB<<20 {
+			return fmt.Errorf("%w: image size %d MB exceeds the limit of %d MB%s", ErrImageRejected, (size+(1<<20)-1)>>20, policy.MaxImageSizeMB, policyScope(policy))
+		}
+	}
+	return nil
+}
+
+// normalizeRepositoryPattern menyamakan nama pendek ("xmrig/xmrig", "docker") dengan nama lengkap
+// yang dipakai saat evaluasi ("docker.io/xmrig/xmrig", "docker.io/library/docker")
+func normalizeRepositoryPattern(pattern string) (string, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return "", errors.New("empty pattern")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", err
+	}
+	first, _, hasSlash := strings.Cut(pattern, "/")
+	if !hasSlash {
+		return dockerHubRegistry + "/library/" + pattern, nil
+	}
+	if !strings.ContainsAny(first, ".:") && first != "localhost" {
+		return dockerHubRegistry + "/" + pattern, nil
+	}
+	return pattern, nil
+}
+
+func policyScope(policy domain.ImagePolicy) string {
+	if policy.Plan == "" {
+		return ""
+	}
+	return fmt.Sprintf(" for plan %q", policy.Plan)
+}
+
+func planTarget(plan string) string {
+	if plan == "" {
+		return "platform"
+	}
+	return plan
+}
+
+func imagePolicySnapshot(p *domain.ImagePolicy) map[string]any {
+	return map[string]any{
+		"allowed_registries":   p.AllowedRegistries,
+		"blocked_repositories": p.BlockedRepositories,
+		"require_digest":       p.RequireDigest,
+		"max_image_size_mb":    p.MaxImageSizeMB,
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+)
+
+const testDigest = "sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestResolvedImagePolicyCheckReference(t *testing.T) {
+	platform := domain.ImagePolicy{BlockedRepositories: []string{"docker.io/xmrig/*", "docker.io/library/docker"}}
+	registries := domain.ImagePolicy{Plan: "free", AllowedRegistries: []string{"docker.io", "ghcr.io"}}
+	digest := domain.ImagePolicy{Plan: "strict", RequireDigest: true}
+
+	tests := []struct {
+		name     string
+		policies []domain.ImagePolicy
+		image    string
+		wantErr  string // kosong = lolos
+	}{
+		{name: "no policies", image: "anything:latest"},
+		{name: "no policies skips parsing", image: "Not A Reference"},
+		{name: "allowed image", policies: []domain.ImagePolicy{platform}, image: "nginx:alpine"},
+		{name: "blocked repository by wildcard", policies: []domain.ImagePolicy{platform}, image: "xmrig/xmrig:latest", wantErr: "repository xmrig/xmrig is blocked"},
+		{name: "blocked official image by short name", policies: []domain.ImagePolicy{platform}, image: "docker:dind", wantErr: "repository docker is blocked"},
+		{name: "blocked official image by full name", policies: []domain.ImagePolicy{platform}, image: "docker.io/library/docker:dind", wantErr: "is blocked"},
+		{name: "wildcard does not cross path segments", policies: []domain.ImagePolicy{platform}, image: "xmrig/xmrig/sub:latest"},
+		{name: "invalid reference", policies: []domain.ImagePolicy{platform}, image: "nginx:bad tag", wantErr: "invalid image reference"},
+		{name: "allowed registry", policies: []domain.ImagePolicy{registries}, image: "ghcr.io/acme/app:1"},
+		{name: "docker hub short name counts as docker.io", policies: []domain.ImagePolicy{registries}, image: "redis"},
+		{name: "registry not allowed", policies: []domain.ImagePolicy{registries}, image: "quay.io/acme/app:1", wantErr: `registry quay.io is not allowed for plan "free"`},
+		{name: "digest required but tag given", policies: []domain.ImagePolicy{digest}, image: "nginx:alpine", wantErr: "must be pinned to a digest"},
+		{name: "digest given", policies: []domain.ImagePolicy{digest}, image: "nginx@" + testDigest},
+		{name: "tag and digest given", policies: []domain.ImagePolicy{digest}, image: "nginx:alpine@" + testDigest},
+		{name: "platform and plan both apply", policies: []domain.ImagePolicy{platform, registries}, image: "ghcr.io/xmrig/xmrig"},
+		{name: "platform block wins over plan allow", policies: []domain.ImagePolicy{platform, registries}, image: "xmrig/xmrig", wantErr: "is blocked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&ResolvedImagePolicy{policies: tt.policies}).CheckReference(tt.image)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckReference(%q) = %v, want nil", tt.image, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrImageRejected) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CheckReference(%q) = %v, want ErrImageRejected containing %q", tt.image, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolvedImagePolicyCheckSize(t *testing.T) {
+	const mb = 1 << 20
+	platform := domain.ImagePolicy{MaxImageSizeMB: 500}
+	free := domain.ImagePolicy{Plan: "free", MaxImageSizeMB: 100}
+	unlimited := domain.ImagePolicy{Plan: "pro"}
+
+	tests := []struct {
+		name     string
+		policies []domain.ImagePolicy
+		size     int64
+		wantErr  string
+	}{
+		{name: "no policies", size: 10 << 30},
+		{name: "zero means unlimited", policies: []domain.ImagePolicy{unlimited}, size: 10 << 30},
+		{name: "exactly at the limit", policies: []domain.ImagePolicy{free}, size: 100 * mb},
+		{name: "one byte over", policies: []domain.ImagePolicy{free}, size: 100*mb + 1, wantErr: `image size 101 MB exceeds the limit of 100 MB for plan "free"`},
+		{name: "strictest policy applies", policies: []domain.ImagePolicy{platform, free}, size: 200 * mb, wantErr: "limit of 100 MB"},
+		{name: "platform limit applies to unlimited plan", policies: []domain.ImagePolicy{platform, unlimited}, size: 600 * mb, wantErr: "exceeds the limit of 500 MB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&ResolvedImagePolicy{policies: tt.policies}).CheckSize(tt.size)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckSize(%d) = %v, want nil", tt.size, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrImageRejected) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CheckSize(%d) = %v, want ErrImageRejected containing %q", tt.size, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeRepositoryPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{pattern: "docker", want: "docker.io/library/docker"},
+		{pattern: " XMRig/* ", want: "docker.io/xmrig/*"},
+		{pattern: "ghcr.io/acme/*", want: "ghcr.io/acme/*"},
+		{pattern: "localhost/app", want: "localhost/app"},
+		{pattern: "registry:5000/app", want: "registry:5000/app"},
+		{pattern: "", wantErr: true},
+		{pattern: "bad[pattern", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeRepositoryPattern(tt.pattern)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("normalizeRepositoryPattern(%q) = %q, %v; want %q (error %v)", tt.pattern, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateImageNameReservesBuildNamespace(t *testing.T) {
+	tests := []struct {
+		image   string
+		wantErr bool
+	}{
+		{image: ""},
+		{image: "nginx:alpine"},
+		{image: "ghcr.io/mth/app:1"},
+		{image: "mthx/app"},
+		{image: "mth/0b9f6b1e-3f1a-4a47-9d6f-3c1b2a7e8d90:build-0123456789ab", wantErr: true},
+		{image: "docker.io/mth/anything", wantErr: true},
+		{image: "index.docker.io/mth/anything:latest", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := validateImageName(tt.image)
+		if tt.wantErr != errors.Is(err, ErrReservedImage) || (!tt.wantErr && err != nil) {
+			t.Errorf("validateImageName(%q) = %v, want reserved=%v", tt.image, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateImageNameRejectsImageIDs(t *testing.T) {
+	tests := []struct {
+		image   string
+		wantErr bool
+	}{
+		{image: "nginx@sha256:" + strings.Repeat("a", 64)},
+		{image: "ghcr.io/acme/web:1"},
+		{image: "sha256:" + strings.Repeat("a", 64), wantErr: true},
+		{image: "sha256:4f5e", wantErr: true},
+		{image: strings.Repeat("a", 64), wantErr: true},
+		{image: "4f5e1c2d3b4a", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := validateImageName(tt.image)
+		if tt.wantErr != errors.Is(err, ErrImageIDReference) || (!tt.wantErr && err != nil) {
+			t.Errorf("validateImageName(%q) = %v, want image ID error %v", tt.image, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+)
+
+var ErrRateLimited = errors.New("too many requests, please try again later")
+
+// RateLimitPolicy batas request per key (mis. per IP) dalam satu window
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// ThrottleConfig lockout eksponensial: setelah MaxFailures gagal berturut-turut,
+// username dikunci BaseLockout, lalu dua kali lipat untuk setiap kegagalan berikutnya (maks MaxLockout).
+type ThrottleConfig struct {
+	MaxFailures   int
+	FailureWindow time.Duration
+	BaseLockout   time.Duration
+	MaxLockout    time.Duration
+}
+
+func DefaultThrottleConfig() ThrottleConfig {
+	return ThrottleConfig{
+		MaxFailures:   5,
+		FailureWindow: time.Hour,
+		BaseLockout:   30 * time.Second,
+		MaxLockout:    15 * time.Minute,
+	}
+}
+
+// LoginThrottle rate limit per IP dan proteksi brute-force per username di atas RateLimitStore.
+// Jika store error, request tetap diizinkan (fail open) supaya DB bermasalah tidak mengunci semua user.
+type LoginThrottle struct {
+	store ports.RateLimitStore
+	cfg   ThrottleConfig
+}
+
+func NewLoginThrottle(store ports.RateLimitStore, cfg ThrottleConfig) *LoginThrottle {
+	return &LoginThrottle{store: store, cfg: cfg}
+}
+
+// Allow menghitung satu request untuk key; return ErrRateLimited + sisa waktu jika limit terlampaui
+func (t *LoginThrottle) Allow(ctx context.Context, policy RateLimitPolicy, key string) (time.Duration, error) {
+	count, resetAt, err := t.store.Increment(ctx, "rl:"+policy.Name+":"+key, policy.Window)
+	if err != nil {
+		slog.WarnContext(ctx, "rate limit store unavailable", slog.String("policy", policy.Name), slog.Any("error", err))
+		return 0, nil
+	}
+	if count > policy.Limit {
+		return time.Until(resetAt), ErrRateLimited
+	}
+	return 0, nil
+}
+
+// CheckLockout return ErrRateLimited jika username sedang dikunci karena terlalu banyak login gagal
+func (t *LoginThrottle) CheckLockout(ctx context.Context, username string) (time.Duration, error) {
+	until, err := t.store.LockedUntil(ctx, lockKey(username))
+	if err != nil {
+		slog.WarnContext(ctx, "rate limit store unavailable", slog.Any("error", err))
+		return 0, nil
+	}
+	if until.IsZero() {
+		return 0, nil
+	}
+	return time.Until(until), ErrRateLimited
+}
+
+// LoginFailed mencatat kegagalan dan mengunci username jika sudah melewati batas.
+// Return durasi lockout (0 jika belum dikunci).
+func (t *LoginThrottle) LoginFailed(ctx context.Context, username string) time.Duration {
+	failures, _, err := t.store.Increment(ctx, failureKey(username), t.cfg.FailureWindow)
+	if err != nil {
+		slog.WarnContext(ctx, "rate limit store unavailable", slog.Any("error", err))
+		return 0
+	}
+	if failures < t.cfg.MaxFailures {
+		return 0
+	}
+
+	lockout := t.lockoutFor(failures)
+	if err := t.store.Lock(ctx, lockKey(username), time.Now().Add(lockout)); err != nil {
+		slog.WarnContext(ctx, "rate limit store unavailable", slog.Any("error", err))
+		return 0
+	}
+	slog.WarnContext(ctx, "login locked after repeated failures",
+		slog.String("username", username), slog.Int("failures", failures), slog.Duration("lockout", lockout))
+	return lockout
+}
+
+// LoginSucceeded mereset counter kegagalan username
+func (t *LoginThrottle) LoginSucceeded(ctx context.Context, username string) {
+	if err := t.store.Reset(ctx, failureKey(username), lockKey(username)); err != nil {
+		slog.WarnContext(ctx, "rate limit store unavailable", slog.Any("error", err))
+	}
+}
+
+func (t *LoginThrottle) lockoutFor(failures int) time.Duration {
+	lockout := t.cfg.BaseLockout
+	for i := t.cfg.MaxFailures; i < failures && lockout < t.cfg.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > t.cfg.MaxLockout {
+		lockout = t.cfg.MaxLockout
+	}
+	return lockout
+}
+
+func failureKey(username string) string {
+	return "login-fail:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func lockKey(username string) string {
+	return "login-lock:" + strings.ToLower(strings.TrimSpace(username))
+}
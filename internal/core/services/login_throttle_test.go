@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/ratelimit"
+)
+
+func TestLoginThrottleAllow(t *testing.T) {
+	ctx := context.Background()
+	throttle := NewLoginThrottle(ratelimit.NewMemoryStore(), DefaultThrottleConfig())
+	policy := RateLimitPolicy{Name: "login", Limit: 2, Window: time.Minute}
+
+	for i := 1; i <= 2; i++ {
+		if _, err := throttle.Allow(ctx, policy, "10.0.0.1"); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	retryAfter, err := throttle.Allow(ctx, policy, "10.0.0.1")
+	if !errors.Is(err, ErrRateLimited) || retryAfter <= 0 || retryAfter > time.Minute {
+		t.Fatalf("Allow over limit = (%v, %v), want ErrRateLimited within the window", retryAfter, err)
+	}
+	if _, err := throttle.Allow(ctx, policy, "10.0.0.2"); err != nil {
+		t.Fatalf("other IP limited: %v", err)
+	}
+	if _, err := throttle.Allow(ctx, RateLimitPolicy{Name: "register", Limit: 2, Window: time.Minute}, "10.0.0.1"); err != nil {
+		t.Fatalf("other policy limited: %v", err)
+	}
+}
+
+func TestLoginThrottleLockoutBackoff(t *testing.T) {
+	ctx := context.Background()
+	throttle := NewLoginThrottle(ratelimit.NewMemoryStore(), ThrottleConfig{
+		MaxFailures:   3,
+		FailureWindow: time.Hour,
+		BaseLockout:   time.Second,
+		MaxLockout:    4 * time.Second,
+	})
+
+	tests := []struct {
+		failure     int
+		wantLockout time.Duration
+	}{
+		{failure: 1, wantLockout: 0},
+		{failure: 2, wantLockout: 0},
+		{failure: 3, wantLockout: time.Second},
+		{failure: 4, wantLockout: 2 * time.Second},
+		{failure: 5, wantLockout: 4 * time.Second},
+		{failure: 6, wantLockout: 4 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := throttle.LoginFailed(ctx, "alice"); got != tt.wantLockout {
+			t.Fatalf("failure %d: lockout = %v, want %v", tt.failure, got, tt.wantLockout)
+		}
+	}
+}
+
+func TestLoginThrottleNormalizesUsername(t *testing.T) {
+	ctx := context.Background()
+	throttle := NewLoginThrottle(ratelimit.NewMemoryStore(), ThrottleConfig{
+		MaxFailures:   2,
+		FailureWindow: time.Hour,
+		BaseLockout:   time.Minute,
+		MaxLockout:    time.Hour,
+	})
+
+	throttle.LoginFailed(ctx, "Alice")
+	if lockout := throttle.LoginFailed(ctx, " alice "); lockout != time.Minute {
+		t.Fatalf("variants of the same username counted separately: lockout = %v", lockout)
+	}
+	for _, username := range []string{"alice", "ALICE", " Alice"} {
+		if _, err := throttle.CheckLockout(ctx, username); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("CheckLockout(%q) = %v, want ErrRateLimited", username, err)
+		}
+	}
+	if _, err := throttle.CheckLockout(ctx, "bob"); err != nil {
+		t.Fatalf("unrelated user locked: %v", err)
+	}
+
+	throttle.LoginSucceeded(ctx, "ALICE")
+	if _, err := throttle.CheckLockout(ctx, "alice"); err != nil {
+		t.Fatalf("lockout not cleared after success: %v", err)
+	}
+	if lockout := throttle.LoginFailed(ctx, "alice"); lockout != 0 {
+		t.Fatalf("failure counter not reset after success: lockout = %v", lockout)
+	}
+}
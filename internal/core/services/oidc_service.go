@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// oidcStateTTL batas waktu user menyelesaikan login di identity provider
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	ErrOIDCDisabled      = errors.New("single sign-on is not configured")
+	ErrOIDCState         = errors.New("invalid or expired login state")
+	ErrOIDCEmailRequired = errors.New("identity provider did not return a verified email")
+	ErrOIDCDomain        = errors.New("email domain is not allowed")
+
+	ErrOIDCAccountUnverified = errors.New("an account with this email exists but its email is not verified; verify it before signing in with SSO")
+)
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// OIDCConfig konfigurasi authorization-code flow (dengan PKCE)
+type OIDCConfig struct {
+	IssuerURL      string
+	ClientID       string
+	ClientSecret   string
+	RedirectURL    string
+	AllowedDomains []string // kosong = semua domain diizinkan
+	Scopes         []string // default: openid, profile, email
+
+	// HTTPClient opsional, mis. untuk mock provider lokal dengan TLS self-signed
+	HTTPClient *http.Client
+}
+
+// OIDCLoginState data yang harus dibawa browser dari /oidc/login ke /oidc/callback (lewat cookie)
+type OIDCLoginState struct {
+	State     string `json:"s"`
+	Nonce     string `json:"n"`
+	Verifier  string `json:"v"`
+	ExpiresAt int64  `json:"e"`
+}
+
+type OIDCService struct {
+	db      *gorm.DB
+	authSvc *AuthService
+	cfg     OIDCConfig
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	verifier *oidc.IDTokenVerifier
+	oauth    *oauth2.Config
+}
+
+func NewOIDCService(db *gorm.DB, authSvc *AuthService, cfg OIDCConfig) *OIDCService {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	return &OIDCService{db: db, authSvc: authSvc, cfg: cfg}
+}
+
+// Enabled true jika issuer dan client ID sudah dikonfigurasi
+func (s *OIDCService) Enabled() bool {
+	return s.cfg.IssuerURL != "" && s.cfg.ClientID != ""
+}
+
+// init melakukan discovery provider secara lazy supaya server tetap bisa start
+// walaupun identity provider sedang tidak bisa dihubungi
+func (s *OIDCService) init(ctx context.Context) error {
+	if !s.Enabled() {
+		return ErrOIDCDisabled
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider != nil {
+		return nil
+	}
+
+	provider, err := oidc.NewProvider(s.clientContext(ctx), s.cfg.IssuerURL)
+	if err != nil {
+		return fmt.Errorf("oidc discovery failed: %w", err)
+	}
+
+	s.provider = provider
+	s.verifier = provider.Verifier(&oidc.Config{ClientID: s.cfg.ClientID})
+	s.oauth = &oauth2.Config{
+		ClientID:     s.cfg.ClientID,
+		ClientSecret: s.cfg.ClientSecret,
+		RedirectURL:  s.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       s.cfg.Scopes,
+	}
+	return nil
+}
+
+func (s *OIDCService) clientContext(ctx context.Context) context.Context {
+	if s.cfg.HTTPClient != nil {
+		return oidc.ClientContext(ctx, s.cfg.HTTPClient)
+	}
+	return ctx
+}
+
+// BeginLogin membuat URL redirect ke identity provider beserta state yang harus disimpan di cookie
+func (s *OIDCService) BeginLogin(ctx context.Context) (string, *OIDCLoginState, error) {
+	if err := s.init(ctx); err != nil {
+		return "", nil, err
+	}
+
+	state, err := generateOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	loginState := &OIDCLoginState{
+		State:     state,
+		Nonce:     nonce,
+		Verifier:  oauth2.GenerateVerifier(),
+		ExpiresAt: time.Now().Add(oidcStateTTL).Unix(),
+	}
+
+	url := s.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(loginState.Verifier))
+	return url, loginState, nil
+}
+
+// CompleteLogin menukar authorization code, memverifikasi ID token, lalu login/provision user.
+// User dengan 2FA aktif tetap harus menyelesaikan challenge TOTP seperti login password.
+func (s *OIDCService) CompleteLogin(ctx context.Context, loginState *OIDCLoginState, state, code string, client ClientInfo) (result *LoginResult, err error) {
+	var user *domain.User
+	defer func() {
+		entry := AuditEntry{Action: AuditLogin, TargetType: "user", Metadata: map[string]any{"method": "oidc"}, Err: err}
+		if user != nil {
+			entry.UserID = &user.ID
+			entry.TargetID = user.ID.String()
+		}
+		if result != nil {
+			entry.Metadata["mfa_required"] = result.MFARequired
+		}
+		s.authSvc.audit.Record(ctx, entry)
+	}()
+
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+	if loginState == nil || time.Now().Unix() > loginState.ExpiresAt ||
+		!hmac.Equal([]byte(loginState.State), []byte(state)) {
+		return nil, ErrOIDCState
+	}
+
+	token, err := s.oauth.Exchange(s.clientContext(ctx), code, oauth2.VerifierOption(loginState.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	idToken, err := s.verifier.Verify(s.clientContext(ctx), rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if !hmac.Equal([]byte(idToken.Nonce), []byte(loginState.Nonce)) {
+		return nil, ErrOIDCState
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     bool   `json:"email_verified"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailRequired
+	}
+	if !s.domainAllowed(claims.Email) {
+		return nil, ErrOIDCDomain
+	}
+
+	user, err = s.resolveUser(ctx, idToken.Issuer, idToken.Subject, strings.ToLower(claims.Email), claims.PreferredUsername)
+	if err != nil {
+		return nil, err
+	}
+	return s.authSvc.finishLogin(ctx, user, client)
+}
+
+func (s *OIDCService) domainAllowed(email string) bool {
+	if len(s.cfg.AllowedDomains) == 0 {
+		return true
+	}
+	_, domainPart, ok := strings.Cut(email, "@")
+	if !ok {
+		return false
+	}
+	for _, allowed := range s.cfg.AllowedDomains {
+		if strings.EqualFold(domainPart, strings.TrimSpace(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveUser urutan: identity yang sudah ter-link -> user lokal dengan email sama (link) -> user baru (JIT)
+func (s *OIDCService) resolveUser(ctx context.Context, issuer, subject, email, preferredUsername string) (*domain.User, error) {
+	var user domain.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var identity domain.UserIdentity
+		err := tx.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+		if err == nil {
+			return tx.First(&user, "id = ?", identity.UserID).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// Account linking berdasarkan email terverifikasi dari provider.
+		// Akun lokal yang emailnya belum diverifikasi tidak di-link: bisa saja didaftarkan orang lain dengan email korban.
+		err = tx.Where("LOWER(email) = ?", email).First(&user).Error
+		if err == nil && !user.EmailVerified {
+			return ErrOIDCAccountUnverified
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			now := time.Now()
+			user = domain.User{
+				Username:        s.uniqueUsername(tx, preferredUsername, email),
+				Email:           email,
+				PasswordHash:    "!", // bukan hash bcrypt valid: login password tidak mungkin berhasil
+				EmailVerified:   true,
+				EmailVerifiedAt: &now,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			if _, err := createPersonalOrganization(tx, &user); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		return tx.Create(&domain.UserIdentity{
+			UserID:  user.ID,
+			Issuer:  issuer,
+			Subject: subject,
+			Email:   email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// uniqueUsername membuat username dari preferred_username / local part email, tambah suffix jika bentrok
+func (s *OIDCService) uniqueUsername(tx *gorm.DB, preferred, email string) string {
+	base := preferred
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		tx.Model(&domain.User{}).Where("LOWER(username) = LOWER(?)", candidate).Count(&count)
+		if count == 0 {
+			return candidate
+		}
+		candidate = base + "-" + uuid.NewString()[:4]
+	}
+	return base + "-" + uuid.NewString()[:8]
+}
+
+// EncodeState menandatangani state login (HMAC dengan secret JWT) untuk disimpan di cookie
+func (s *OIDCService) EncodeState(state *OIDCLoginState) (string, error) {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded), nil
+}
+
+// DecodeState memverifikasi tanda tangan cookie state
+func (s *OIDCService) DecodeState(value string) (*OIDCLoginState, error) {
+	encoded, sig, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(encoded))) {
+		return nil, ErrOIDCState
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrOIDCState
+	}
+	var state OIDCLoginState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return nil, ErrOIDCState
+	}
+	return &state, nil
+}
+
+func (s *OIDCService) sign(data string) string {
+	mac := hmac.New(sha256.New, s.authSvc.secretKey)
+	mac.Write([]byte("oidc-state:" + data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/mailer"
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+const testClientID = "mth-test"
+
+// fakeIssuer identity provider minimal: discovery, JWKS dan token endpoint dengan validasi PKCE
+type fakeIssuer struct {
+	t   *testing.T
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+
+	// claims untuk ID token berikutnya; nonce diisi dari authorization request kecuali di-override
+	subject       string
+	email         string
+	emailVerified bool
+	nonce         string
+}
+
+type authRequest struct {
+	nonce     string
+	challenge string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	f := &fakeIssuer{t: t, key: key, codes: make(map[string]authRequest), subject: "sub-1", email: "alice@example.com", emailVerified: true}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                f.srv.URL,
+			"authorization_endpoint":                f.srv.URL + "/authorize",
+			"token_endpoint":                        f.srv.URL + "/token",
+			"jwks_uri":                              f.srv.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "idp-1",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", f.token)
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// authorize mensimulasikan user login di provider lalu diarahkan balik dengan code
+func (f *fakeIssuer) authorize(authURL string) (state, code string) {
+	f.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		f.t.Fatalf("parse auth url: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		f.t.Fatalf("auth url without S256 PKCE challenge: %s", authURL)
+	}
+	if q.Get("nonce") == "" {
+		f.t.Fatalf("auth url without nonce: %s", authURL)
+	}
+
+	code = "code-" + q.Get("state")
+	f.mu.Lock()
+	f.codes[code] = authRequest{nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	f.mu.Unlock()
+	return q.Get("state"), code
+}
+
+func (f *fakeIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	req, ok := f.codes[r.PostForm.Get("code")]
+	delete(f.codes, r.PostForm.Get("code"))
+	f.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := req.nonce
+	if f.nonce != "" {
+		nonce = f.nonce
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            f.srv.URL,
+		"aud":            testClientID,
+		"sub":            f.subject,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"nonce":          nonce,
+		"email":          f.email,
+		"email_verified": f.emailVerified,
+	})
+	token.Header["kid"] = "idp-1"
+	idToken, err := token.SignedString(f.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]any{"access_token": "at", "token_type": "Bearer", "expires_in": 60, "id_token": idToken})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTestOIDCService(t *testing.T, db *gorm.DB, issuer *fakeIssuer, allowedDomains ...string) *OIDCService {
+	t.Helper()
+	return NewOIDCService(db, newTestAuthService(t, db, mailer.NewMemoryMailer()), OIDCConfig{
+		IssuerURL:      issuer.srv.URL,
+		ClientID:       testClientID,
+		ClientSecret:   "secret",
+		RedirectURL:    "https://app.test/oidc/callback",
+		AllowedDomains: allowedDomains,
+	})
+}
+
+// login menjalankan satu putaran BeginLogin -> authorize -> CompleteLogin; mutate boleh mengubah state sebelum callback
+func login(t *testing.T, svc *OIDCService, issuer *fakeIssuer, mutate func(state *OIDCLoginState, returnedState *string)) (*LoginResult, error) {
+	t.Helper()
+	ctx := context.Background()
+	authURL, loginState, err := svc.BeginLogin(ctx)
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	state, code := issuer.authorize(authURL)
+	if mutate != nil {
+		mutate(loginState, &state)
+	}
+	return svc.CompleteLogin(ctx, loginState, state, code, ClientInfo{})
+}
+
+func TestOIDCCompleteLoginRejects(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowedDomains []string
+		issuer         func(f *fakeIssuer)
+		mutate         func(state *OIDCLoginState, returnedState *string)
+		wantErr        error
+		wantMsg        string
+	}{
+		{
+			name:    "state mismatch",
+			mutate:  func(_ *OIDCLoginState, returned *string) { *returned = "forged" },
+			wantErr: ErrOIDCState,
+		},
+		{
+			name:    "expired state",
+			mutate:  func(s *OIDCLoginState, _ *string) { s.ExpiresAt = time.Now().Add(-time.Second).Unix() },
+			wantErr: ErrOIDCState,
+		},
+		{
+			name:    "wrong PKCE verifier",
+			mutate:  func(s *OIDCLoginState, _ *string) { s.Verifier = oauth2.GenerateVerifier() },
+			wantMsg: "invalid_grant",
+		},
+		{
+			name:    "nonce mismatch",
+			issuer:  func(f *fakeIssuer) { f.nonce = "replayed-nonce" },
+			wantErr: ErrOIDCState,
+		},
+		{
+			name:    "unverified email",
+			issuer:  func(f *fakeIssuer) { f.emailVerified = false },
+			wantErr: ErrOIDCEmailRequired,
+		},
+		{
+			name:           "disallowed domain",
+			allowedDomains: []string{"corp.example"},
+			issuer:         func(f *fakeIssuer) { f.email = "mallory@evil.example" },
+			wantErr:        ErrOIDCDomain,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newFakeIssuer(t)
+			if tt.issuer != nil {
+				tt.issuer(issuer)
+			}
+			// Semua kasus ditolak sebelum user dicari, jadi tidak perlu database
+			svc := newTestOIDCService(t, nil, issuer, tt.allowedDomains...)
+
+			result, err := login(t, svc, issuer, tt.mutate)
+			if err == nil {
+				t.Fatalf("login succeeded: %+v", result)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Fatalf("err = %v, want it to mention %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestOIDCDecodeStateRejectsTampering(t *testing.T) {
+	svc := NewOIDCService(nil, newTestAuthService(t, nil, mailer.NewMemoryMailer()), OIDCConfig{})
+	encoded, err := svc.EncodeState(&OIDCLoginState{State: "s", Nonce: "n", Verifier: "v", ExpiresAt: time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.DecodeState(encoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	forged, _ := json.Marshal(OIDCLoginState{State: "s", Nonce: "attacker", Verifier: "v", ExpiresAt: time.Now().Add(time.Minute).Unix()})
+	_, sig, _ := strings.Cut(encoded, ".")
+	if _, err := svc.DecodeState(base64.RawURLEncoding.EncodeToString(forged) + "." + sig); !errors.Is(err, ErrOIDCState) {
+		t.Fatalf("forged state: err = %v, want ErrOIDCState", err)
+	}
+}
+
+func TestOIDCJITProvisioning(t *testing.T) {
+	db := testDB(t)
+	issuer := newFakeIssuer(t)
+	svc := newTestOIDCService(t, db, issuer, "example.com")
+
+	result, err := login(t, svc, issuer, nil)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if result.Tokens == nil || result.MFARequired {
+		t.Fatalf("result = %+v, want session tokens", result)
+	}
+
+	var user domain.User
+	if err := db.First(&user, "email = ?", "alice@example.com").Error; err != nil {
+		t.Fatalf("user not provisioned: %v", err)
+	}
+	if !user.EmailVerified || user.Username != "alice" {
+		t.Fatalf("provisioned user = %+v", user)
+	}
+	var memberships int64
+	db.Model(&domain.Membership{}).Where("user_id = ? AND role = ?", user.ID, domain.RoleOwner).Count(&memberships)
+	if memberships != 1 {
+		t.Fatalf("personal organization memberships = %d, want 1", memberships)
+	}
+
+	// Login kedua dengan subject yang sama memakai identity yang sudah ter-link
+	if _, err := login(t, svc, issuer, nil); err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	var users, identities int64
+	db.Model(&domain.User{}).Count(&users)
+	db.Model(&domain.UserIdentity{}).Where("user_id = ?", user.ID).Count(&identities)
+	if users != 1 || identities != 1 {
+		t.Fatalf("users = %d, identities = %d, want 1 and 1", users, identities)
+	}
+}
+
+func TestOIDCLinksOnlyVerifiedEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		verified bool
+		wantErr  error
+	}{
+		{name: "verified local account is linked", verified: true},
+		{name: "unverified local account is not linked", verified: false, wantErr: ErrOIDCAccountUnverified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			local := createTestUser(t, db, "alice-local", "Alice@Example.com", tt.verified)
+			issuer := newFakeIssuer(t)
+			svc := newTestOIDCService(t, db, issuer)
+
+			_, err := login(t, svc, issuer, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			var identity domain.UserIdentity
+			linkErr := db.First(&identity, "subject = ?", issuer.subject).Error
+			if tt.wantErr != nil {
+				if !errors.Is(linkErr, gorm.ErrRecordNotFound) {
+					t.Fatalf("identity created for unverified account: %+v", identity)
+				}
+				return
+			}
+			if linkErr != nil || identity.UserID != local.ID {
+				t.Fatalf("identity = %+v (%v), want linked to %s", identity, linkErr, local.ID)
+			}
+		})
+	}
+}
+
+func TestOIDCLoginRequiresTOTPChallenge(t *testing.T) {
+	db := testDB(t)
+	user := createTestUser(t, db, "alice", "alice@example.com", true)
+	if err := db.Model(user).Updates(map[string]any{"totp_enabled": true, "totp_secret": "JBSWY3DPEHPK3PXP"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	issuer := newFakeIssuer(t)
+	svc := newTestOIDCService(t, db, issuer)
+
+	result, err := login(t, svc, issuer, nil)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if !result.MFARequired || result.Tokens != nil || result.ChallengeToken == "" {
+		t.Fatalf("result = %+v, want TOTP challenge without tokens", result)
+	}
+}
@@ -0,0 +1,501 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrNotMember       = errors.New("you are not a member of this organization")
+	ErrForbidden       = errors.New("insufficient role for this action")
+	ErrLastOwner       = errors.New("organization must keep at least one owner")
+	ErrInvalidRole     = errors.New("invalid role (owner, admin, developer, viewer)")
+	ErrInvitationEmail = errors.New("invitation was sent to a different email")
+)
+
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+type OrganizationService struct {
+	db    *gorm.DB
+	audit *AuditService
+}
+
+func NewOrganizationService(db *gorm.DB, audit *AuditService) *OrganizationService {
+	return &OrganizationService{db: db, audit: audit}
+}
+
+// MemberView data member untuk response API (tanpa field sensitif User)
+type MemberView struct {
+	UserID   uuid.UUID
+	Username string
+	Email    string
+	Role     domain.Role
+	JoinedAt time.Time
+}
+
+// OrganizationView organization beserta role user yang sedang login
+type OrganizationView struct {
+	domain.Organization
+	Role domain.Role
+}
+
+func (s *OrganizationService) CreateOrganization(ctx context.Context, ownerID uuid.UUID, name string) (_ *domain.Organization, err error) {
+	org := &domain.Organization{
+		Name: name,
+		Slug: slugify(name) + "-" + uuid.NewString()[:6],
+	}
+	defer func() {
+		entry := AuditEntry{Action: AuditOrgCreate, TargetType: "organization", Metadata: map[string]any{"name": name}, Err: err}
+		if err == nil {
+			entry.TargetID = org.ID.String()
+			entry.OrganizationID = &org.ID
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("organization name is required")
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&domain.Membership{OrganizationID: org.ID, UserID: ownerID, Role: domain.RoleOwner}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// ListForUser mengembalikan semua organization tempat user menjadi member
+func (s *OrganizationService) ListForUser(ctx context.Context, userID uuid.UUID) ([]OrganizationView, error) {
+	var rows []struct {
+		domain.Organization
+		Role domain.Role
+	}
+	if err := s.db.WithContext(ctx).Model(&domain.Organization{}).
+		Select("organizations.*, memberships.role").
+		Joins("JOIN memberships ON memberships.organization_id = organizations.id").
+		Where("memberships.user_id = ?", userID).
+		Order("organizations.personal DESC, organizations.name ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	views := make([]OrganizationView, 0, len(rows))
+	for _, row := range rows {
+		views = append(views, OrganizationView{Organization: row.Organization, Role: row.Role})
+	}
+	return views, nil
+}
+
+// OrganizationIDsForUser dipakai untuk listing project lintas organization
+func (s *OrganizationService) OrganizationIDsForUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := s.db.WithContext(ctx).Model(&domain.Membership{}).Where("user_id = ?", userID).Pluck("organization_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// PersonalOrganization organization default user, dipakai saat membuat project tanpa organization_id
+func (s *OrganizationService) PersonalOrganization(ctx context.Context, userID uuid.UUID) (*domain.Organization, error) {
+	var org domain.Organization
+	if err := s.db.WithContext(ctx).
+		Joins("JOIN memberships ON memberships.organization_id = organizations.id").
+		Where("memberships.user_id = ? AND memberships.role = ? AND organizations.personal = ?", userID, domain.RoleOwner, true).
+		First(&org).Error; err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// MemberRole role user di organization, ErrNotMember jika bukan member
+func (s *OrganizationService) MemberRole(ctx context.Context, orgID, userID uuid.UUID) (domain.Role, error) {
+	var m domain.Membership
+	if err := s.db.WithContext(ctx).Where("organization_id = ? AND user_id = ?", orgID, userID).First(&m).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrNotMember
+		}
+		return "", err
+	}
+	return m.Role, nil
+}
+
+// Authorize memastikan user punya minimal role tertentu di organization,
+// dan sudah mengaktifkan 2FA jika organization mewajibkannya
+func (s *OrganizationService) Authorize(ctx context.Context, orgID, userID uuid.UUID, min domain.Role) (domain.Role, error) {
+	role, err := s.MemberRole(ctx, orgID, userID)
+	if err != nil {
+		return "", err
+	}
+	if !role.AtLeast(min) {
+		return role, ErrForbidden
+	}
+
+	var org domain.Organization
+	if err := s.db.WithContext(ctx).Select("id", "require_2fa").First(&org, "id = ?", orgID).Error; err != nil {
+		return "", err
+	}
+	if org.Require2FA {
+		var user domain.User
+		if err := s.db.WithContext(ctx).Select("id", "totp_enabled").First(&user, "id = ?", userID).Error; err != nil {
+			return "", err
+		}
+		if !user.TOTPEnabled {
+			return role, ErrTwoFactorRequired
+		}
+	}
+	return role, nil
+}
+
+// UpdateOrganizationInput field yang boleh diubah (nil = tidak diubah)
+type UpdateOrganizationInput struct {
+	Name       *string
+	Require2FA *bool
+}
+
+// UpdateOrganization mengubah nama / kebijakan 2FA. Actor harus sudah 2FA sebelum mewajibkannya,
+// supaya admin tidak mengunci dirinya sendiri.
+func (s *OrganizationService) UpdateOrganization(ctx context.Context, orgID, actorID uuid.UUID, input UpdateOrganizationInput) (_ *domain.Organization, err error) {
+	var org domain.Organization
+	var before map[string]any
+	defer func() {
+		entry := AuditEntry{Action: AuditOrgUpdate, TargetType: "organization", TargetID: orgID.String(), OrganizationID: &orgID, Before: before, Err: err}
+		if err == nil {
+			entry.After = map[string]any{"name": org.Name, "require_2fa": org.Require2FA}
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	if err := s.db.WithContext(ctx).First(&org, "id = ?", orgID).Error; err != nil {
+		return nil, err
+	}
+	before = map[string]any{"name": org.Name, "require_2fa": org.Require2FA}
+
+	updates := map[string]interface{}{}
+	if input.Name != nil {
+		if strings.TrimSpace(*input.Name) == "" {
+			return nil, fmt.Errorf("organization name is required")
+		}
+		updates["name"] = *input.Name
+	}
+	if input.Require2FA != nil {
+		if *input.Require2FA {
+			var actor domain.User
+			if err := s.db.WithContext(ctx).First(&actor, "id = ?", actorID).Error; err != nil {
+				return nil, err
+			}
+			if !actor.TOTPEnabled {
+				return nil, ErrTwoFactorRequired
+			}
+		}
+		updates["require_2fa"] = *input.Require2FA
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.WithContext(ctx).Model(&org).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &org, nil
+}
+
+func (s *OrganizationService) ListMembers(ctx context.Context, orgID uuid.UUID) ([]MemberView, error) {
+	var members []MemberView
+	if err := s.db.WithContext(ctx).Model(&domain.Membership{}).
+		Select("memberships.user_id, users.username, users.email, memberships.role, memberships.created_at AS joined_at").
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.organization_id = ?", orgID).
+		Order("memberships.created_at ASC").
+		Scan(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// UpdateMemberRole mengubah role member. Hanya owner yang boleh memberi/mencabut role owner.
+func (s *OrganizationService) UpdateMemberRole(ctx context.Context, orgID, actorID, memberID uuid.UUID, role domain.Role) (err error) {
+	var previous domain.Role
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{
+			Action:         AuditOrgMemberRole,
+			TargetType:     "user",
+			TargetID:       memberID.String(),
+			OrganizationID: &orgID,
+			Before:         map[string]any{"role": previous},
+			After:          map[string]any{"role": role},
+			Err:            err,
+		})
+	}()
+
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		actor, target, err := s.actorAndTarget(tx, orgID, actorID, memberID)
+		if err != nil {
+			return err
+		}
+		previous = target.Role
+		if (role == domain.RoleOwner || target.Role == domain.RoleOwner) && actor.Role != domain.RoleOwner {
+			return ErrForbidden
+		}
+		if target.Role == domain.RoleOwner && role != domain.RoleOwner {
+			if err := ensureAnotherOwner(tx, orgID, memberID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(target).Update("role", role).Error
+	})
+}
+
+// RemoveMember mengeluarkan member (atau keluar sendiri jika actorID == memberID)
+func (s *OrganizationService) RemoveMember(ctx context.Context, orgID, actorID, memberID uuid.UUID) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditOrgMemberRemove, TargetType: "user", TargetID: memberID.String(), OrganizationID: &orgID, Err: err})
+	}()
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		actor, target, err := s.actorAndTarget(tx, orgID, actorID, memberID)
+		if err != nil && !(errors.Is(err, ErrForbidden) && actorID == memberID) {
+			return err
+		}
+		if target.Role == domain.RoleOwner {
+			if actor.Role != domain.RoleOwner {
+				return ErrForbidden
+			}
+			if err := ensureAnotherOwner(tx, orgID, memberID); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(target).Error
+	})
+}
+
+// actorAndTarget memuat membership actor (minimal admin) dan member yang dituju
+func (s *OrganizationService) actorAndTarget(tx *gorm.DB, orgID, actorID, memberID uuid.UUID) (*domain.Membership, *domain.Membership, error) {
+	var actor, target domain.Membership
+	if err := tx.Where("organization_id = ? AND user_id = ?", orgID, actorID).First(&actor).Error; err != nil {
+		return nil, nil, ErrNotMember
+	}
+	if err := tx.Where("organization_id = ? AND user_id = ?", orgID, memberID).First(&target).Error; err != nil {
+		return nil, nil, fmt.Errorf("member not found")
+	}
+	if !actor.Role.AtLeast(domain.RoleAdmin) {
+		return &actor, &target, ErrForbidden
+	}
+	return &actor, &target, nil
+}
+
+func ensureAnotherOwner(tx *gorm.DB, orgID, exceptUserID uuid.UUID) error {
+	var owners int64
+	if err := tx.Model(&domain.Membership{}).
+		Where("organization_id = ? AND role = ? AND user_id <> ?", orgID, domain.RoleOwner, exceptUserID).
+		Count(&owners).Error; err != nil {
+		return err
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// Invite membuat undangan dan mengembalikan token mentah (hanya sekali)
+func (s *OrganizationService) Invite(ctx context.Context, orgID, actorID uuid.UUID, email string, role domain.Role) (inv *domain.Invitation, _ string, err error) {
+	defer func() {
+		entry := AuditEntry{Action: AuditOrgInvite, TargetType: "invitation", OrganizationID: &orgID, Metadata: map[string]any{"email": email, "role": role}, Err: err}
+		if inv != nil {
+			entry.TargetID = inv.ID.String()
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	if !role.Valid() {
+		return nil, "", ErrInvalidRole
+	}
+	actorRole, err := s.Authorize(ctx, orgID, actorID, domain.RoleAdmin)
+	if err != nil {
+		return nil, "", err
+	}
+	if role == domain.RoleOwner && actorRole != domain.RoleOwner {
+		return nil, "", ErrForbidden
+	}
+
+	raw, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	inv = &domain.Invitation{
+		OrganizationID: orgID,
+		Email:          strings.ToLower(strings.TrimSpace(email)),
+		Role:           role,
+		TokenHash:      hashToken(raw),
+		InvitedBy:      actorID,
+		ExpiresAt:      time.Now().Add(invitationTTL),
+	}
+	if err := s.db.WithContext(ctx).Create(inv).Error; err != nil {
+		return nil, "", err
+	}
+	return inv, raw, nil
+}
+
+func (s *OrganizationService) ListInvitations(ctx context.Context, orgID uuid.UUID) ([]domain.Invitation, error) {
+	var invs []domain.Invitation
+	if err := s.db.WithContext(ctx).
+		Where("organization_id = ? AND accepted_at IS NULL AND expires_at > ?", orgID, time.Now()).
+		Order("created_at DESC").
+		Find(&invs).Error; err != nil {
+		return nil, err
+	}
+	return invs, nil
+}
+
+func (s *OrganizationService) RevokeInvitation(ctx context.Context, orgID, invitationID uuid.UUID) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditOrgInviteRevoke, TargetType: "invitation", TargetID: invitationID.String(), OrganizationID: &orgID, Err: err})
+	}()
+
+	return s.db.WithContext(ctx).
+		Where("id = ? AND organization_id = ? AND accepted_at IS NULL", invitationID, orgID).
+		Delete(&domain.Invitation{}).Error
+}
+
+// AcceptInvitation menjadikan user member sesuai undangan. Email user harus sama dengan email undangan.
+func (s *OrganizationService) AcceptInvitation(ctx context.Context, userID uuid.UUID, rawToken string) (_ *domain.Membership, err error) {
+	var membership *domain.Membership
+	var inv domain.Invitation
+	defer func() {
+		entry := AuditEntry{Action: AuditOrgInvitationAccept, TargetType: "invitation", Err: err}
+		if inv.ID != uuid.Nil {
+			entry.TargetID = inv.ID.String()
+			entry.OrganizationID = &inv.OrganizationID
+			entry.Metadata = map[string]any{"role": inv.Role}
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND accepted_at IS NULL AND expires_at > ?", hashToken(rawToken), time.Now()).First(&inv).Error; err != nil {
+			return ErrInvalidToken
+		}
+
+		var user domain.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		if !strings.EqualFold(user.Email, inv.Email) {
+			return ErrInvitationEmail
+		}
+		// Undangan dicocokkan lewat email, jadi email harus terbukti milik user
+		if !user.EmailVerified {
+			return ErrEmailNotVerified
+		}
+
+		now := time.Now()
+		if err := tx.Model(&inv).Update("accepted_at", now).Error; err != nil {
+			return err
+		}
+
+		var existing domain.Membership
+		err := tx.Where("organization_id = ? AND user_id = ?", inv.OrganizationID, userID).First(&existing).Error
+		if err == nil {
+			// Sudah member: jangan turunkan role yang sudah ada
+			if inv.Role.AtLeast(existing.Role) {
+				existing.Role = inv.Role
+				if err := tx.Save(&existing).Error; err != nil {
+					return err
+				}
+			}
+			membership = &existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		membership = &domain.Membership{OrganizationID: inv.OrganizationID, UserID: userID, Role: inv.Role}
+		return tx.Create(membership).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return membership, nil
+}
+
+// ListAudit audit log milik satu organization
+func (s *OrganizationService) ListAudit(ctx context.Context, orgID uuid.UUID, filter ports.AuditFilter) ([]domain.AuditEvent, int64, error) {
+	filter.OrganizationID = &orgID
+	return s.audit.List(ctx, filter)
+}
+
+// MigratePersonalOrganizations membuat personal organization untuk user lama yang belum punya,
+// lalu memindahkan project lama (yang belum punya organization_id) ke organization tersebut.
+// Idempotent, aman dijalankan setiap startup.
+func (s *OrganizationService) MigratePersonalOrganizations(ctx context.Context) error {
+	var users []domain.User
+	if err := s.db.WithContext(ctx).
+		Where("NOT EXISTS (SELECT 1 FROM memberships m JOIN organizations o ON o.id = m.organization_id WHERE m.user_id = users.id AND o.personal = ?)", true).
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	for i := range users {
+		if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			_, err := createPersonalOrganization(tx, &users[i])
+			return err
+		}); err != nil {
+			return fmt.Errorf("personal organization for %s: %w", users[i].Username, err)
+		}
+	}
+
+	// Project lama: pindahkan ke personal organization pembuatnya
+	return s.db.WithContext(ctx).Exec(`
+		UPDATE projects SET organization_id = o.id
+		FROM memberships m JOIN organizations o ON o.id = m.organization_id
+		WHERE projects.organization_id IS NULL
+		  AND m.user_id = projects.user_id AND m.role = ? AND o.personal = ?`, domain.RoleOwner, true).Error
+}
+
+// createPersonalOrganization dipakai saat registrasi dan migrasi user lama
+func createPersonalOrganization(tx *gorm.DB, user *domain.User) (*domain.Organization, error) {
+	org := &domain.Organization{
+		Name:     user.Username,
+		Slug:     slugify(user.Username) + "-" + uuid.NewString()[:6],
+		Personal: true,
+	}
+	if err := tx.Create(org).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&domain.Membership{OrganizationID: org.ID, UserID: user.ID, Role: domain.RoleOwner}).Error; err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func slugify(name string) string {
+	slug := slugInvalidChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+	slug = strings.Trim(slug, "-")
+	if len(slug) > 50 {
+		slug = slug[:50]
+	}
+	if slug == "" {
+		slug = "org"
+	}
+	return slug
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/google/uuid"
+)
+
+func TestAuthorizeRoleOrdering(t *testing.T) {
+	db := testDB(t)
+	svc := NewOrganizationService(db, NewAuditService(&memoryAuditRepo{}))
+	ctx := context.Background()
+
+	org := domain.Organization{Name: "team", Slug: "team"}
+	if err := db.Create(&org).Error; err != nil {
+		t.Fatal(err)
+	}
+	members := make(map[domain.Role]uuid.UUID)
+	for _, role := range []domain.Role{domain.RoleViewer, domain.RoleDeveloper, domain.RoleAdmin, domain.RoleOwner} {
+		user := createTestUser(t, db, string(role), string(role)+"@example.com", true)
+		if err := db.Create(&domain.Membership{OrganizationID: org.ID, UserID: user.ID, Role: role}).Error; err != nil {
+			t.Fatal(err)
+		}
+		members[role] = user.ID
+	}
+	outsider := createTestUser(t, db, "outsider", "outsider@example.com", true)
+
+	tests := []struct {
+		member  domain.Role
+		min     domain.Role
+		wantErr error
+	}{
+		{domain.RoleViewer, domain.RoleViewer, nil},
+		{domain.RoleViewer, domain.RoleDeveloper, ErrForbidden},
+		{domain.RoleDeveloper, domain.RoleDeveloper, nil},
+		{domain.RoleDeveloper, domain.RoleAdmin, ErrForbidden},
+		{domain.RoleAdmin, domain.RoleDeveloper, nil},
+		{domain.RoleAdmin, domain.RoleOwner, ErrForbidden},
+		{domain.RoleOwner, domain.RoleAdmin, nil},
+		{domain.RoleOwner, domain.RoleOwner, nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.member)+">="+string(tt.min), func(t *testing.T) {
+			role, err := svc.Authorize(ctx, org.ID, members[tt.member], tt.min)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if role != tt.member {
+				t.Fatalf("role = %q, want %q", role, tt.member)
+			}
+		})
+	}
+
+	t.Run("non-member", func(t *testing.T) {
+		if _, err := svc.Authorize(ctx, org.ID, outsider.ID, domain.RoleViewer); !errors.Is(err, ErrNotMember) {
+			t.Fatalf("err = %v, want ErrNotMember", err)
+		}
+	})
+
+	t.Run("organization requires 2FA", func(t *testing.T) {
+		if err := db.Model(&org).Update("require_2fa", true).Error; err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.Authorize(ctx, org.ID, members[domain.RoleDeveloper], domain.RoleViewer); !errors.Is(err, ErrTwoFactorRequired) {
+			t.Fatalf("member without 2FA: err = %v, want ErrTwoFactorRequired", err)
+		}
+		if err := db.Model(&domain.User{}).Where("id = ?", members[domain.RoleDeveloper]).Update("totp_enabled", true).Error; err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.Authorize(ctx, org.ID, members[domain.RoleDeveloper], domain.RoleViewer); err != nil {
+			t.Fatalf("member with 2FA: %v", err)
+		}
+	})
+
+}
+
+func TestMemberRoleChanges(t *testing.T) {
+	db := testDB(t)
+	svc := NewOrganizationService(db, NewAuditService(&memoryAuditRepo{}))
+	ctx := context.Background()
+
+	owner := createTestUser(t, db, "owner", "owner@example.com", true)
+	org, err := svc.CreateOrganization(ctx, owner.ID, "Team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	add := func(username string, role domain.Role) uuid.UUID {
+		user := createTestUser(t, db, username, username+"@example.com", true)
+		if err := db.Create(&domain.Membership{OrganizationID: org.ID, UserID: user.ID, Role: role}).Error; err != nil {
+			t.Fatal(err)
+		}
+		return user.ID
+	}
+	admin := add("admin", domain.RoleAdmin)
+	dev := add("dev", domain.RoleDeveloper)
+
+	if err := svc.UpdateMemberRole(ctx, org.ID, admin, dev, domain.RoleOwner); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("admin grants owner: err = %v, want ErrForbidden", err)
+	}
+	if err := svc.UpdateMemberRole(ctx, org.ID, admin, owner.ID, domain.RoleViewer); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("admin demotes owner: err = %v, want ErrForbidden", err)
+	}
+	if err := svc.UpdateMemberRole(ctx, org.ID, dev, admin, domain.RoleViewer); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("developer changes roles: err = %v, want ErrForbidden", err)
+	}
+	if err := svc.UpdateMemberRole(ctx, org.ID, owner.ID, owner.ID, domain.RoleAdmin); !errors.Is(err, ErrLastOwner) {
+		t.Fatalf("last owner steps down: err = %v, want ErrLastOwner", err)
+	}
+	if err := svc.RemoveMember(ctx, org.ID, owner.ID, owner.ID); !errors.Is(err, ErrLastOwner) {
+		t.Fatalf("last owner leaves: err = %v, want ErrLastOwner", err)
+	}
+	if err := svc.UpdateMemberRole(ctx, org.ID, admin, dev, domain.RoleViewer); err != nil {
+		t.Fatalf("admin demotes developer: %v", err)
+	}
+
+	// Member biasa boleh keluar sendiri
+	if err := svc.RemoveMember(ctx, org.ID, dev, dev); err != nil {
+		t.Fatalf("member leaves: %v", err)
+	}
+	if _, err := svc.MemberRole(ctx, org.ID, dev); !errors.Is(err, ErrNotMember) {
+		t.Fatalf("removed member: err = %v, want ErrNotMember", err)
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	db := testDB(t)
+	svc := NewOrganizationService(db, NewAuditService(&memoryAuditRepo{}))
+	ctx := context.Background()
+
+	owner := createTestUser(t, db, "owner", "owner@example.com", true)
+	org, err := svc.CreateOrganization(ctx, owner.ID, "Team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := createTestUser(t, db, "alice", "alice@example.com", true)
+	mallory := createTestUser(t, db, "mallory", "mallory@example.com", true)
+	unverified := createTestUser(t, db, "alice2", "ALICE@example.com", false)
+
+	_, raw, err := svc.Invite(ctx, org.ID, owner.ID, " Alice@Example.com ", domain.RoleDeveloper)
+	if err != nil {
+		t.Fatalf("invite: %v", err)
+	}
+	if _, err := svc.AcceptInvitation(ctx, mallory.ID, raw); !errors.Is(err, ErrInvitationEmail) {
+		t.Fatalf("accept with another email: err = %v, want ErrInvitationEmail", err)
+	}
+	// Email sama tapi belum terbukti milik user
+	if _, err := svc.AcceptInvitation(ctx, unverified.ID, raw); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("accept with unverified email: err = %v, want ErrEmailNotVerified", err)
+	}
+	membership, err := svc.AcceptInvitation(ctx, alice.ID, raw)
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if membership.Role != domain.RoleDeveloper {
+		t.Fatalf("role = %q, want developer", membership.Role)
+	}
+	if _, err := svc.AcceptInvitation(ctx, alice.ID, raw); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("second accept: err = %v, want ErrInvalidToken", err)
+	}
+
+	// Undangan dengan role lebih rendah tidak menurunkan role yang sudah ada
+	_, raw, err = svc.Invite(ctx, org.ID, owner.ID, "alice@example.com", domain.RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if membership, err = svc.AcceptInvitation(ctx, alice.ID, raw); err != nil || membership.Role != domain.RoleDeveloper {
+		t.Fatalf("re-invite as viewer: role = %v, err = %v", membership, err)
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"My Team":               "my-team",
+		"  ACME, Inc.  ":        "acme-inc",
+		"---":                   "org",
+		"":                      "org",
+		strings.Repeat("a", 60): strings.Repeat("a", 50),
+	}
+	for in, want := range tests {
+		if got := slugify(in); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
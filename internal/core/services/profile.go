@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // batas input bcrypt (byte)
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{2,49}$`)
+
+var (
+	ErrInvalidUsername = errors.New("username must be 3-50 characters: letters, digits, '_', '.', '-', starting with a letter or digit")
+	ErrWeakPassword    = errors.New("password must be 8-72 characters and contain at least one letter and one digit")
+	ErrUsernameTaken   = errors.New("username is already taken")
+	ErrEmailTaken      = errors.New("email is already registered")
+)
+
+// UpdateProfileInput field nil tidak diubah
+type UpdateProfileInput struct {
+	Username *string `json:"username"`
+	Email    *string `json:"email" binding:"omitempty,email,max=100"`
+}
+
+// GetProfile data user yang sedang login
+func (s *AuthService) GetProfile(ctx context.Context, userID uuid.UUID) (*domain.User, error) {
+	var user domain.User
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateProfile mengganti username/email. Email baru harus diverifikasi ulang.
+func (s *AuthService) UpdateProfile(ctx context.Context, userID uuid.UUID, input UpdateProfileInput) (_ *domain.User, err error) {
+	var user domain.User
+	var before map[string]any
+	defer func() {
+		entry := AuditEntry{Action: AuditProfileUpdate, TargetType: "user", TargetID: userID.String(), Before: before, Err: err}
+		if err == nil {
+			entry.After = map[string]any{"username": user.Username, "email": user.Email}
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	emailChanged := false
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		before = map[string]any{"username": user.Username, "email": user.Email}
+
+		if input.Username != nil && *input.Username != user.Username {
+			username := strings.TrimSpace(*input.Username)
+			if err := validateUsername(username); err != nil {
+				return err
+			}
+			if err := ensureUnique(tx, "LOWER(username) = LOWER(?)", username, user.ID, ErrUsernameTaken); err != nil {
+				return err
+			}
+			user.Username = username
+		}
+
+		if input.Email != nil {
+			email := strings.ToLower(strings.TrimSpace(*input.Email))
+			if !strings.EqualFold(email, user.Email) {
+				if err := ensureUnique(tx, "LOWER(email) = ?", email, user.ID, ErrEmailTaken); err != nil {
+					return err
+				}
+				user.Email = email
+				user.EmailVerified = false
+				user.EmailVerifiedAt = nil
+				emailChanged = true
+			}
+		}
+
+		return tx.Save(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if emailChanged {
+		if err := s.SendVerificationEmail(ctx, user.ID); err != nil {
+			slog.WarnContext(ctx, "failed to send verification email", slog.String("user_id", user.ID.String()), slog.Any("error", err))
+		}
+	}
+	return &user, nil
+}
+
+// ChangePassword memverifikasi password lama lalu mencabut semua session lain (session saat ini tetap login)
+func (s *AuthService) ChangePassword(ctx context.Context, userID, currentSessionID uuid.UUID, currentPassword, newPassword string) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditPasswordChange, TargetType: "user", TargetID: userID.String(), Err: err})
+	}()
+
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	var user domain.User
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password_hash", string(hashed)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&domain.Session{}).
+			Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, currentSessionID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+func validateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
+// validatePassword policy minimal: panjang 8-72 byte, ada huruf dan angka
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrWeakPassword
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return ErrWeakPassword
+	}
+	return nil
+}
+
+// ensureUnique return errTaken jika ada user lain (selain exceptID) yang cocok dengan kondisi
+func ensureUnique(tx *gorm.DB, cond string, value string, exceptID uuid.UUID, errTaken error) error {
+	var count int64
+	q := tx.Model(&domain.User{}).Where(cond, value)
+	if exceptID != uuid.Nil {
+		q = q.Where("id <> ?", exceptID)
+	}
+	if err := q.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errTaken
+	}
+	return nil
+}
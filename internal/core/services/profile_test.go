@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/mailer"
+)
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		wantErr  bool
+	}{
+		{username: "alice"},
+		{username: "a.b-c_9"},
+		{username: "abc"},
+		{username: strings.Repeat("a", 50)},
+		{username: "ab", wantErr: true},
+		{username: strings.Repeat("a", 51), wantErr: true},
+		{username: "_alice", wantErr: true},
+		{username: "alice smith", wantErr: true},
+		{username: "alice@example.com", wantErr: true},
+		{username: "ålice", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			err := validateUsername(tt.username)
+			if tt.wantErr != errors.Is(err, ErrInvalidUsername) {
+				t.Fatalf("validateUsername(%q) = %v, wantErr %v", tt.username, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{name: "letters and digits", password: "correct-horse-42"},
+		{name: "minimum length", password: "abcdefg1"},
+		{name: "maximum length", password: strings.Repeat("a", 71) + "1"},
+		{name: "too short", password: "abc1234", wantErr: true},
+		{name: "too long for bcrypt", password: strings.Repeat("a", 72) + "1", wantErr: true},
+		{name: "no digit", password: "correct-horse", wantErr: true},
+		{name: "no letter", password: "1234567890", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePassword(tt.password)
+			if tt.wantErr != errors.Is(err, ErrWeakPassword) {
+				t.Fatalf("validatePassword = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	db := testDB(t)
+	svc := newTestAuthService(t, db, mailer.NewMemoryMailer())
+	ctx := context.Background()
+	createTestUser(t, db, "alice", "alice@example.com", true)
+
+	tests := []struct {
+		name    string
+		input   RegisterInput
+		wantErr error
+	}{
+		{name: "username differs only by case", input: RegisterInput{Username: "Alice", Email: "other@example.com", Password: testPassword}, wantErr: ErrUsernameTaken},
+		{name: "email differs only by case", input: RegisterInput{Username: "bob", Email: " ALICE@example.com ", Password: testPassword}, wantErr: ErrEmailTaken},
+		{name: "invalid username", input: RegisterInput{Username: "b o b", Email: "bob@example.com", Password: testPassword}, wantErr: ErrInvalidUsername},
+		{name: "weak password", input: RegisterInput{Username: "bob", Email: "bob@example.com", Password: "password"}, wantErr: ErrWeakPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := svc.Register(ctx, tt.input); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Register = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoginUsernameIsCaseInsensitive(t *testing.T) {
+	db := testDB(t)
+	svc := newTestAuthService(t, db, mailer.NewMemoryMailer())
+	createTestUser(t, db, "alice", "alice@example.com", true)
+
+	for _, username := range []string{"alice", "Alice", " ALICE "} {
+		if _, err := svc.Login(context.Background(), username, testPassword, ClientInfo{}); err != nil {
+			t.Errorf("Login(%q) = %v, want success", username, err)
+		}
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	db := testDB(t)
+	svc := newTestAuthService(t, db, mailer.NewMemoryMailer())
+	ctx := context.Background()
+	alice := createTestUser(t, db, "alice", "alice@example.com", true)
+	createTestUser(t, db, "bob", "bob@example.com", true)
+
+	taken := "BOB"
+	if _, err := svc.UpdateProfile(ctx, alice.ID, UpdateProfileInput{Username: &taken}); !errors.Is(err, ErrUsernameTaken) {
+		t.Fatalf("rename to existing username: err = %v", err)
+	}
+	takenEmail := "Bob@Example.com"
+	if _, err := svc.UpdateProfile(ctx, alice.ID, UpdateProfileInput{Email: &takenEmail}); !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("change to existing email: err = %v", err)
+	}
+
+	// Email sama dengan beda huruf besar tidak dianggap perubahan
+	sameEmail := "ALICE@example.com"
+	user, err := svc.UpdateProfile(ctx, alice.ID, UpdateProfileInput{Email: &sameEmail})
+	if err != nil || !user.EmailVerified {
+		t.Fatalf("same email: verified = %v, err = %v", user != nil && user.EmailVerified, err)
+	}
+
+	username, email := "alice2", "alice@new.example"
+	user, err = svc.UpdateProfile(ctx, alice.ID, UpdateProfileInput{Username: &username, Email: &email})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != username || user.Email != email || user.EmailVerified {
+		t.Fatalf("profile = %s/%s verified=%v, want new unverified email", user.Username, user.Email, user.EmailVerified)
+	}
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	db := testDB(t)
+	svc := newTestAuthService(t, db, mailer.NewMemoryMailer())
+	ctx := context.Background()
+	user := createTestUser(t, db, "alice", "alice@example.com", true)
+
+	current, err := svc.createSession(ctx, user.ID, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := svc.createSession(ctx, user.ID, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := svc.ValidateToken(ctx, current.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const newPassword = "new-password-77"
+	if err := svc.ChangePassword(ctx, user.ID, claims.SessionID, "wrong-password-1", newPassword); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong current password: err = %v", err)
+	}
+	if err := svc.ChangePassword(ctx, user.ID, claims.SessionID, testPassword, "short"); !errors.Is(err, ErrWeakPassword) {
+		t.Fatalf("weak new password: err = %v", err)
+	}
+	if err := svc.ChangePassword(ctx, user.ID, claims.SessionID, testPassword, newPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.ValidateToken(ctx, current.AccessToken); err != nil {
+		t.Fatalf("current session revoked: %v", err)
+	}
+	if _, err := svc.ValidateToken(ctx, other.AccessToken); err == nil {
+		t.Fatal("other session still valid after password change")
+	}
+	if _, err := svc.Login(ctx, "alice", newPassword, ClientInfo{}); err != nil {
+		t.Fatalf("login with new password: %v", err)
+	}
+}
@@ -2,65 +2,205 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"log/slog"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
 	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/damantine/multi-tenant-hosting/internal/logging"
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
+	"github.com/distribution/reference"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+var (
+	ErrTenantSuspended      = errors.New("the owner of this project is suspended")
+	ErrProjectQuotaExceeded = errors.New("project quota exceeded")
+	ErrInvalidEnvKey        = errors.New("invalid environment variable name")
+	ErrProjectNotBuilt      = errors.New("project has no built image yet, run a build first")
+	ErrGitURLRequired       = errors.New("git repository URL is required")
+	ErrInvalidPullPolicy    = errors.New("invalid pull policy (always, if-not-present, never)")
+	ErrInvalidSourceType    = errors.New("invalid source type (image or git; upload projects are created by uploading a build)")
+	ErrReservedImage        = errors.New("images under mth/ are reserved for builds of the platform")
+	ErrNoDeployment         = errors.New("project has no deployments yet")
+	ErrImageIDReference     = errors.New("image must be referenced by name (e.g. nginx:alpine or nginx@sha256:...), not by local image ID")
+)
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// imageIDPattern image ID (lengkap atau prefix) yang diterima Docker sebagai pengganti nama image
+var imageIDPattern = regexp.MustCompile(`^[a-f0-9]+$`)
+
+// ProjectConfig pengaturan runtime deploy yang diisi dari konfigurasi server
+type ProjectConfig struct {
+	BaseDomain    string // project di-route ke <subdomain>.<BaseDomain>
+	NetworkPrefix string // network tenant = NetworkPrefix + ID organization
+}
+
 type ProjectService struct {
 	repo          ports.ProjectRepository
 	dockerRuntime ports.ContainerRuntime
+	metrics       ports.MetricsRecorder
+	tenants       ports.TenantRepository
+	registries    ports.RegistryAuthProvider
+	policies      *ImagePolicyService
+	hardening     *HardeningService
+	audit         *AuditService
+	cfg           ProjectConfig
 }
 
-func NewProjectService(repo ports.ProjectRepository, docker ports.ContainerRuntime) *ProjectService {
+func NewProjectService(repo ports.ProjectRepository, docker ports.ContainerRuntime, metrics ports.MetricsRecorder, tenants ports.TenantRepository, registries ports.RegistryAuthProvider, policies *ImagePolicyService, hardening *HardeningService, audit *AuditService, cfg ProjectConfig) *ProjectService {
 	return &ProjectService{
 		repo:          repo,
 		dockerRuntime: docker,
+		metrics:       metrics,
+		tenants:       tenants,
+		registries:    registries,
+		policies:      policies,
+		hardening:     hardening,
+		audit:         audit,
+		cfg:           cfg,
 	}
 }
 
-// DeployProject menghandle logika deployment aplikasi user
+// DeployProject menghandle logika deployment aplikasi user dan mencatat durasi serta hasilnya
 func (s *ProjectService) DeployProject(ctx context.Context, projectID uuid.UUID) (*domain.Deployment, error) {
-	// 1. Ambil data project
+	// ID deployment dibuat di awal supaya semua log (termasuk dari Docker adapter) bisa dikorelasikan
+	deploymentID := uuid.New()
+	ctx = logging.With(ctx,
+		slog.String(logging.KeyProjectID, projectID.String()),
+		slog.String(logging.KeyDeploymentID, deploymentID.String()),
+	)
+
+	ctx, span := tracing.Start(ctx, "ProjectService.DeployProject",
+		attribute.String("project.id", projectID.String()),
+		attribute.String("deployment.id", deploymentID.String()),
+	)
+
+	slog.InfoContext(ctx, "deployment started")
+	start := time.Now()
 	project, err := s.repo.GetByID(ctx, projectID)
+	var deployment *domain.Deployment
 	if err != nil {
-		return nil, fmt.Errorf("project not found: %w", err)
+		err = fmt.Errorf("project not found: %w", err)
+	} else {
+		deployment, err = s.deploy(ctx, project, deploymentID)
 	}
+	tracing.End(span, err)
 
-	// 2. Siapkan config container
-	// Ambil Base Domain dari environment variables (default: localhost)
-	baseDomain := os.Getenv("BASE_DOMAIN")
-	if baseDomain == "" {
-		baseDomain = "localhost"
+	entry := projectAudit(AuditProjectDeploy, projectID, project, err)
+	entry.Metadata = map[string]any{"deployment_id": deploymentID.String()}
+	if project != nil {
+		entry.Metadata["image"] = project.ImageName
+	}
+	if deployment != nil {
+		entry.Metadata["image_digest"] = deployment.ImageDigest
+	}
+	s.audit.Record(ctx, entry)
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+		slog.ErrorContext(ctx, "deployment failed", slog.Duration("duration", time.Since(start)), slog.Any("error", err))
+	} else {
+		slog.InfoContext(ctx, "deployment succeeded", slog.Duration("duration", time.Since(start)), slog.String("container_id", deployment.ContainerID))
 	}
+	s.metrics.ObserveDeploy(outcome, time.Since(start))
+
+	return deployment, err
+}
+
+func (s *ProjectService) deploy(ctx context.Context, project *domain.Project, deploymentID uuid.UUID) (*domain.Deployment, error) {
+	// 1. Pastikan tenant pemilik project aktif
+	if err := s.ensureTenantActive(ctx, project.OrganizationID); err != nil {
+		return nil, err
+	}
+	if project.ImageName == "" {
+		return nil, ErrProjectNotBuilt
+	}
+
+	// 2. Siapkan config container
+	baseDomain := s.cfg.BaseDomain
 
 	// Format Label Traefik v2/v3 untuk subdomain routing
 	// "traefik.http.routers.my-app.rule=Host(`subdomain.domain.com`)"
 	labels := map[string]string{
 		"traefik.enable": "true",
-		fmt.Sprintf("traefik.http.routers.%s.rule", project.Subdomain): fmt.Sprintf("Host(`%s.%s`)", project.Subdomain, baseDomain), 
+		fmt.Sprintf("traefik.http.routers.%s.rule", project.Subdomain):                      fmt.Sprintf("Host(`%s.%s`)", project.Subdomain, baseDomain),
 		fmt.Sprintf("traefik.http.services.%s.loadbalancer.server.port", project.Subdomain): fmt.Sprintf("%d", project.ContainerPort),
 	}
-	
+
 	// Convert EnvVars domain ke []string format "KEY=VALUE"
 	var envs []string
 	for _, env := range project.EnvVars {
 		envs = append(envs, fmt.Sprintf("%s=%s", env.Key, env.Value))
 	}
 
+	// 3. Cek policy image platform/plan: nama image sebelum pull, ukuran setelah image tersedia
+	plan, err := s.tenants.Plan(ctx, project.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.policies.Resolve(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+	// Image hasil build lokal bukan referensi registry, hanya ukurannya yang dicek,
+	// asalkan memang hasil build project ini sendiri (mth/<project id>:...)
+	localBuild := project.SourceType == domain.SourceTypeGit || project.SourceType == domain.SourceTypeUpload
+	if localBuild && !strings.HasPrefix(project.ImageName, localImagePrefix(project.ID)) {
+		return nil, fmt.Errorf("%w: %s is not a build of this project", ErrImageRejected, project.ImageName)
+	}
+	if !localBuild {
+		if err := validateImageName(project.ImageName); err != nil {
+			return nil, err
+		}
+		if err := policy.CheckReference(project.ImageName); err != nil {
+			return nil, err
+		}
+	}
+
+	// 4. Siapkan image sesuai pull policy; container dibuat dari digest supaya deploy bisa diulang persis sama
+	image, err := s.prepareImage(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	// Tag mth/<project id>:... bisa saja menunjuk image lain di host; label build yang menentukan pemiliknya
+	if localBuild && image.Labels[projectIDLabel] != project.ID.String() {
+		return nil, fmt.Errorf("%w: %s was not built for this project", ErrImageRejected, project.ImageName)
+	}
+	if err := policy.CheckSize(image.Size); err != nil {
+		return nil, err
+	}
+
+	// Container tenant selalu di-hardening; pelonggaran hanya yang diizinkan plan
+	security, err := s.hardening.Profile(ctx, plan, project.Security)
+	if err != nil {
+		return nil, err
+	}
+
+	// Setiap tenant punya network sendiri; Traefik memakai network ini untuk meneruskan request
+	network := s.tenantNetwork(project.OrganizationID)
+	labels["traefik.docker.network"] = network
+
 	config := ports.ContainerConfig{
-		Name:   fmt.Sprintf("%s-%s", project.Subdomain, uuid.NewString()[:8]), // Uniq name
-		Image:  project.ImageName,
-		Env:    envs,
-		Labels: labels,
-		Port:   project.ContainerPort,
+		Name:           fmt.Sprintf("%s-%s", project.Subdomain, uuid.NewString()[:8]), // Uniq name
+		Image:          image.Pinned(),
+		Env:            envs,
+		Labels:         labels,
+		Port:           project.ContainerPort,
+		Security:       security,
+		Network:        network,
+		NetworkAliases: []string{project.Subdomain},
 	}
 
-	// 3. Panggil Docker Adapter
+	// 5. Panggil Docker Adapter
 	containerID, err := s.dockerRuntime.CreateContainer(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("docker create failed: %w", err)
@@ -70,63 +210,202 @@ func (s *ProjectService) DeployProject(ctx context.Context, projectID uuid.UUID)
 		return nil, fmt.Errorf("docker start failed: %w", err)
 	}
 
-	// 4. Record deployment history
+	// 6. Record deployment history
 	deployment := &domain.Deployment{
+		ID:          deploymentID,
 		ProjectID:   project.ID,
 		ContainerID: containerID,
+		Image:       project.ImageName,
+		ImageDigest: image.Pinned(),
 		Status:      "running",
 	}
-	// Di real app, simpan deployment ke DB via repo (belum diimplementasi di interface repo contoh ini)
-	// s.repo.SaveDeployment(deployment)
-	
+	if err := s.repo.CreateDeployment(ctx, deployment); err != nil {
+		return nil, fmt.Errorf("failed to record deployment: %w", err)
+	}
+
 	// Update status project
 	project.Status = "running"
-	s.repo.Update(ctx, project)
+	if err := s.repo.Update(ctx, project); err != nil {
+		slog.WarnContext(ctx, "failed to update project status", slog.Any("error", err))
+	}
 
 	return deployment, nil
 }
 
-// CreateProject hanya menyimpan metadata ke DB
-func (s *ProjectService) CreateProject(ctx context.Context, userID uuid.UUID, name, image, subdomain string, port int) (*domain.Project, error) {
-    if strings.Contains(subdomain, " ") {
-        return nil, fmt.Errorf("subdomain cannot contain spaces")
-    }
+// prepareImage image hasil build lokal tidak pernah di-pull; image dari private registry
+// di-pull (atau, jika sudah di cache, aksesnya dicek) dengan kredensial organization pemilik project
+func (s *ProjectService) prepareImage(ctx context.Context, project *domain.Project) (*ports.ImageRef, error) {
+	if project.SourceType == domain.SourceTypeGit || project.SourceType == domain.SourceTypeUpload {
+		return s.dockerRuntime.PrepareImage(ctx, project.ImageName, domain.PullNever, nil)
+	}
 
-	project := &domain.Project{
-		UserID:        userID,
-		Name:          name,
-		ImageName:     image,
-		Subdomain:     subdomain,
-		ContainerPort: port,
-		Status:        "created",
+	policy := project.PullPolicy
+	if policy == "" {
+		policy = domain.PullIfNotPresent
+	}
+	auth, err := s.registries.AuthFor(ctx, project.OrganizationID, project.ImageName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registry credential: %w", err)
+	}
+	return s.dockerRuntime.PrepareImage(ctx, project.ImageName, policy, auth)
+}
+
+// CreateProject hanya menyimpan metadata ke DB. source nil berarti project memakai image jadi;
+// jika diisi, image dibangun dari repository Git lewat BuildService.
+func (s *ProjectService) CreateProject(ctx context.Context, userID, orgID uuid.UUID, name, image, subdomain string, port int, pullPolicy string, source *domain.GitSource) (_ *domain.Project, err error) {
+	ctx, span := tracing.Start(ctx, "ProjectService.CreateProject", attribute.String("project.subdomain", subdomain))
+	defer func() { tracing.End(span, err) }()
+
+	var project *domain.Project
+	defer func() {
+		entry := AuditEntry{Action: AuditProjectCreate, TargetType: "project", OrganizationID: &orgID, Metadata: map[string]any{"subdomain": subdomain}, Err: err}
+		if err == nil {
+			entry.TargetID = project.ID.String()
+			entry.After = projectSnapshot(project)
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	if strings.Contains(subdomain, " ") {
+		return nil, fmt.Errorf("subdomain cannot contain spaces")
+	}
+	if err := validateGitSource(source); err != nil {
+		return nil, err
+	}
+	if source == nil {
+		if err := validateImageName(image); err != nil {
+			return nil, err
+		}
+	}
+	if pullPolicy == "" {
+		pullPolicy = domain.PullIfNotPresent
+	}
+	if !domain.ValidPullPolicy(pullPolicy) {
+		return nil, ErrInvalidPullPolicy
+	}
+
+	// Kuota dan status suspend milik tenant (owner organization), bukan member yang membuat project
+	if err := s.ensureTenantActive(ctx, orgID); err != nil {
+		return nil, err
+	}
+	maxProjects, err := s.tenants.MaxProjects(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if maxProjects > 0 {
+		count, err := s.tenants.CountProjects(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		if count >= int64(maxProjects) {
+			return nil, fmt.Errorf("%w (limit %d)", ErrProjectQuotaExceeded, maxProjects)
+		}
+	}
+
+	project = &domain.Project{
+		UserID:         userID,
+		OrganizationID: orgID,
+		Name:           name,
+		ImageName:      image,
+		Subdomain:      subdomain,
+		ContainerPort:  port,
+		Status:         "created",
+		SourceType:     domain.SourceTypeImage,
+		PullPolicy:     pullPolicy,
+	}
+	if source != nil {
+		project.SourceType = domain.SourceTypeGit
+		project.GitSource = *source
+		project.ImageName = "" // diisi tag hasil build pertama
 	}
 
 	if err := s.repo.Create(ctx, project); err != nil {
 		return nil, err
 	}
+	slog.InfoContext(logging.With(ctx, slog.String(logging.KeyProjectID, project.ID.String())), "project created", slog.String("subdomain", project.Subdomain))
 	return project, nil
 }
 
-func (s *ProjectService) ListProjects(ctx context.Context, userID uuid.UUID) ([]domain.Project, error) {
-	return s.repo.ListByUserID(ctx, userID)
+// ListProjects mengambil project dari semua organization yang diberikan
+func (s *ProjectService) ListProjects(ctx context.Context, orgIDs []uuid.UUID) (_ []domain.Project, err error) {
+	ctx, span := tracing.Start(ctx, "ProjectService.ListProjects", attribute.Int("organization.count", len(orgIDs)))
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.ListByOrganizationIDs(ctx, orgIDs)
 }
 
-func (s *ProjectService) GetProject(ctx context.Context, projectID uuid.UUID) (*domain.Project, error) {
+func (s *ProjectService) GetProject(ctx context.Context, projectID uuid.UUID) (_ *domain.Project, err error) {
+	ctx, span := tracing.Start(ctx, "ProjectService.GetProject", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
 	return s.repo.GetByID(ctx, projectID)
 }
 
-func (s *ProjectService) UpdateProject(ctx context.Context, projectID uuid.UUID, name, image, subdomain string, port int) (*domain.Project, error) {
-	project, err := s.repo.GetByID(ctx, projectID)
+// UpdateProject source nil, pullPolicy dan sourceType kosong tidak mengubah nilai sebelumnya.
+// image hanya dipakai untuk project image; project git/upload kembali ke image hanya lewat sourceType "image".
+func (s *ProjectService) UpdateProject(ctx context.Context, projectID uuid.UUID, name, image, subdomain string, port int, pullPolicy, sourceType string, source *domain.GitSource) (_ *domain.Project, err error) {
+	ctx, span := tracing.Start(ctx, "ProjectService.UpdateProject", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var project *domain.Project
+	var before map[string]any
+	defer func() {
+		entry := projectAudit(AuditProjectUpdate, projectID, project, err)
+		entry.Before = before
+		if err == nil {
+			entry.After = projectSnapshot(project)
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	project, err = s.repo.GetByID(ctx, projectID)
 	if err != nil {
 		return nil, err
 	}
+	before = projectSnapshot(project)
+	if err := validateGitSource(source); err != nil {
+		return nil, err
+	}
+	if pullPolicy != "" && !domain.ValidPullPolicy(pullPolicy) {
+		return nil, ErrInvalidPullPolicy
+	}
+	switch sourceType {
+	case "":
+	case domain.SourceTypeImage:
+		if source != nil {
+			return nil, ErrInvalidSourceType
+		}
+	case domain.SourceTypeGit:
+		if source == nil && project.SourceType != domain.SourceTypeGit {
+			return nil, ErrGitURLRequired
+		}
+	default:
+		return nil, ErrInvalidSourceType
+	}
 
 	// Update fields
 	project.Name = name
-	project.ImageName = image
 	project.Subdomain = subdomain
 	project.ContainerPort = port
-	
+	if pullPolicy != "" {
+		project.PullPolicy = pullPolicy
+	}
+	if source != nil {
+		project.SourceType = domain.SourceTypeGit
+		project.GitSource = *source
+	}
+	if sourceType == domain.SourceTypeImage && project.SourceType != domain.SourceTypeImage {
+		project.SourceType = domain.SourceTypeImage
+		project.GitSource = domain.GitSource{}
+	}
+	// Image project git/upload hanya diganti oleh hasil build project itu sendiri
+	if project.SourceType == domain.SourceTypeImage {
+		if err := validateImageName(image); err != nil {
+			return nil, err
+		}
+		project.ImageName = image
+	}
+
 	// Reset status if critical config builds changes (optional, but good practice)
 	// For now we keep it simple.
 
@@ -136,8 +415,21 @@ func (s *ProjectService) UpdateProject(ctx context.Context, projectID uuid.UUID,
 	return project, nil
 }
 
-func (s *ProjectService) DeleteProject(ctx context.Context, projectID uuid.UUID) error {
-	project, err := s.repo.GetByID(ctx, projectID)
+func (s *ProjectService) DeleteProject(ctx context.Context, projectID uuid.UUID) (err error) {
+	ctx = logging.With(ctx, slog.String(logging.KeyProjectID, projectID.String()))
+	ctx, span := tracing.Start(ctx, "ProjectService.DeleteProject", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var project *domain.Project
+	defer func() {
+		entry := projectAudit(AuditProjectDelete, projectID, project, err)
+		if project != nil {
+			entry.Before = projectSnapshot(project)
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	project, err = s.repo.GetByID(ctx, projectID)
 	if err != nil {
 		return err
 	}
@@ -148,15 +440,20 @@ func (s *ProjectService) DeleteProject(ctx context.Context, projectID uuid.UUID)
 	// Better: Check latest deployment or loop through deployments.
 	// In this simple version, let's assume we try to cleanup resources based on potential container names or just skip if complex.
 	// Actually, we should check active deployment.
-    // Let's use List to find deployments if not loaded.
-    // Repo GetByID loads deployments.
-    
-    if len(project.Deployments) > 0 {
+	// Let's use List to find deployments if not loaded.
+	// Repo GetByID loads deployments.
+
+	if len(project.Deployments) > 0 {
 		for _, d := range project.Deployments {
-			if d.Status == "running" {
+			// Container yang sudah di-stop juga dihapus supaya network tenant bisa ikut dibersihkan
+			if d.Status != "removed" {
 				// Try to stop and remove
-				_ = s.dockerRuntime.StopContainer(ctx, d.ContainerID)
-				_ = s.dockerRuntime.RemoveContainer(ctx, d.ContainerID)
+				if err := s.dockerRuntime.StopContainer(ctx, d.ContainerID); err != nil {
+					slog.WarnContext(ctx, "failed to stop container", slog.String("container_id", d.ContainerID), slog.Any("error", err))
+				}
+				if err := s.dockerRuntime.RemoveContainer(ctx, d.ContainerID); err != nil {
+					slog.WarnContext(ctx, "failed to remove container", slog.String("container_id", d.ContainerID), slog.Any("error", err))
+				}
 			}
 		}
 	} else {
@@ -166,14 +463,38 @@ func (s *ProjectService) DeleteProject(ctx context.Context, projectID uuid.UUID)
 	}
 
 	// 2. Remove from DB
-	return s.repo.Delete(ctx, projectID)
+	if err := s.repo.Delete(ctx, projectID); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "project deleted")
+
+	// 3. Network tenant dihapus jika ini container terakhir yang memakainya (best effort)
+	if err := s.dockerRuntime.RemoveNetworkIfUnused(ctx, s.tenantNetwork(project.OrganizationID)); err != nil {
+		slog.WarnContext(ctx, "failed to remove tenant network", slog.Any("error", err))
+	}
+	return nil
 }
 
-func (s *ProjectService) StartProject(ctx context.Context, projectID uuid.UUID) error {
-	project, err := s.repo.GetByID(ctx, projectID)
+// tenantNetwork nama network Docker milik organization; container tenant lain tidak bisa menjangkaunya
+func (s *ProjectService) tenantNetwork(orgID uuid.UUID) string {
+	return s.cfg.NetworkPrefix + orgID.String()
+}
+
+func (s *ProjectService) StartProject(ctx context.Context, projectID uuid.UUID) (err error) {
+	ctx = logging.With(ctx, slog.String(logging.KeyProjectID, projectID.String()))
+	ctx, span := tracing.Start(ctx, "ProjectService.StartProject", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var project *domain.Project
+	defer func() { s.audit.Record(ctx, projectAudit(AuditProjectStart, projectID, project, err)) }()
+
+	project, err = s.repo.GetByID(ctx, projectID)
 	if err != nil {
 		return err
 	}
+	if err := s.ensureTenantActive(ctx, project.OrganizationID); err != nil {
+		return err
+	}
 
 	// Find running or stopped container
 	// For simplicity, we assume the latest deployment contains the relevant container ID
@@ -181,16 +502,29 @@ func (s *ProjectService) StartProject(ctx context.Context, projectID uuid.UUID)
 	if len(project.Deployments) == 0 {
 		return fmt.Errorf("no deployments found for this project")
 	}
-	
+
 	// Get latest deployment
 	latestDeployment := project.Deployments[len(project.Deployments)-1]
-	
+
 	// Start container
-	return s.dockerRuntime.StartContainer(ctx, latestDeployment.ContainerID)
+	ctx = logging.With(ctx, slog.String(logging.KeyDeploymentID, latestDeployment.ID.String()))
+	if err := s.dockerRuntime.StartContainer(ctx, latestDeployment.ContainerID); err != nil {
+		slog.ErrorContext(ctx, "failed to start project", slog.Any("error", err))
+		return err
+	}
+	slog.InfoContext(ctx, "project started")
+	return nil
 }
 
-func (s *ProjectService) StopProject(ctx context.Context, projectID uuid.UUID) error {
-	project, err := s.repo.GetByID(ctx, projectID)
+func (s *ProjectService) StopProject(ctx context.Context, projectID uuid.UUID) (err error) {
+	ctx = logging.With(ctx, slog.String(logging.KeyProjectID, projectID.String()))
+	ctx, span := tracing.Start(ctx, "ProjectService.StopProject", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var project *domain.Project
+	defer func() { s.audit.Record(ctx, projectAudit(AuditProjectStop, projectID, project, err)) }()
+
+	project, err = s.repo.GetByID(ctx, projectID)
 	if err != nil {
 		return err
 	}
@@ -200,5 +534,282 @@ func (s *ProjectService) StopProject(ctx context.Context, projectID uuid.UUID) e
 	}
 
 	latestDeployment := project.Deployments[len(project.Deployments)-1]
-	return s.dockerRuntime.StopContainer(ctx, latestDeployment.ContainerID)
+	ctx = logging.With(ctx, slog.String(logging.KeyDeploymentID, latestDeployment.ID.String()))
+	if err := s.dockerRuntime.StopContainer(ctx, latestDeployment.ContainerID); err != nil {
+		slog.ErrorContext(ctx, "failed to stop project", slog.Any("error", err))
+		return err
+	}
+	slog.InfoContext(ctx, "project stopped")
+	return nil
+}
+
+// RedeployProject membuat deployment baru lalu menghentikan dan menghapus container lama
+func (s *ProjectService) RedeployProject(ctx context.Context, projectID uuid.UUID) (_ *domain.Deployment, err error) {
+	ctx = logging.With(ctx, slog.String(logging.KeyProjectID, projectID.String()))
+	ctx, span := tracing.Start(ctx, "ProjectService.RedeployProject", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var project *domain.Project
+	defer func() { s.audit.Record(ctx, projectAudit(AuditProjectRedeploy, projectID, project, err)) }()
+
+	project, err = s.repo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := s.DeployProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Container lama baru dihentikan setelah yang baru jalan
+	for _, d := range project.Deployments {
+		if d.Status != "running" {
+			continue
+		}
+		if err := s.dockerRuntime.StopContainer(ctx, d.ContainerID); err != nil {
+			slog.WarnContext(ctx, "failed to stop old container", slog.String("container_id", d.ContainerID), slog.Any("error", err))
+		}
+		if err := s.dockerRuntime.RemoveContainer(ctx, d.ContainerID); err != nil {
+			slog.WarnContext(ctx, "failed to remove old container", slog.String("container_id", d.ContainerID), slog.Any("error", err))
+		}
+		if err := s.repo.UpdateDeploymentStatus(ctx, d.ID, "removed"); err != nil {
+			slog.WarnContext(ctx, "failed to update deployment status", slog.String("deployment_id", d.ID.String()), slog.Any("error", err))
+		}
+	}
+	return deployment, nil
+}
+
+// StopAllDeployments menghentikan semua container project yang masih running (force stop)
+func (s *ProjectService) StopAllDeployments(ctx context.Context, projectID uuid.UUID) (err error) {
+	ctx = logging.With(ctx, slog.String(logging.KeyProjectID, projectID.String()))
+	ctx, span := tracing.Start(ctx, "ProjectService.StopAllDeployments", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var project *domain.Project
+	defer func() { s.audit.Record(ctx, projectAudit(AuditProjectStopAll, projectID, project, err)) }()
+
+	project, err = s.repo.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, d := range project.Deployments {
+		if d.Status != "running" {
+			continue
+		}
+		if err := s.dockerRuntime.StopContainer(ctx, d.ContainerID); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", d.ContainerID, err))
+			continue
+		}
+		if err := s.repo.UpdateDeploymentStatus(ctx, d.ID, "stopped"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	project.Status = "stopped"
+	if err := s.repo.Update(ctx, project); err != nil {
+		errs = append(errs, err)
+	}
+	slog.InfoContext(ctx, "all deployments stopped")
+	return errors.Join(errs...)
+}
+
+// StopUserProjects menghentikan semua project yang dibuat user dan semua project di organization
+// yang tenant-nya dipegang user (dipakai saat tenant di-suspend)
+func (s *ProjectService) StopUserProjects(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "ProjectService.StopUserProjects", attribute.String("user.id", userID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	created, err := s.repo.ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	orgIDs, err := s.tenants.OwnedOrganizationIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	owned, err := s.repo.ListByOrganizationIDs(ctx, orgIDs)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	seen := make(map[uuid.UUID]bool)
+	for _, p := range append(created, owned...) {
+		if seen[p.ID] {
+			continue
+		}
+		seen[p.ID] = true
+		if err := s.StopAllDeployments(ctx, p.ID); err != nil {
+			errs = append(errs, fmt.Errorf("project %s: %w", p.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetEnvVars mengganti seluruh env var project; berlaku pada deployment berikutnya.
+// Audit hanya mencatat nama variabel, nilainya bisa berisi secret.
+func (s *ProjectService) SetEnvVars(ctx context.Context, projectID uuid.UUID, vars map[string]string) (err error) {
+	ctx, span := tracing.Start(ctx, "ProjectService.SetEnvVars", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var project *domain.Project
+	var beforeKeys []string
+	defer func() {
+		entry := projectAudit(AuditProjectEnv, projectID, project, err)
+		entry.Before = map[string]any{"keys": beforeKeys}
+		entry.After = map[string]any{"keys": sortedKeys(vars)}
+		s.audit.Record(ctx, entry)
+	}()
+
+	project, err = s.repo.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	for _, env := range project.EnvVars {
+		beforeKeys = append(beforeKeys, env.Key)
+	}
+	sort.Strings(beforeKeys)
+
+	envVars := make([]domain.EnvVar, 0, len(vars))
+	for _, key := range sortedKeys(vars) {
+		if !envKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w: %q", ErrInvalidEnvKey, key)
+		}
+		envVars = append(envVars, domain.EnvVar{Key: key, Value: vars[key]})
+	}
+	return s.repo.ReplaceEnvVars(ctx, projectID, envVars)
+}
+
+// SetSecurity mengganti pelonggaran hardening project; berlaku pada deployment berikutnya
+func (s *ProjectService) SetSecurity(ctx context.Context, projectID uuid.UUID, relax domain.SecurityRelaxations) (_ *domain.Project, err error) {
+	ctx, span := tracing.Start(ctx, "ProjectService.SetSecurity", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	var project *domain.Project
+	var before domain.SecurityRelaxations
+	defer func() {
+		entry := projectAudit(AuditProjectSecurity, projectID, project, err)
+		entry.Before = map[string]any{"security": before}
+		entry.After = map[string]any{"security": relax}
+		s.audit.Record(ctx, entry)
+	}()
+
+	project, err = s.repo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	before = project.Security
+
+	if relax.AddCapabilities, err = normalizeCapabilities(relax.AddCapabilities); err != nil {
+		return nil, err
+	}
+	plan, err := s.tenants.Plan(ctx, project.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.hardening.CheckRelaxations(ctx, plan, relax); err != nil {
+		return nil, err
+	}
+
+	project.Security = relax
+	if err := s.repo.Update(ctx, project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (s *ProjectService) ensureTenantActive(ctx context.Context, orgID uuid.UUID) error {
+	suspended, err := s.tenants.IsSuspended(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if suspended {
+		return ErrTenantSuspended
+	}
+	return nil
+}
+
+// GetProjectStats mengambil pemakaian resource terkini dari container project
+func (s *ProjectService) GetProjectStats(ctx context.Context, projectID uuid.UUID) (_ *ports.ContainerStats, err error) {
+	ctx, span := tracing.Start(ctx, "ProjectService.GetProjectStats", attribute.String("project.id", projectID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	project, err := s.repo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(project.Deployments) == 0 {
+		return nil, ErrNoDeployment
+	}
+
+	latestDeployment := project.Deployments[len(project.Deployments)-1]
+	return s.dockerRuntime.Stats(ctx, latestDeployment.ContainerID)
+}
+
+// projectAudit entry audit untuk aksi pada project (organization diambil dari project jika sudah ter-load)
+func projectAudit(action string, projectID uuid.UUID, project *domain.Project, err error) AuditEntry {
+	entry := AuditEntry{Action: action, TargetType: "project", TargetID: projectID.String(), Err: err}
+	if project != nil {
+		orgID := project.OrganizationID
+		entry.OrganizationID = &orgID
+	}
+	return entry
+}
+
+// projectSnapshot field project yang dicatat sebagai before/after di audit log
+func projectSnapshot(p *domain.Project) map[string]any {
+	snapshot := map[string]any{
+		"name":        p.Name,
+		"image":       p.ImageName,
+		"subdomain":   p.Subdomain,
+		"port":        p.ContainerPort,
+		"status":      p.Status,
+		"source_type": p.SourceType,
+		"pull_policy": p.PullPolicy,
+	}
+	if p.SourceType == domain.SourceTypeGit {
+		snapshot["git_url"] = p.GitURL
+		snapshot["git_branch"] = p.GitBranch
+		snapshot["git_subdir"] = p.GitSubdir
+		snapshot["dockerfile"] = p.DockerfilePath
+	}
+	return snapshot
+}
+
+// validateImageName image project biasa tidak boleh menunjuk image hasil build project lain (mth/<id>:...)
+// maupun image ID lokal (sha256:... atau hex), yang di-resolve Docker ke image milik siapa pun di host.
+// Image kosong boleh: project yang akan diisi lewat upload build.
+func validateImageName(image string) error {
+	if strings.HasPrefix(image, "sha256:") || imageIDPattern.MatchString(image) {
+		return ErrImageIDReference
+	}
+	if named, err := reference.ParseNormalizedNamed(image); err == nil && strings.HasPrefix(reference.FamiliarName(named), localImageRepo+"/") {
+		return ErrReservedImage
+	}
+	return nil
+}
+
+// validateGitSource cek minimal; URL divalidasi lagi oleh GitClient saat clone
+func validateGitSource(source *domain.GitSource) error {
+	if source == nil {
+		return nil
+	}
+	if strings.TrimSpace(source.GitURL) == "" {
+		return ErrGitURLRequired
+	}
+	_, _, err := buildPaths(source.GitSubdir, source.DockerfilePath)
+	return err
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeTenantRepo status tenant tetap untuk semua organization; lookups mencatat org yang ditanyakan
+type fakeTenantRepo struct {
+	suspended   bool
+	maxProjects int
+	plan        string
+	projects    int64 // jumlah project tenant yang sudah ada
+	lookups     []uuid.UUID
+}
+
+func (r *fakeTenantRepo) IsSuspended(ctx context.Context, orgID uuid.UUID) (bool, error) {
+	r.lookups = append(r.lookups, orgID)
+	return r.suspended, nil
+}
+
+func (r *fakeTenantRepo) MaxProjects(ctx context.Context, orgID uuid.UUID) (int, error) {
+	r.lookups = append(r.lookups, orgID)
+	return r.maxProjects, nil
+}
+
+func (r *fakeTenantRepo) Plan(ctx context.Context, orgID uuid.UUID) (string, error) {
+	r.lookups = append(r.lookups, orgID)
+	return r.plan, nil
+}
+
+func (r *fakeTenantRepo) CountProjects(ctx context.Context, orgID uuid.UUID) (int64, error) {
+	r.lookups = append(r.lookups, orgID)
+	return r.projects, nil
+}
+
+func (r *fakeTenantRepo) OwnedOrganizationIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+// tenantProjectRepo menyimpan project yang dibuat
+type tenantProjectRepo struct {
+	ports.ProjectRepository
+	created []*domain.Project
+}
+
+func (r *tenantProjectRepo) Create(ctx context.Context, project *domain.Project) error {
+	r.created = append(r.created, project)
+	return nil
+}
+
+func (r *tenantProjectRepo) ListByUserID(ctx context.Context, userID uuid.UUID) ([]domain.Project, error) {
+	return nil, nil
+}
+
+func (r *tenantProjectRepo) ListByOrganizationIDs(ctx context.Context, orgIDs []uuid.UUID) ([]domain.Project, error) {
+	return nil, nil
+}
+
+func TestCreateProjectTenantChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenant  fakeTenantRepo
+		wantErr error
+	}{
+		{name: "below quota", tenant: fakeTenantRepo{maxProjects: 3, projects: 2}},
+		{name: "quota reached", tenant: fakeTenantRepo{maxProjects: 3, projects: 3}, wantErr: ErrProjectQuotaExceeded},
+		{name: "zero quota is unlimited", tenant: fakeTenantRepo{maxProjects: 0, projects: 1000}},
+		{name: "suspended tenant", tenant: fakeTenantRepo{suspended: true, maxProjects: 3}, wantErr: ErrTenantSuspended},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &tenantProjectRepo{}
+			audit := &memoryAuditRepo{}
+			svc := NewProjectService(repo, nil, nil, &tt.tenant, nil, nil, nil, NewAuditService(audit), ProjectConfig{})
+
+			orgID := uuid.New()
+			_, err := svc.CreateProject(context.Background(), uuid.New(), orgID, "web", "nginx:alpine", "web", 80, "", nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateProject err = %v, want %v", err, tt.wantErr)
+			}
+			// Status dan kuota milik organization, bukan member yang membuat project
+			for _, id := range tt.tenant.lookups {
+				if id != orgID {
+					t.Fatalf("tenant looked up by %v, want organization %v", id, orgID)
+				}
+			}
+			if created := len(repo.created) == 1; created != (tt.wantErr == nil) {
+				t.Fatalf("project created = %v with err %v", created, err)
+			}
+			if len(audit.events) != 1 || audit.events[0].Action != AuditProjectCreate || (audit.events[0].Outcome == domain.AuditOutcomeFailure) != (tt.wantErr != nil) {
+				t.Fatalf("audit events = %+v, want one %s with matching outcome", audit.events, AuditProjectCreate)
+			}
+		})
+	}
+}
+
+func TestCreateProjectPullPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    string
+		wantErr error
+	}{
+		{policy: "", want: domain.PullIfNotPresent},
+		{policy: domain.PullAlways, want: domain.PullAlways},
+		{policy: domain.PullNever, want: domain.PullNever},
+		{policy: "sometimes", wantErr: ErrInvalidPullPolicy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			repo := &tenantProjectRepo{}
+			svc := NewProjectService(repo, nil, nil, &fakeTenantRepo{}, nil, nil, nil, NewAuditService(&memoryAuditRepo{}), ProjectConfig{})
+
+			project, err := svc.CreateProject(context.Background(), uuid.New(), uuid.New(), "web", "nginx:alpine", "web", 80, tt.policy, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && project.PullPolicy != tt.want {
+				t.Fatalf("pull policy = %q, want %q", project.PullPolicy, tt.want)
+			}
+		})
+	}
+}
+
+// deployRepo satu project; deployment yang dicatat disimpan
+type deployRepo struct {
+	ports.ProjectRepository
+	project     *domain.Project
+	deployments []*domain.Deployment
+}
+
+func (r *deployRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Project, error) {
+	return r.project, nil
+}
+
+func (r *deployRepo) CreateDeployment(ctx context.Context, d *domain.Deployment) error {
+	r.deployments = append(r.deployments, d)
+	return nil
+}
+
+func (r *deployRepo) Update(ctx context.Context, project *domain.Project) error { return nil }
+
+// deployRuntime mencatat image yang disiapkan dan config container yang dibuat
+type deployRuntime struct {
+	ports.ContainerRuntime
+	labels   map[string]string // label image yang dikembalikan PrepareImage
+	private  bool              // registry menolak PrepareImage tanpa kredensial
+	prepared []string          // image|policy
+	auth     *ports.RegistryAuth
+	created  []ports.ContainerConfig
+}
+
+func (r *deployRuntime) PrepareImage(ctx context.Context, image, pullPolicy string, auth *ports.RegistryAuth) (*ports.ImageRef, error) {
+	r.prepared = append(r.prepared, image+"|"+pullPolicy)
+	r.auth = auth
+	if r.private && auth == nil {
+		return nil, ports.ErrRegistryAccessDenied
+	}
+	return &ports.ImageRef{ID: "sha256:abc", Digest: "ghcr.io/acme/web@sha256:abc", Labels: r.labels}, nil
+}
+
+func (r *deployRuntime) CreateContainer(ctx context.Context, config ports.ContainerConfig) (string, error) {
+	r.created = append(r.created, config)
+	return "container-1", nil
+}
+
+func (r *deployRuntime) StartContainer(ctx context.Context, containerID string) error { return nil }
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveDeploy(string, time.Duration) {}
+func (nopMetrics) ObserveImagePull(time.Duration)      {}
+func (nopMetrics) IncDockerError(string)               {}
+
+// fakeRegistries mengembalikan auth tetap dan mencatat image yang diminta
+type fakeRegistries struct {
+	auth     *ports.RegistryAuth
+	err      error
+	requests []string
+}
+
+func (r *fakeRegistries) AuthFor(ctx context.Context, orgID uuid.UUID, image string) (*ports.RegistryAuth, error) {
+	r.requests = append(r.requests, orgID.String()+"|"+image)
+	return r.auth, r.err
+}
+
+// dryRunDB database tanpa koneksi; query tidak pernah dijalankan sehingga policy apa pun tidak ditemukan
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open dry-run db: %v", err)
+	}
+	return db
+}
+
+func TestDeployPreparesImage(t *testing.T) {
+	auth := &ports.RegistryAuth{ServerAddress: "ghcr.io", Username: "bot", Password: "secret"}
+
+	tests := []struct {
+		name         string
+		sourceType   string
+		pullPolicy   string
+		ownBuild     bool // image hasil build project ini (mth/<id>:...)
+		foreignLabel bool // tag project ini tapi label build milik project lain
+		private      bool // image private yang sudah ada di cache host
+		registries   fakeRegistries
+		wantPolicy   string
+		wantAuth     *ports.RegistryAuth
+		wantLookup   bool
+		wantDeployed bool
+	}{
+		{name: "registry image with credential", sourceType: domain.SourceTypeImage, pullPolicy: domain.PullAlways, registries: fakeRegistries{auth: auth}, wantPolicy: domain.PullAlways, wantAuth: auth, wantLookup: true, wantDeployed: true},
+		{name: "default policy", sourceType: domain.SourceTypeImage, wantPolicy: domain.PullIfNotPresent, wantLookup: true, wantDeployed: true},
+		{name: "never pull still passes the credential", sourceType: domain.SourceTypeImage, pullPolicy: domain.PullNever, registries: fakeRegistries{auth: auth}, wantPolicy: domain.PullNever, wantAuth: auth, wantLookup: true, wantDeployed: true},
+		{name: "locally built image", sourceType: domain.SourceTypeUpload, ownBuild: true, pullPolicy: domain.PullAlways, registries: fakeRegistries{auth: auth}, wantPolicy: domain.PullNever, wantDeployed: true},
+		{name: "build tag relabelled by another project", sourceType: domain.SourceTypeGit, ownBuild: true, foreignLabel: true},
+		{name: "upload project pointing at a foreign image", sourceType: domain.SourceTypeUpload, registries: fakeRegistries{auth: auth}},
+		{name: "cached private image with credential", sourceType: domain.SourceTypeImage, private: true, registries: fakeRegistries{auth: auth}, wantPolicy: domain.PullIfNotPresent, wantAuth: auth, wantLookup: true, wantDeployed: true},
+		{name: "cached private image without credential", sourceType: domain.SourceTypeImage, private: true, wantLookup: true},
+		{name: "cached private image without credential and never", sourceType: domain.SourceTypeImage, pullPolicy: domain.PullNever, private: true, wantLookup: true},
+		{name: "credential lookup fails", sourceType: domain.SourceTypeImage, registries: fakeRegistries{err: errors.New("db down")}, wantLookup: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := &domain.Project{ID: uuid.New(), UserID: uuid.New(), OrganizationID: uuid.New(), ImageName: "ghcr.io/acme/web:1", Subdomain: "web", ContainerPort: 80, SourceType: tt.sourceType, PullPolicy: tt.pullPolicy}
+			if tt.ownBuild {
+				project.ImageName = localImagePrefix(project.ID) + "build-1"
+			}
+			repo := &deployRepo{project: project}
+			runtime := &deployRuntime{private: tt.private}
+			if tt.ownBuild {
+				runtime.labels = map[string]string{projectIDLabel: project.ID.String()}
+			}
+			if tt.foreignLabel {
+				runtime.labels = map[string]string{projectIDLabel: uuid.NewString()}
+			}
+			db := dryRunDB(t)
+			svc := NewProjectService(repo, runtime, nopMetrics{}, &fakeTenantRepo{}, &tt.registries, NewImagePolicyService(db, nil), NewHardeningService(db, nil, ""), NewAuditService(&memoryAuditRepo{}), ProjectConfig{BaseDomain: "apps.test", NetworkPrefix: "mth-org-"})
+
+			deployment, err := svc.DeployProject(context.Background(), project.ID)
+			if (err == nil) != tt.wantDeployed || len(runtime.created) != len(repo.deployments) || (len(repo.deployments) == 1) != tt.wantDeployed {
+				t.Fatalf("deploy err = %v, containers = %d, want deployed %v", err, len(runtime.created), tt.wantDeployed)
+			}
+			if wantReq := project.OrganizationID.String() + "|" + project.ImageName; tt.wantLookup != (len(tt.registries.requests) == 1 && tt.registries.requests[0] == wantReq) {
+				t.Fatalf("AuthFor requests = %v, want lookup %v", tt.registries.requests, tt.wantLookup)
+			}
+			if !tt.wantDeployed {
+				return
+			}
+			if want := project.ImageName + "|" + tt.wantPolicy; len(runtime.prepared) != 1 || runtime.prepared[0] != want || runtime.auth != tt.wantAuth {
+				t.Fatalf("PrepareImage = %v auth %+v, want %s auth %+v", runtime.prepared, runtime.auth, want, tt.wantAuth)
+			}
+			// Container dibuat dari digest, bukan tag yang bisa dipindah
+			if runtime.created[0].Image != "ghcr.io/acme/web@sha256:abc" || deployment.ImageDigest != "ghcr.io/acme/web@sha256:abc" || deployment.Image != project.ImageName {
+				t.Fatalf("container image = %q, deployment = %q/%q", runtime.created[0].Image, deployment.Image, deployment.ImageDigest)
+			}
+			// Container masuk network organization-nya dengan alias subdomain
+			if cfg := runtime.created[0]; cfg.Network != "mth-org-"+project.OrganizationID.String() || cfg.Labels["traefik.docker.network"] != cfg.Network || len(cfg.NetworkAliases) != 1 || cfg.NetworkAliases[0] != "web" {
+				t.Fatalf("container network = %q aliases %v labels %v", cfg.Network, cfg.NetworkAliases, cfg.Labels)
+			}
+		})
+	}
+}
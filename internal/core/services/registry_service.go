@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/damantine/multi-tenant-hosting/internal/tracing"
+	"github.com/distribution/reference"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+const (
+	dockerHubRegistry   = "docker.io"
+	maxRegistryTokenLen = 4096
+)
+
+var (
+	ErrInvalidRegistry            = errors.New("invalid registry host (e.g. ghcr.io, registry.example.com:5000, docker.io)")
+	ErrRegistryCredentialExists   = errors.New("a credential for this registry already exists, delete it first")
+	ErrRegistryCredentialNotFound = errors.New("registry credential not found")
+	ErrRegistryCredentialInput    = errors.New("username and token are required")
+)
+
+// RegistryService kelola kredensial private registry per organization
+// dan memilih kredensial yang cocok saat image di-pull (implementasi ports.RegistryAuthProvider).
+type RegistryService struct {
+	db    *gorm.DB
+	box   *SecretBox
+	audit *AuditService
+}
+
+func NewRegistryService(db *gorm.DB, box *SecretBox, audit *AuditService) *RegistryService {
+	return &RegistryService{db: db, box: box, audit: audit}
+}
+
+type CreateRegistryCredentialInput struct {
+	Registry string
+	Username string
+	Token    string
+}
+
+// CreateCredential menyimpan token terenkripsi. Satu kredensial per host registry di setiap organization.
+func (s *RegistryService) CreateCredential(ctx context.Context, orgID, userID uuid.UUID, input CreateRegistryCredentialInput) (cred *domain.RegistryCredential, err error) {
+	ctx, span := tracing.Start(ctx, "RegistryService.CreateCredential", attribute.String("organization.id", orgID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	defer func() {
+		entry := AuditEntry{Action: AuditRegistryCredentialCreate, TargetType: "registry_credential", OrganizationID: &orgID,
+			Metadata: map[string]any{"registry": input.Registry, "username": input.Username}, Err: err}
+		if cred != nil {
+			entry.TargetID = cred.ID.String()
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	registry, err := normalizeRegistry(input.Registry)
+	if err != nil {
+		return nil, err
+	}
+	input.Registry = registry
+	username := strings.TrimSpace(input.Username)
+	if username == "" || input.Token == "" || len(username) > 255 || len(input.Token) > maxRegistryTokenLen {
+		return nil, ErrRegistryCredentialInput
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&domain.RegistryCredential{}).
+		Where("organization_id = ? AND registry = ?", orgID, registry).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrRegistryCredentialExists
+	}
+
+	ciphertext, err := s.box.Seal([]byte(input.Token), credentialAD(orgID, registry))
+	if err != nil {
+		return nil, err
+	}
+	cred = &domain.RegistryCredential{
+		ID:              uuid.New(),
+		OrganizationID:  orgID,
+		Registry:        registry,
+		Username:        username,
+		TokenCiphertext: ciphertext,
+		CreatedBy:       userID,
+	}
+	if err := s.db.WithContext(ctx).Create(cred).Error; err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// ListCredentials tanpa token (TokenCiphertext tidak diserialisasi)
+func (s *RegistryService) ListCredentials(ctx context.Context, orgID uuid.UUID) ([]domain.RegistryCredential, error) {
+	var creds []domain.RegistryCredential
+	err := s.db.WithContext(ctx).Omit("token_ciphertext").
+		Where("organization_id = ?", orgID).Order("registry").Find(&creds).Error
+	return creds, err
+}
+
+func (s *RegistryService) DeleteCredential(ctx context.Context, orgID, credentialID uuid.UUID) (err error) {
+	var cred domain.RegistryCredential
+	defer func() {
+		entry := AuditEntry{Action: AuditRegistryCredentialDelete, TargetType: "registry_credential", TargetID: credentialID.String(), OrganizationID: &orgID, Err: err}
+		if cred.Registry != "" {
+			entry.Metadata = map[string]any{"registry": cred.Registry}
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	if err := s.db.WithContext(ctx).Omit("token_ciphertext").
+		Where("id = ? AND organization_id = ?", credentialID, orgID).First(&cred).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRegistryCredentialNotFound
+		}
+		return err
+	}
+	return s.db.WithContext(ctx).Delete(&cred).Error
+}
+
+// AuthFor implementasi ports.RegistryAuthProvider: host registry diambil dari nama image
+// (tanpa host = Docker Hub) lalu dicocokkan dengan kredensial organization.
+func (s *RegistryService) AuthFor(ctx context.Context, orgID uuid.UUID, image string) (_ *ports.RegistryAuth, err error) {
+	ctx, span := tracing.Start(ctx, "RegistryService.AuthFor", attribute.String("organization.id", orgID.String()))
+	defer func() { tracing.End(span, err) }()
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		// Nama image tidak valid, biarkan pull yang melaporkan error-nya
+		return nil, nil
+	}
+	registry := strings.ToLower(reference.Domain(named))
+	span.SetAttributes(attribute.String("registry", registry))
+
+	var cred domain.RegistryCredential
+	err = s.db.WithContext(ctx).Where("organization_id = ? AND registry = ?", orgID, registry).First(&cred).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.box.Open(cred.TokenCiphertext, credentialAD(orgID, registry))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Model(&cred).UpdateColumn("last_used_at", time.Now()).Error; err != nil {
+		slog.WarnContext(ctx, "failed to update registry credential last_used_at", slog.Any("error", err))
+	}
+	return &ports.RegistryAuth{ServerAddress: registry, Username: cred.Username, Password: string(token)}, nil
+}
+
+// normalizeRegistry menerima host dengan skema/path (https://index.docker.io/v1/) dan
+// menyamakannya dengan host yang dipakai di nama image
+func normalizeRegistry(input string) (string, error) {
+	host := strings.ToLower(strings.TrimSpace(input))
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		host = dockerHubRegistry
+	}
+	if host == "" {
+		return "", ErrInvalidRegistry
+	}
+	// Host tanpa titik/port (selain localhost) akan dibaca docker sebagai namespace Docker Hub
+	named, err := reference.ParseNormalizedNamed(host + "/probe")
+	if err != nil || reference.Domain(named) != host {
+		return "", ErrInvalidRegistry
+	}
+	return host, nil
+}
+
+// credentialAD mengikat ciphertext ke organization dan registry-nya
+func credentialAD(orgID uuid.UUID, registry string) []byte {
+	return []byte(orgID.String() + "|" + registry)
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/google/uuid"
+)
+
+func TestNormalizeRegistry(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "ghcr.io", want: "ghcr.io"},
+		{input: " GHCR.io ", want: "ghcr.io"},
+		{input: "https://registry.example.com:5000/v2/", want: "registry.example.com:5000"},
+		{input: "localhost:5000", want: "localhost:5000"},
+		{input: "https://index.docker.io/v1/", want: "docker.io"},
+		{input: "registry-1.docker.io", want: "docker.io"},
+		{input: "docker.io", want: "docker.io"},
+		{input: "", wantErr: true},
+		{input: "myorg", wantErr: true},
+		{input: "bad host!", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := normalizeRegistry(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRegistry) {
+					t.Fatalf("normalizeRegistry = %q, %v; want ErrInvalidRegistry", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("normalizeRegistry = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryAuthFor(t *testing.T) {
+	db := testDB(t)
+	box, err := NewSecretBox(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewRegistryService(db, box, NewAuditService(&memoryAuditRepo{}))
+	ctx := context.Background()
+	orgA, orgB, user := uuid.New(), uuid.New(), uuid.New()
+
+	for _, input := range []CreateRegistryCredentialInput{
+		{Registry: "https://ghcr.io", Username: "bot", Token: "ghcr-token"},
+		{Registry: "index.docker.io", Username: "hub", Token: "hub-token"},
+	} {
+		if _, err := svc.CreateCredential(ctx, orgA, user, input); err != nil {
+			t.Fatalf("create %s: %v", input.Registry, err)
+		}
+	}
+	if _, err := svc.CreateCredential(ctx, orgA, user, CreateRegistryCredentialInput{Registry: "GHCR.IO", Username: "x", Token: "y"}); !errors.Is(err, ErrRegistryCredentialExists) {
+		t.Fatalf("duplicate registry: err = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		org       uuid.UUID
+		image     string
+		wantUser  string
+		wantToken string
+		wantHost  string
+	}{
+		{name: "matching host", org: orgA, image: "ghcr.io/acme/web:1", wantUser: "bot", wantToken: "ghcr-token", wantHost: "ghcr.io"},
+		{name: "docker hub short name", org: orgA, image: "nginx:alpine", wantUser: "hub", wantToken: "hub-token", wantHost: "docker.io"},
+		{name: "other registry", org: orgA, image: "quay.io/acme/web"},
+		{name: "other organization", org: orgB, image: "ghcr.io/acme/web:1"},
+		{name: "invalid image", org: orgA, image: "Not A Ref"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := svc.AuthFor(ctx, tt.org, tt.image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantUser == "" {
+				if auth != nil {
+					t.Fatalf("AuthFor = %+v, want nil", auth)
+				}
+				return
+			}
+			if auth == nil || auth.Username != tt.wantUser || auth.Password != tt.wantToken || auth.ServerAddress != tt.wantHost {
+				t.Fatalf("AuthFor = %+v, want %s/%s@%s", auth, tt.wantUser, tt.wantToken, tt.wantHost)
+			}
+		})
+	}
+
+	// Ciphertext yang dipindah ke organization lain tidak bisa dibuka
+	var cred domain.RegistryCredential
+	if err := db.First(&cred, "organization_id = ? AND registry = ?", orgA, "ghcr.io").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&cred).Update("organization_id", orgB).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AuthFor(ctx, orgB, "ghcr.io/acme/web:1"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("moved credential: err = %v, want ErrDecrypt", err)
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+var ErrDecrypt = errors.New("failed to decrypt secret (encryption key changed?)")
+
+// SecretBox enkripsi AES-256-GCM untuk secret yang harus bisa dibaca kembali (mis. token registry).
+// Format ciphertext: nonce || sealed.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox key harus 32 byte
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal additionalData mengikat ciphertext ke pemiliknya supaya tidak bisa dipindah ke baris lain
+func (b *SecretBox) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (b *SecretBox) Open(ciphertext, additionalData []byte) ([]byte, error) {
+	n := b.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := b.aead.Open(nil, ciphertext[:n], ciphertext[n:], additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSecretBox(t *testing.T) {
+	box, err := NewSecretBox(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSecretBox(bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := box.Seal([]byte("token"), []byte("org-a|ghcr.io"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := box.Seal([]byte("token"), []byte("org-a|ghcr.io"))
+	if bytes.Equal(sealed, again) {
+		t.Fatal("ciphertext is deterministic, nonce not random")
+	}
+	if got, err := box.Open(sealed, []byte("org-a|ghcr.io")); err != nil || string(got) != "token" {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+	tests := map[string]struct {
+		box        *SecretBox
+		ciphertext []byte
+		ad         string
+	}{
+		"other organization": {box: box, ciphertext: sealed, ad: "org-b|ghcr.io"},
+		"other registry":     {box: box, ciphertext: sealed, ad: "org-a|docker.io"},
+		"other key":          {box: other, ciphertext: sealed, ad: "org-a|ghcr.io"},
+		"tampered":           {box: box, ciphertext: tampered, ad: "org-a|ghcr.io"},
+		"truncated":          {box: box, ciphertext: sealed[:4], ad: "org-a|ghcr.io"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := tt.box.Open(tt.ciphertext, []byte(tt.ad)); !errors.Is(err, ErrDecrypt) {
+				t.Fatalf("err = %v, want ErrDecrypt", err)
+			}
+		})
+	}
+
+	if _, err := NewSecretBox([]byte("short")); err == nil {
+		t.Fatal("NewSecretBox accepted a short key")
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey satu key JWT. Private nil berarti key hanya untuk verifikasi (sudah dirotasi keluar).
+type SigningKey struct {
+	KID     string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+}
+
+// KeySet key aktif untuk menandatangani token baru + semua key yang masih diterima saat verifikasi.
+// Rotasi: tambahkan key baru dan jadikan aktif, key lama tetap di set sampai token terakhirnya kadaluarsa.
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// NewKeySet activeKID kosong = private key dengan kid terbesar (urutan string), mis. kid berbasis tanggal
+func NewKeySet(keys []*SigningKey, activeKID string) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*SigningKey, len(keys))}
+	for _, k := range keys {
+		if _, dup := set.keys[k.KID]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", k.KID)
+		}
+		set.keys[k.KID] = k
+		if k.Private != nil && activeKID == "" && (set.active == nil || k.KID > set.active.KID) {
+			set.active = k
+		}
+	}
+	if activeKID != "" {
+		set.active = set.keys[activeKID]
+	}
+	if set.active == nil || set.active.Private == nil {
+		return nil, errors.New("no private signing key available")
+	}
+	return set, nil
+}
+
+// LoadKeySet membaca semua file *.pem di dir; nama file (tanpa .pem) menjadi kid.
+// File berisi PRIVATE KEY (PKCS#8/PKCS#1, RSA atau Ed25519) atau PUBLIC KEY (verifikasi saja).
+func LoadKeySet(dir, activeKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no *.pem keys found in %s", dir)
+	}
+
+	var keys []*SigningKey
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := parsePEMKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewKeySet(keys, activeKID)
+}
+
+// GenerateKeySet key Ed25519 sementara (hanya untuk development: token tidak valid lagi setelah restart)
+func GenerateKeySet() (*KeySet, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	kid := "ephemeral-" + time.Now().UTC().Format("20060102150405")
+	return NewKeySet([]*SigningKey{{KID: kid, Method: jwt.SigningMethodEdDSA, Private: priv, Public: pub}}, kid)
+}
+
+func parsePEMKey(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY", "RSA PRIVATE KEY":
+		var parsed any
+		var err error
+		if block.Type == "RSA PRIVATE KEY" {
+			parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		} else {
+			parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch k := parsed.(type) {
+		case *rsa.PrivateKey:
+			return &SigningKey{KID: kid, Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+		case ed25519.PrivateKey:
+			return &SigningKey{KID: kid, Method: jwt.SigningMethodEdDSA, Private: k, Public: k.Public()}, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T (use RSA or Ed25519)", parsed)
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch k := parsed.(type) {
+		case *rsa.PublicKey:
+			return &SigningKey{KID: kid, Method: jwt.SigningMethodRS256, Public: k}, nil
+		case ed25519.PublicKey:
+			return &SigningKey{KID: kid, Method: jwt.SigningMethodEdDSA, Public: k}, nil
+		}
+		return nil, fmt.Errorf("unsupported public key type %T (use RSA or Ed25519)", parsed)
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// Sign menandatangani claims dengan key aktif dan menyertakan header kid
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.KID
+	return token.SignedString(k.active.Private)
+}
+
+// Parse memverifikasi token: kid harus dikenal dan alg harus sama dengan algoritma key tersebut
+// (mencegah alg confusion, mis. token "none" atau HS256 dengan public key sebagai secret).
+func (k *KeySet) Parse(tokenString string, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	opts = append(opts, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+		}
+		return key.Public, nil
+	}, opts...)
+}
+
+// JWK representasi public key sesuai RFC 7517 (RSA & OKP/Ed25519)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS semua public key yang masih diterima, untuk service lain yang ingin memverifikasi token kita
+func (k *KeySet) JWKS() JWKS {
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	out := JWKS{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		key := k.keys[kid]
+		jwk := JWK{KeyID: kid, Use: "sig", Algorithm: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		out.Keys = append(out.Keys, jwk)
+	}
+	return out
+}
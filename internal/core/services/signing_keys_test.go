@@ -0,0 +1,128 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeySetParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSigning := &SigningKey{KID: "rsa-1", Method: jwt.SigningMethodRS256, Private: rsaKey, Public: &rsaKey.PublicKey}
+	edSigning := &SigningKey{KID: "ed-1", Method: jwt.SigningMethodEdDSA, Private: edPriv, Public: edPub}
+	keys, err := NewKeySet([]*SigningKey{rsaSigning, edSigning}, "rsa-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.MapClaims{"sub": "user", "exp": time.Now().Add(time.Minute).Unix()}
+	sign := func(method jwt.SigningMethod, kid any, key any) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != nil {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublicDER})
+
+	activeToken, err := keys.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "active key", token: activeToken},
+		{name: "rotated key still accepted", token: sign(jwt.SigningMethodEdDSA, "ed-1", edPriv)},
+		{name: "EdDSA token claiming the RSA kid", token: sign(jwt.SigningMethodEdDSA, "rsa-1", edPriv), wantErr: true},
+		{name: "RS256 token claiming the Ed25519 kid", token: sign(jwt.SigningMethodRS256, "ed-1", rsaKey), wantErr: true},
+		{name: "HS256 with the RSA public key as secret", token: sign(jwt.SigningMethodHS256, "rsa-1", rsaPublicPEM), wantErr: true},
+		{name: "RS512 with the right key", token: sign(jwt.SigningMethodRS512, "rsa-1", rsaKey), wantErr: true},
+		{name: "alg none", token: sign(jwt.SigningMethodNone, "rsa-1", jwt.UnsafeAllowNoneSignatureType), wantErr: true},
+		{name: "unknown kid", token: sign(jwt.SigningMethodRS256, "rsa-2", rsaKey), wantErr: true},
+		{name: "missing kid", token: sign(jwt.SigningMethodRS256, nil, rsaKey), wantErr: true},
+		{name: "non-string kid", token: sign(jwt.SigningMethodRS256, 1, rsaKey), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := keys.Parse(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("token accepted: %v", token.Header)
+				}
+				return
+			}
+			if err != nil || !token.Valid {
+				t.Fatalf("parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewKeySetActiveKey(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	pub := priv.Public()
+	key := func(kid string, private bool) *SigningKey {
+		k := &SigningKey{KID: kid, Method: jwt.SigningMethodEdDSA, Public: pub}
+		if private {
+			k.Private = priv
+		}
+		return k
+	}
+
+	tests := []struct {
+		name       string
+		keys       []*SigningKey
+		activeKID  string
+		wantActive string
+		wantErr    bool
+	}{
+		{name: "largest private kid by default", keys: []*SigningKey{key("2024-01", true), key("2025-01", true), key("2026-01", false)}, wantActive: "2025-01"},
+		{name: "explicit active kid", keys: []*SigningKey{key("2024-01", true), key("2025-01", true)}, activeKID: "2024-01", wantActive: "2024-01"},
+		{name: "active kid without private key", keys: []*SigningKey{key("2024-01", false)}, activeKID: "2024-01", wantErr: true},
+		{name: "unknown active kid", keys: []*SigningKey{key("2024-01", true)}, activeKID: "2023-01", wantErr: true},
+		{name: "duplicate kid", keys: []*SigningKey{key("2024-01", true), key("2024-01", true)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := NewKeySet(tt.keys, tt.activeKID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewKeySet succeeded with active key %q", set.active.KID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if set.active.KID != tt.wantActive {
+				t.Fatalf("active kid = %q, want %q", set.active.KID, tt.wantActive)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/damantine/multi-tenant-hosting/internal/logging"
+	"github.com/google/uuid"
+)
+
+// StatsCollector mengambil sampel stats semua project yang running secara berkala
+// dan menyimpan riwayat singkat (rolling) per project di memory.
+type StatsCollector struct {
+	repo          ports.ProjectRepository
+	dockerRuntime ports.ContainerRuntime
+	interval      time.Duration
+	historySize   int
+
+	mu      sync.RWMutex
+	history map[uuid.UUID][]ports.ContainerStats
+}
+
+func NewStatsCollector(repo ports.ProjectRepository, docker ports.ContainerRuntime, interval time.Duration, historySize int) *StatsCollector {
+	return &StatsCollector{
+		repo:          repo,
+		dockerRuntime: docker,
+		interval:      interval,
+		historySize:   historySize,
+		history:       make(map[uuid.UUID][]ports.ContainerStats),
+	}
+}
+
+// Run menjalankan loop sampling sampai ctx dibatalkan. Panggil dalam goroutine.
+func (c *StatsCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.collect(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *StatsCollector) collect(ctx context.Context) {
+	projects, err := c.repo.ListByStatus(ctx, "running")
+	if err != nil {
+		slog.ErrorContext(ctx, "stats collector: failed to list running projects", slog.Any("error", err))
+		return
+	}
+
+	active := make(map[uuid.UUID]bool, len(projects))
+	for _, p := range projects {
+		if len(p.Deployments) == 0 {
+			continue
+		}
+		active[p.ID] = true
+
+		containerID := p.Deployments[len(p.Deployments)-1].ContainerID
+		stats, err := c.dockerRuntime.Stats(logging.With(ctx, slog.String(logging.KeyProjectID, p.ID.String())), containerID)
+		if err != nil {
+			// Error sudah di-log oleh adapter Docker lengkap dengan project_id
+			continue
+		}
+		c.record(p.ID, *stats)
+	}
+
+	// Buang riwayat project yang sudah tidak running
+	c.mu.Lock()
+	for id := range c.history {
+		if !active[id] {
+			delete(c.history, id)
+		}
+	}
+	c.mu.Unlock()
+}
+
+func (c *StatsCollector) record(projectID uuid.UUID, stats ports.ContainerStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	samples := append(c.history[projectID], stats)
+	if len(samples) > c.historySize {
+		samples = samples[len(samples)-c.historySize:]
+	}
+	c.history[projectID] = samples
+}
+
+// History mengembalikan salinan riwayat sampel project (paling lama di awal)
+func (c *StatsCollector) History(projectID uuid.UUID) []ports.ContainerStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	samples := c.history[projectID]
+	out := make([]ports.ContainerStats, len(samples))
+	copy(out, samples)
+	return out
+}
+
+// Latest mengembalikan sampel terakhir semua project, berguna untuk melihat noisy neighbour
+func (c *StatsCollector) Latest() map[uuid.UUID]ports.ContainerStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make(map[uuid.UUID]ports.ContainerStats, len(c.history))
+	for id, samples := range c.history {
+		if len(samples) > 0 {
+			out[id] = samples[len(samples)-1]
+		}
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/damantine/multi-tenant-hosting/internal/core/ports"
+	"github.com/google/uuid"
+)
+
+// fakeProjectRepo hanya mengimplementasikan method yang dipakai test; method lain panic (interface nil)
+type fakeProjectRepo struct {
+	ports.ProjectRepository
+	running []domain.Project
+}
+
+func (r *fakeProjectRepo) ListByStatus(ctx context.Context, status string) ([]domain.Project, error) {
+	if status != "running" {
+		return nil, nil
+	}
+	return r.running, nil
+}
+
+// fakeRuntime mengembalikan sampel berurutan per container; container tanpa sampel mengembalikan error
+type fakeRuntime struct {
+	ports.ContainerRuntime
+	cpu map[string]float64
+}
+
+func (r *fakeRuntime) Stats(ctx context.Context, containerID string) (*ports.ContainerStats, error) {
+	cpu, ok := r.cpu[containerID]
+	if !ok {
+		return nil, errors.New("no such container")
+	}
+	r.cpu[containerID] = cpu + 1
+	return &ports.ContainerStats{Timestamp: time.Now(), CPUPercent: cpu}, nil
+}
+
+func runningProject(containerIDs ...string) domain.Project {
+	p := domain.Project{ID: uuid.New(), Status: "running"}
+	for _, id := range containerIDs {
+		p.Deployments = append(p.Deployments, domain.Deployment{ID: uuid.New(), ContainerID: id})
+	}
+	return p
+}
+
+func TestStatsCollectorKeepsRollingHistory(t *testing.T) {
+	web := runningProject("old", "web")
+	repo := &fakeProjectRepo{running: []domain.Project{web}}
+	runtime := &fakeRuntime{cpu: map[string]float64{"old": 99, "web": 1}}
+	c := NewStatsCollector(repo, runtime, time.Minute, 3)
+
+	for i := 0; i < 5; i++ {
+		c.collect(context.Background())
+	}
+
+	history := c.History(web.ID)
+	if len(history) != 3 {
+		t.Fatalf("history has %d samples, want 3", len(history))
+	}
+	// Sampel diambil dari deployment terakhir; yang paling lama dibuang lebih dulu
+	for i, want := range []float64{3, 4, 5} {
+		if history[i].CPUPercent != want {
+			t.Fatalf("history[%d].CPUPercent = %v, want %v", i, history[i].CPUPercent, want)
+		}
+	}
+	if got := c.Latest()[web.ID].CPUPercent; got != 5 {
+		t.Fatalf("latest CPUPercent = %v, want 5", got)
+	}
+
+	// History mengembalikan salinan
+	history[0].CPUPercent = -1
+	if c.History(web.ID)[0].CPUPercent != 3 {
+		t.Fatal("History exposes the internal slice")
+	}
+}
+
+func TestStatsCollectorDropsStoppedProjects(t *testing.T) {
+	web := runningProject("web")
+	broken := runningProject("gone")
+	fresh := runningProject()
+	repo := &fakeProjectRepo{running: []domain.Project{web, broken, fresh}}
+	c := NewStatsCollector(repo, &fakeRuntime{cpu: map[string]float64{"web": 1}}, time.Minute, 10)
+
+	c.collect(context.Background())
+	if len(c.History(web.ID)) != 1 {
+		t.Fatal("running project was not sampled")
+	}
+	if len(c.History(broken.ID)) != 0 || len(c.History(fresh.ID)) != 0 {
+		t.Fatal("project without a readable container has samples")
+	}
+
+	repo.running = nil
+	c.collect(context.Background())
+	if len(c.History(web.ID)) != 0 || len(c.Latest()) != 0 {
+		t.Fatal("history of a stopped project was kept")
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APITokenPrefix penanda token personal, supaya middleware bisa membedakannya dari JWT
+const APITokenPrefix = "mth_"
+
+// lastUsedResolution supaya last_used_at tidak di-update di setiap request
+const lastUsedResolution = time.Minute
+
+var ErrTokenNotFound = errors.New("token not found")
+
+type TokenService struct {
+	db    *gorm.DB
+	audit *AuditService
+}
+
+func NewTokenService(db *gorm.DB, audit *AuditService) *TokenService {
+	return &TokenService{db: db, audit: audit}
+}
+
+type CreateTokenInput struct {
+	Name      string
+	Scopes    []string
+	ExpiresIn time.Duration // 0 = tidak kedaluwarsa
+}
+
+// CreateToken membuat API token baru. Nilai token mentah hanya dikembalikan sekali di sini.
+func (s *TokenService) CreateToken(ctx context.Context, userID uuid.UUID, input CreateTokenInput) (token *domain.APIToken, _ string, err error) {
+	defer func() {
+		entry := AuditEntry{Action: AuditTokenCreate, TargetType: "api_token", Metadata: map[string]any{"name": input.Name, "scopes": input.Scopes}, Err: err}
+		if token != nil {
+			entry.TargetID = token.ID.String()
+		}
+		s.audit.Record(ctx, entry)
+	}()
+
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, "", fmt.Errorf("token name is required")
+	}
+	if len(input.Scopes) == 0 {
+		return nil, "", fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range input.Scopes {
+		if !domain.ValidScope(scope) {
+			return nil, "", fmt.Errorf("invalid scope %q (expected <read|write|deploy|*>:<project|*>)", scope)
+		}
+	}
+
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	raw := APITokenPrefix + secret
+
+	token = &domain.APIToken{
+		UserID:    userID,
+		Name:      input.Name,
+		Prefix:    raw[:len(APITokenPrefix)+6],
+		TokenHash: hashToken(raw),
+		Scopes:    input.Scopes,
+	}
+	if input.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(input.ExpiresIn)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.db.WithContext(ctx).Create(token).Error; err != nil {
+		return nil, "", err
+	}
+	return token, raw, nil
+}
+
+func (s *TokenService) ListTokens(ctx context.Context, userID uuid.UUID) ([]domain.APIToken, error) {
+	var tokens []domain.APIToken
+	if err := s.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *TokenService) RevokeToken(ctx context.Context, userID, tokenID uuid.UUID) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditTokenRevoke, TargetType: "api_token", TargetID: tokenID.String(), Err: err})
+	}()
+
+	res := s.db.WithContext(ctx).Model(&domain.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+// ValidateToken mencari token berdasarkan hash dan mencatat last_used_at
+func (s *TokenService) ValidateToken(ctx context.Context, raw string) (*domain.APIToken, error) {
+	var token domain.APIToken
+	if err := s.db.WithContext(ctx).Where("token_hash = ?", hashToken(raw)).First(&token).Error; err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if !token.Active(now) {
+		return nil, ErrInvalidToken
+	}
+
+	var suspended int64
+	if err := s.db.WithContext(ctx).Model(&domain.User{}).Where("id = ? AND suspended_at IS NOT NULL", token.UserID).Count(&suspended).Error; err != nil {
+		return nil, err
+	}
+	if suspended > 0 {
+		return nil, ErrAccountSuspended
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedResolution {
+		token.LastUsedAt = &now
+		if err := s.db.WithContext(ctx).Model(&domain.APIToken{}).Where("id = ?", token.ID).Update("last_used_at", now).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &token, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+)
+
+func TestTokenLifecycle(t *testing.T) {
+	db := testDB(t)
+	svc := NewTokenService(db, NewAuditService(&memoryAuditRepo{}))
+	ctx := context.Background()
+	user := createTestUser(t, db, "alice", "alice@example.com", true)
+
+	token, raw, err := svc.CreateToken(ctx, user.ID, CreateTokenInput{Name: "ci", Scopes: []string{"deploy:web"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !strings.HasPrefix(raw, APITokenPrefix) || !strings.HasPrefix(raw, token.Prefix) || token.TokenHash == raw {
+		t.Fatalf("unexpected token %q / %+v", raw, token)
+	}
+
+	got, err := svc.ValidateToken(ctx, raw)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if got.UserID != user.ID || got.LastUsedAt == nil || len(got.Scopes) != 1 || got.Scopes[0] != "deploy:web" {
+		t.Fatalf("validated token = %+v", got)
+	}
+	if _, err := svc.ValidateToken(ctx, raw+"x"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("unknown token: err = %v", err)
+	}
+
+	other := createTestUser(t, db, "bob", "bob@example.com", true)
+	if err := svc.RevokeToken(ctx, other.ID, token.ID); !errors.Is(err, ErrTokenNotFound) {
+		t.Fatalf("revoke by another user: err = %v, want ErrTokenNotFound", err)
+	}
+	if err := svc.RevokeToken(ctx, user.ID, token.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := svc.ValidateToken(ctx, raw); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("revoked token: err = %v, want ErrInvalidToken", err)
+	}
+	if tokens, _ := svc.ListTokens(ctx, user.ID); len(tokens) != 0 {
+		t.Fatalf("revoked token still listed: %+v", tokens)
+	}
+}
+
+func TestCreateTokenValidation(t *testing.T) {
+	db := testDB(t)
+	svc := NewTokenService(db, NewAuditService(&memoryAuditRepo{}))
+	ctx := context.Background()
+	user := createTestUser(t, db, "alice", "alice@example.com", true)
+
+	tests := []struct {
+		name  string
+		input CreateTokenInput
+	}{
+		{name: "missing name", input: CreateTokenInput{Name: " ", Scopes: []string{"read:*"}}},
+		{name: "no scopes", input: CreateTokenInput{Name: "ci"}},
+		{name: "unknown action", input: CreateTokenInput{Name: "ci", Scopes: []string{"admin:*"}}},
+		{name: "missing resource", input: CreateTokenInput{Name: "ci", Scopes: []string{"read"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := svc.CreateToken(ctx, user.ID, tt.input); err == nil {
+				t.Fatal("token created")
+			}
+		})
+	}
+
+	// Token kedaluwarsa ditolak
+	_, raw, err := svc.CreateToken(ctx, user.ID, CreateTokenInput{Name: "ci", Scopes: []string{"read:*"}, ExpiresIn: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&domain.APIToken{}).Where("user_id = ?", user.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := svc.ValidateToken(ctx, raw); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expired token: err = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer         = "MultiTenantHosting"
+	totpPeriod         = 30 // detik
+	totpSkew           = 1  // toleransi +-1 step untuk clock drift
+	challengeTTL       = 5 * time.Minute
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+var (
+	ErrInvalidTOTPCode      = errors.New("invalid two-factor code")
+	ErrTOTPNotEnrolled      = errors.New("two-factor enrollment has not been started")
+	ErrTOTPAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorRequired    = errors.New("this organization requires two-factor authentication")
+	ErrTwoFactorEnforcedOrg = errors.New("two-factor authentication is required by one of your organizations")
+)
+
+// TOTPEnrollment data untuk ditampilkan ke user saat enrollment
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCodePNG  string `json:"qr_code_png"` // base64
+}
+
+// EnrollTOTP membuat secret baru (belum aktif sampai VerifyTOTP berhasil)
+func (s *AuthService) EnrollTOTP(ctx context.Context, userID uuid.UUID) (*TOTPEnrollment, error) {
+	var user domain.User
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Model(&user).Update("totp_secret", key.Secret()).Error; err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(200, 200)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+		QRCodePNG:  base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// VerifyTOTP mengaktifkan 2FA setelah kode pertama valid dan mengembalikan recovery codes (sekali tampil)
+func (s *AuthService) VerifyTOTP(ctx context.Context, userID uuid.UUID, code string) (_ []string, err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditTwoFactorEnable, TargetType: "user", TargetID: userID.String(), Err: err})
+	}()
+
+	var codes []string
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		if user.TOTPEnabled {
+			return ErrTOTPAlreadyEnabled
+		}
+		if user.TOTPSecret == "" {
+			return ErrTOTPNotEnrolled
+		}
+		if err := s.checkTOTP(tx, &user, code); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&user).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP mematikan 2FA (butuh kode TOTP valid). Ditolak jika ada organization yang mewajibkan 2FA.
+func (s *AuthService) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) (err error) {
+	defer func() {
+		s.audit.Record(ctx, AuditEntry{Action: AuditTwoFactorOff, TargetType: "user", TargetID: userID.String(), Err: err})
+	}()
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		if !user.TOTPEnabled {
+			return ErrTOTPNotEnabled
+		}
+
+		var enforced int64
+		if err := tx.Model(&domain.Membership{}).
+			Joins("JOIN organizations ON organizations.id = memberships.organization_id").
+			Where("memberships.user_id = ? AND organizations.require_2fa = ?", userID, true).
+			Count(&enforced).Error; err != nil {
+			return err
+		}
+		if enforced > 0 {
+			return ErrTwoFactorEnforcedOrg
+		}
+
+		if err := s.checkTOTP(tx, &user, code); err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": ""}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes mengganti semua recovery code lama (butuh kode TOTP valid)
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	var codes []string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		if !user.TOTPEnabled {
+			return ErrTOTPNotEnabled
+		}
+		if err := s.checkTOTP(tx, &user, code); err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CompleteMFA menukar challenge token + kode TOTP (atau recovery code) dengan token session
+func (s *AuthService) CompleteMFA(ctx context.Context, challengeToken, code, recoveryCode string, client ClientInfo) (_ *TokenPair, err error) {
+	challenge, err := s.ParseChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	userID := challenge.UserID
+	defer func() {
+		method := "totp"
+		if recoveryCode != "" {
+			method = "recovery_code"
+		}
+		s.audit.Record(ctx, AuditEntry{Action: AuditLoginMFA, TargetType: "user", TargetID: userID.String(), UserID: &userID, Metadata: map[string]any{"method": method}, Err: err})
+	}()
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		if !user.TOTPEnabled {
+			return ErrTOTPNotEnabled
+		}
+
+		if recoveryCode != "" {
+			return useRecoveryCode(tx, user.ID, recoveryCode)
+		}
+		return s.checkTOTP(tx, &user, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createSession(ctx, userID, client)
+}
+
+// checkTOTP validasi kode dengan toleransi skew, dan menolak step yang sudah pernah dipakai
+func (s *AuthService) checkTOTP(tx *gorm.DB, user *domain.User, code string) error {
+	code = strings.TrimSpace(code)
+	now := time.Now().Unix() / totpPeriod
+
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		step := now + offset
+		expected, err := totp.GenerateCodeCustom(user.TOTPSecret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return err
+		}
+		if expected != code {
+			continue
+		}
+		if step <= user.TOTPLastUsedStep {
+			return ErrInvalidTOTPCode
+		}
+		user.TOTPLastUsedStep = step
+		return tx.Model(user).Update("totp_last_used_step", step).Error
+	}
+	return ErrInvalidTOTPCode
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.Create(&domain.RecoveryCode{UserID: userID, CodeHash: hashToken(code)}).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func useRecoveryCode(tx *gorm.DB, userID uuid.UUID, code string) error {
+	normalized := strings.ToLower(strings.TrimSpace(code))
+	res := tx.Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashToken(normalized)).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// generateRecoveryCode format "xxxxx-xxxxx" (huruf kecil + angka, tanpa karakter ambigu)
+func generateRecoveryCode() (string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	b := make([]byte, recoveryCodeLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[n.Int64()]
+	}
+	half := recoveryCodeLength / 2
+	return fmt.Sprintf("%s-%s", b[:half], b[half:]), nil
+}
+
+// MFAChallengeInfo isi challenge token; Username dipakai sebagai key lockout yang sama dengan login password
+type MFAChallengeInfo struct {
+	ID       string // unik per challenge, untuk membatasi jumlah percobaan kode
+	UserID   uuid.UUID
+	Username string
+}
+
+// signChallengeToken JWT berumur pendek yang hanya bisa dipakai di /auth/2fa/challenge
+// (tidak punya claim sid sehingga ditolak ValidateToken)
+func (s *AuthService) signChallengeToken(user *domain.User) (string, error) {
+	return s.keys.Sign(jwt.MapClaims{
+		"jti": uuid.NewString(),
+		"sub": user.ID.String(),
+		"usr": user.Username,
+		"typ": "mfa",
+		"exp": time.Now().Add(challengeTTL).Unix(),
+	})
+}
+
+// ParseChallenge validasi challenge token tanpa memakainya
+func (s *AuthService) ParseChallenge(tokenString string) (*MFAChallengeInfo, error) {
+	token, err := s.keys.Parse(tokenString)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["typ"] != "mfa" {
+		return nil, ErrInvalidToken
+	}
+	sub, _ := claims.GetSubject()
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	id, _ := claims["jti"].(string)
+	username, _ := claims["usr"].(string)
+	if id == "" {
+		return nil, ErrInvalidToken
+	}
+	return &MFAChallengeInfo{ID: id, UserID: userID, Username: username}, nil
+}
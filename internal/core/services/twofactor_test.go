@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/damantine/multi-tenant-hosting/internal/adapters/mailer"
+	"github.com/damantine/multi-tenant-hosting/internal/core/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestGenerateRecoveryCode(t *testing.T) {
+	format := regexp.MustCompile(`^[a-hjkmnp-z2-9]{5}-[a-hjkmnp-z2-9]{5}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !format.MatchString(code) {
+			t.Fatalf("recovery code %q has an unexpected format", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate recovery code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	svc := newTestAuthService(t, nil, mailer.NewMemoryMailer())
+	userID := uuid.New()
+
+	valid, err := svc.signChallengeToken(&domain.User{ID: userID, Username: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := svc.ParseChallenge(valid)
+	if err != nil || got.UserID != userID || got.Username != "alice" || got.ID == "" {
+		t.Fatalf("parse = %+v, %v; want %v/alice with an ID", got, err, userID)
+	}
+	// ID unik per challenge supaya batas percobaan tidak terbawa ke login berikutnya
+	other, _ := svc.signChallengeToken(&domain.User{ID: userID, Username: "alice"})
+	if next, err := svc.ParseChallenge(other); err != nil || next.ID == got.ID {
+		t.Fatalf("second challenge = %+v, %v; want a different ID than %q", next, err, got.ID)
+	}
+
+	expired, err := svc.keys.Sign(jwt.MapClaims{"sub": userID.String(), "typ": "mfa", "exp": time.Now().Add(-time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	noID, err := svc.keys.Sign(jwt.MapClaims{"sub": userID.String(), "typ": "mfa", "exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": userID.String(), "typ": "mfa", "exp": time.Now().Add(time.Minute).Unix()}).SignedString(svc.secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	access, _, err := svc.signAccessToken(userID, uuid.New(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"session access token":       access,
+		"expired":                    expired,
+		"without challenge ID":       noID,
+		"HS256 with the HMAC secret": hmac,
+		"malformed":                  "not-a-jwt",
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := svc.ParseChallenge(token); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("err = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	// Challenge token tidak bisa dipakai sebagai access token
+	if _, err := svc.ValidateToken(context.Background(), valid); err == nil {
+		t.Fatal("challenge token accepted as access token")
+	}
+}
+
+func TestTOTPLoginFlow(t *testing.T) {
+	db := testDB(t)
+	svc := newTestAuthService(t, db, mailer.NewMemoryMailer())
+	ctx := context.Background()
+	user := createTestUser(t, db, "alice", "alice@example.com", true)
+
+	enrollment, err := svc.EnrollTOTP(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	if _, err := svc.VerifyTOTP(ctx, user.ID, "000000"); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("verify with wrong code: err = %v", err)
+	}
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovery, err := svc.VerifyTOTP(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(recovery) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(recovery), recoveryCodeCount)
+	}
+
+	result, err := svc.Login(ctx, "alice", testPassword, ClientInfo{})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if !result.MFARequired || result.Tokens != nil || result.ChallengeToken == "" {
+		t.Fatalf("login result = %+v, want a challenge without tokens", result)
+	}
+
+	// Kode yang sudah dipakai untuk verifikasi tidak bisa dipakai ulang
+	if _, err := svc.CompleteMFA(ctx, result.ChallengeToken, code, "", ClientInfo{}); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("replayed code: err = %v, want ErrInvalidTOTPCode", err)
+	}
+	tokens, err := svc.CompleteMFA(ctx, result.ChallengeToken, "", " "+recovery[0]+" ", ClientInfo{})
+	if err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	if _, err := svc.ValidateToken(ctx, tokens.AccessToken); err != nil {
+		t.Fatalf("session from MFA: %v", err)
+	}
+	if _, err := svc.CompleteMFA(ctx, result.ChallengeToken, "", recovery[0], ClientInfo{}); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("reused recovery code: err = %v, want ErrInvalidTOTPCode", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package logging menyediakan logger slog JSON yang otomatis membawa atribut dari context
+// (request_id, user_id, project_id, deployment_id, trace_id) sehingga satu deploy bisa ditelusuri end to end.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Kunci atribut standar yang dipakai di seluruh aplikasi
+const (
+	KeyRequestID    = "request_id"
+	KeyUserID       = "user_id"
+	KeyImpersonator = "impersonator_id"
+	KeyProjectID    = "project_id"
+	KeyDeploymentID = "deployment_id"
+	KeyTraceID      = "trace_id"
+	KeySpanID       = "span_id"
+)
+
+type ctxKey struct{}
+
+// New membuat logger JSON yang membaca atribut dari context pada setiap *Context call
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(&contextHandler{
+		Handler: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}),
+	})
+}
+
+// ParseLevel mengubah "debug", "info", "warn", "error" ke slog.Level (default info)
+func ParseLevel(s string) slog.Level {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// With mengembalikan context baru yang membawa atribut tambahan.
+// Atribut dengan key yang sama akan menimpa nilai sebelumnya.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing := attrsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	for _, a := range existing {
+		if !hasKey(attrs, a.Key) {
+			merged = append(merged, a)
+		}
+	}
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, ctxKey{}, merged)
+}
+
+// Value mengambil nilai atribut dari context (mis. request_id untuk header response)
+func Value(ctx context.Context, key string) (slog.Value, bool) {
+	for _, a := range attrsFromContext(ctx) {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+func hasKey(attrs []slog.Attr, key string) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// contextHandler menambahkan atribut dari context (dan trace/span ID OpenTelemetry) ke setiap record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := attrsFromContext(ctx); len(attrs) > 0 {
+		r.AddAttrs(attrs...)
+	}
+	if ctx != nil {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String(KeyTraceID, sc.TraceID().String()), slog.String(KeySpanID, sc.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextAttributesAreLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	ctx := With(context.Background(), slog.String(KeyRequestID, "req-1"), slog.String(KeyUserID, "u-1"))
+	ctx = With(ctx, slog.String(KeyUserID, "u-2"), slog.String(KeyProjectID, "p-1"))
+	logger.With("component", "test").InfoContext(ctx, "deployed")
+	logger.DebugContext(ctx, "filtered by level")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("want exactly one JSON line, got %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"msg":        "deployed",
+		"component":  "test",
+		KeyRequestID: "req-1",
+		KeyUserID:    "u-2", // key yang sama ditimpa, bukan diduplikasi
+		KeyProjectID: "p-1",
+	}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("%s = %v, want %v", k, record[k], v)
+		}
+	}
+
+	if v, ok := Value(ctx, KeyRequestID); !ok || v.String() != "req-1" {
+		t.Errorf("Value(request_id) = %v, %v", v, ok)
+	}
+	if _, ok := Value(context.Background(), KeyRequestID); ok {
+		t.Error("Value found an attribute in an empty context")
+	}
+}
+
+func TestWithDoesNotMutateParent(t *testing.T) {
+	parent := With(context.Background(), slog.String(KeyUserID, "parent"))
+	child := With(parent, slog.String(KeyUserID, "child"))
+
+	if v, _ := Value(parent, KeyUserID); v.String() != "parent" {
+		t.Fatalf("parent user_id = %q after child override", v.String())
+	}
+	if v, _ := Value(child, KeyUserID); v.String() != "child" {
+		t.Fatalf("child user_id = %q", v.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"DEBUG":   slog.LevelDebug,
+		"warn":    slog.LevelWarn,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+		"info":    slog.LevelInfo,
+		"":        slog.LevelInfo,
+		"verbose": slog.LevelInfo,
+	}
+	for in, want := range tests {
+		if got := ParseLevel(in); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestTraceIDsAreLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+	}))
+	logger.InfoContext(ctx, "traced")
+	logger.InfoContext(context.Background(), "untraced")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(lines))
+	}
+	var traced, untraced map[string]any
+	json.Unmarshal(lines[0], &traced)
+	json.Unmarshal(lines[1], &untraced)
+
+	if traced[KeyTraceID] != traceID.String() || traced[KeySpanID] != spanID.String() {
+		t.Fatalf("traced record = %v, want trace_id and span_id", traced)
+	}
+	if _, ok := untraced[KeyTraceID]; ok {
+		t.Fatalf("untraced record has a trace_id: %v", untraced)
+	}
+}
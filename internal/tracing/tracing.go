@@ -0,0 +1,75 @@
+// Package tracing menyiapkan OpenTelemetry TracerProvider (export via OTLP/HTTP)
+// dan helper kecil untuk membuat span di handler, service, repository dan adapter Docker.
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName nama instrumentation scope untuk semua span aplikasi
+const TracerName = "github.com/damantine/multi-tenant-hosting"
+
+// Setup memasang TracerProvider global. Exporter OTLP/HTTP hanya aktif jika
+// OTEL_EXPORTER_OTLP_ENDPOINT / OTEL_EXPORTER_OTLP_TRACES_ENDPOINT di-set;
+// konfigurasi lain (header, insecure, dsb) dibaca exporter langsung dari env standar OTel.
+// Fungsi shutdown yang dikembalikan harus dipanggil saat server berhenti.
+func Setup(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := NewTracerProvider(serviceName, sdktrace.WithBatcher(exporter))
+	Install(tp)
+	return tp.Shutdown, nil
+}
+
+// NewTracerProvider membuat TracerProvider dengan resource service.name
+func NewTracerProvider(serviceName string, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))
+	return sdktrace.NewTracerProvider(append([]sdktrace.TracerProviderOption{sdktrace.WithResource(res)}, opts...)...)
+}
+
+// InstallExporter memasang TracerProvider global yang mengirim setiap span langsung (sinkron) ke exporter,
+// mis. tracetest.NewInMemoryExporter() di test. Panggil sebelum router / client dibuat.
+func InstallExporter(serviceName string, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	tp := NewTracerProvider(serviceName, sdktrace.WithSyncer(exporter))
+	Install(tp)
+	return tp
+}
+
+// Install menjadikan tp sebagai provider global beserta propagator W3C
+func Install(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Start membuat span baru dari tracer global aplikasi
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End menutup span dan menandainya error jika err != nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}